@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"radio/internal/client"
+	"radio/internal/config"
+	"radio/internal/favsync"
+	"radio/internal/netconf"
 	"radio/internal/player"
+	"radio/internal/recorder"
+	"radio/internal/scan"
+	"radio/internal/scheduler"
 	"radio/internal/storage"
 	"radio/internal/ui"
 	"runtime"
+	"strings"
 	"time"
 
 	"radio/pkg/logger"
@@ -30,19 +40,73 @@ func clearTerminal() {
 	}
 }
 
+// findFavorite matches a favorite by URL or name; an empty query picks the
+// oldest one, as ListFavorites lists it first.
+func findFavorite(stor storage.Store, query string) (client.Station, bool) {
+	favorites := stor.ListFavorites()
+	for _, st := range favorites {
+		if query == "" || st.URL == query || strings.EqualFold(st.Name, query) {
+			return st, true
+		}
+	}
+	return client.Station{}, false
+}
+
+// listAudioDevices prints what -audio-device accepts.
+func listAudioDevices(pl player.Backend) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	devices, err := pl.AudioDevices(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to list audio devices:", err)
+		os.Exit(1)
+	}
+	for _, d := range devices {
+		fmt.Printf("%-50s %s\n", d.Name, d.Description)
+	}
+}
+
 func main() {
-	clearTerminal()
+	configPath := flag.String("config", config.DefaultPath, "path to the config file")
+	headless := flag.Bool("headless", false, "run schedules without the UI until interrupted")
+	sleep := flag.Duration("sleep", 0, "stop playback after this long, e.g. 45m")
+	alarm := flag.String("alarm", "", "wake up at HH:MM with a favorite station")
+	alarmStation := flag.String("alarm-station", "", "favorite to wake up to, by name or URL (default: oldest favorite)")
+	audioDevice := flag.String("audio-device", "", "output device for this run; \"help\" lists them")
+	flag.Parse()
+
+	if !*headless && *audioDevice != "help" {
+		clearTerminal()
+	}
 
 	logger.Init()
 	logger.Log.Info().Msg("Logger initialized")
 
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Failed to load config")
+	}
+
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt)
 
-	client := client.NewClient("", 10*time.Second)
-	pl := player.New()
+	network, err := netconf.New(cfg.Network)
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Invalid network config")
+	}
+	defer network.Close()
 
-	storagePath := "./jsonfile/favorites.json"
+	client := client.NewClient("", 10*time.Second, network)
+	pl, err := player.Open(cfg.Player, network)
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Failed to initialize player")
+	}
+	if *audioDevice == "help" {
+		listAudioDevices(pl)
+		return
+	}
+
+	storagePath := cfg.Storage.ResolvedPath()
 	storageDir := filepath.Dir(storagePath)
 	if err := os.MkdirAll(storageDir, os.ModePerm); err != nil {
 		logger.Log.Fatal().Err(err).Msg("Failed to create storage directory")
@@ -56,13 +120,88 @@ func main() {
 		logger.Log.Info().Msgf("Favorites file found: %s", storagePath)
 	}
 
-	stor, err := storage.NewStorage(storagePath)
+	stor, err := storage.Open(cfg.Storage.Backend, storagePath)
 	if err != nil {
 		logger.Log.Fatal().Err(err).Msg("Failed to initialize storage")
 	}
+	defer stor.Close()
+
+	device := *audioDevice
+	if device == "" {
+		device, _ = stor.GetSetting(ui.AudioDeviceSetting)
+	}
+	if err := pl.SetAudioDevice(device); err != nil {
+		logger.Log.Fatal().Err(err).Msg("Failed to set audio device")
+	}
+
+	syncer, err := favsync.Open(stor, cfg.Sync, network.Client(30*time.Second))
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Failed to configure sync")
+	}
+
+	rec := recorder.New(cfg.Recording.Dir, network.Client(0))
+	rec.SplitTracks = cfg.Recording.SplitTracks
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Schedule.Path), os.ModePerm); err != nil {
+		logger.Log.Fatal().Err(err).Msg("Failed to create schedules directory")
+	}
+	sched, err := scheduler.New(cfg.Schedule.Path)
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Failed to load schedules")
+	}
+
+	if *headless {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-sigs
+			logger.Log.Info().Msg("Interrupt signal received, stopping schedules...")
+			cancel()
+		}()
+
+		logger.Log.Info().Msgf("Running %d schedule(s) headless", len(sched.List()))
+		scheduler.RunHeadless(ctx, sched, scheduler.NewExecutor(pl, rec))
+		_ = rec.Stop()
+		_ = pl.Stop()
+		return
+	}
 
 	// создаём UIModel
 	m := ui.NewUIModel(client, pl, stor)
+	if syncer != nil {
+		m.SetSyncer(syncer, time.Duration(cfg.Sync.IntervalSeconds)*time.Second)
+	}
+	m.SetNetwork(network)
+	m.SetRecorder(rec)
+	m.SetScheduler(sched)
+	m.SetSleepTimer(*sleep)
+	m.SetAudioDevice(device)
+
+	scanMode, err := scan.ParseMode(cfg.Scan.Mode)
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Invalid scan config")
+	}
+	scanner := scan.New(scanMode, time.Duration(cfg.Scan.DwellSeconds)*time.Second, nil)
+	scanner.SetFilter(scan.Filter{Tag: cfg.Scan.Tag, Country: cfg.Scan.Country})
+	m.SetScan(scanner, cfg.Scan.Favorites)
+
+	crossfade, err := player.ParseCrossfadeMode(cfg.Player.Crossfade)
+	if err != nil {
+		logger.Log.Fatal().Err(err).Msg("Invalid player config")
+	}
+	m.SetCrossfade(crossfade, time.Duration(cfg.Player.CrossfadeSeconds)*time.Second)
+	m.SetStreamDefaults(cfg.Player.Stream)
+
+	if *alarm != "" {
+		at, err := ui.ParseAlarmTime(*alarm, time.Now())
+		if err != nil {
+			logger.Log.Fatal().Err(err).Msg("Invalid -alarm")
+		}
+		station, ok := findFavorite(stor, *alarmStation)
+		if !ok {
+			logger.Log.Fatal().Msgf("No favorite matches -alarm-station %q", *alarmStation)
+		}
+		m.SetAlarm(at, station)
+	}
 
 	p := tea.NewProgram(m)
 
@@ -0,0 +1,80 @@
+// Package audio is the pure-Go playback path: it reads a station's stream,
+// decodes it and hands samples to a Sink, so the app can play without mpv.
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Format describes interleaved float32 PCM.
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+// Sink consumes decoded audio. Device sinks block in Write to pace playback
+// in real time; file and null sinks do not.
+type Sink interface {
+	Open(f Format) error
+	// Write takes interleaved samples in -1..1.
+	Write(samples []float32) error
+	Close() error
+}
+
+// Decoder produces interleaved float32 samples in its Format.
+type Decoder interface {
+	Format() Format
+	Read(out []float32) (int, error)
+}
+
+// ErrUnsupportedCodec is returned for streams the native path cannot
+// decode; they still play with mpv.
+var ErrUnsupportedCodec = errors.New("codec not supported by the native player")
+
+// Codec names used by DetectCodec and NewDecoder.
+const (
+	CodecMP3  = "mp3"
+	CodecAAC  = "aac"
+	CodecOgg  = "ogg"
+	CodecFLAC = "flac"
+)
+
+// DetectCodec identifies the stream from its first bytes, falling back to
+// the Content-Type header. It returns "" when neither says.
+func DetectCodec(contentType string, head []byte) string {
+	switch {
+	case strings.HasPrefix(string(head), "OggS"):
+		return CodecOgg
+	case strings.HasPrefix(string(head), "fLaC"):
+		return CodecFLAC
+	case strings.HasPrefix(string(head), "ID3"):
+		return CodecMP3
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0:
+		return CodecAAC
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return CodecMP3
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "audio/mpeg", "audio/mp3", "audio/mpeg3":
+		return CodecMP3
+	case "audio/aac", "audio/aacp", "audio/x-aac", "audio/mp4":
+		return CodecAAC
+	case "audio/ogg", "application/ogg", "audio/opus", "audio/vorbis":
+		return CodecOgg
+	case "audio/flac", "audio/x-flac":
+		return CodecFLAC
+	}
+	return ""
+}
+
+func unsupported(codec string) error {
+	if codec == "" {
+		codec = "unknown"
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+}
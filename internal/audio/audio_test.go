@@ -0,0 +1,466 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestDetectCodec(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		head        []byte
+		want        string
+	}{
+		{"ogg magic", "audio/mpeg", []byte("OggS"), CodecOgg},
+		{"flac magic", "", []byte("fLaC"), CodecFLAC},
+		{"id3 tag", "", []byte("ID3\x04"), CodecMP3},
+		{"mpeg frame", "", []byte{0xFF, 0xFB, 0x90, 0x00}, CodecMP3},
+		{"adts frame", "", []byte{0xFF, 0xF1, 0x50, 0x80}, CodecAAC},
+		{"header only", "audio/aacp", []byte{0, 0, 0, 0}, CodecAAC},
+		{"header params", "audio/mpeg; charset=binary", nil, CodecMP3},
+		{"unknown", "text/html", []byte("<htm"), ""},
+	}
+	for _, tt := range tests {
+		if got := DetectCodec(tt.contentType, tt.head); got != tt.want {
+			t.Errorf("%s: DetectCodec = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewDecoderUnsupported(t *testing.T) {
+	for _, codec := range []string{CodecAAC, CodecFLAC, ""} {
+		if _, err := NewDecoder(codec, bytes.NewReader(nil)); !errors.Is(err, ErrUnsupportedCodec) {
+			t.Errorf("NewDecoder(%q) error = %v, want ErrUnsupportedCodec", codec, err)
+		}
+	}
+}
+
+func TestDecodeVorbis(t *testing.T) {
+	data, err := os.ReadFile("testdata/vorbis.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodeAll := func(data []byte) (Format, int) {
+		t.Helper()
+		dec, err := NewDecoder(CodecOgg, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("NewDecoder: %v", err)
+		}
+		var total int
+		buf := make([]float32, 1000)
+		for {
+			n, err := dec.Read(buf)
+			total += n
+			if err == io.EOF {
+				return dec.Format(), total
+			}
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+		}
+	}
+
+	f, single := decodeAll(data)
+	if f.SampleRate == 0 || f.Channels == 0 || single == 0 {
+		t.Fatalf("decoded %d samples in format %+v", single, f)
+	}
+
+	// A chained stream, as Icecast sends across tracks, plays every link.
+	if _, chained := decodeAll(append(append([]byte{}, data...), data...)); chained != 2*single {
+		t.Errorf("chained stream decoded %d samples, want %d", chained, 2*single)
+	}
+}
+
+func TestDecodeOpusUnsupported(t *testing.T) {
+	head := append([]byte("OggS\x00\x02"), make([]byte, 22)...)
+	head = append(head, "OpusHead"...)
+	if _, err := NewDecoder(CodecOgg, bytes.NewReader(head)); !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("NewDecoder(opus) error = %v, want ErrUnsupportedCodec", err)
+	}
+}
+
+func TestDecodeMP3(t *testing.T) {
+	data, err := os.ReadFile("testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewDecoder(CodecMP3, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewDecoder: %v", err)
+	}
+	if f := dec.Format(); f.Channels != 2 || f.SampleRate == 0 {
+		t.Fatalf("unexpected format %+v", f)
+	}
+
+	var total int
+	var peak float32
+	buf := make([]float32, 1000)
+	for {
+		n, err := dec.Read(buf)
+		for _, v := range buf[:n] {
+			peak = max(peak, float32(math.Abs(float64(v))))
+		}
+		total += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+	}
+	if total == 0 || total%2 != 0 {
+		t.Errorf("decoded %d samples, want a positive even count", total)
+	}
+	if peak == 0 || peak > 1 {
+		t.Errorf("peak %v out of range", peak)
+	}
+}
+
+// constDecoder yields frames mono samples of value v.
+type constDecoder struct {
+	format Format
+	frames int
+	v      float32
+}
+
+func (d *constDecoder) Format() Format { return d.format }
+
+func (d *constDecoder) Read(out []float32) (int, error) {
+	n := min(len(out)/d.format.Channels, d.frames) * d.format.Channels
+	if n == 0 {
+		return 0, io.EOF
+	}
+	for i := range out[:n] {
+		out[i] = d.v
+	}
+	d.frames -= n / d.format.Channels
+	return n, nil
+}
+
+func TestResampler(t *testing.T) {
+	src := &constDecoder{format: Format{SampleRate: 22050, Channels: 1}, frames: 22050, v: 0.5}
+	r := newResampler(src, OutputFormat)
+	if r.Format() != OutputFormat {
+		t.Fatalf("format = %+v", r.Format())
+	}
+
+	out, err := readAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := len(out) / 2
+	if frames < 44090 || frames > 44110 {
+		t.Errorf("got %d frames, want about 44100", frames)
+	}
+	for i, v := range out {
+		if v != 0.5 {
+			t.Fatalf("sample %d = %v, want 0.5", i, v)
+		}
+	}
+
+	same := &constDecoder{format: OutputFormat}
+	if newResampler(same, OutputFormat) != Decoder(same) {
+		t.Error("expected matching formats to skip resampling")
+	}
+}
+
+func readAll(d Decoder) ([]float32, error) {
+	var all []float32
+	buf := make([]float32, 777*2)
+	for {
+		n, err := d.Read(buf)
+		all = append(all, buf[:n]...)
+		if err == io.EOF {
+			return all, nil
+		}
+		if err != nil {
+			return all, err
+		}
+	}
+}
+
+// withICY interleaves a StreamTitle block every metaint bytes of audio.
+func withICY(audio []byte, metaint int, title string) []byte {
+	meta := "StreamTitle='" + title + "';"
+	block := []byte{byte((len(meta) + 15) / 16)}
+	block = append(block, meta...)
+	block = append(block, make([]byte, int(block[0])*16-len(meta))...)
+
+	var out bytes.Buffer
+	for len(audio) > 0 {
+		n := min(metaint, len(audio))
+		out.Write(audio[:n])
+		audio = audio[n:]
+		if n == metaint {
+			out.Write(block)
+		}
+	}
+	return out.Bytes()
+}
+
+func TestPipelinePlay(t *testing.T) {
+	data, err := os.ReadFile("testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const metaint = 1024
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Test FM")
+		if r.Header.Get("Icy-MetaData") == "1" && r.URL.Path == "/icy" {
+			w.Header().Set("icy-metaint", strconv.Itoa(metaint))
+			w.Write(withICY(data, metaint, "Artist - Song"))
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	var plainBytes int64
+	t.Run("null sink", func(t *testing.T) {
+		p := NewPipeline(srv.Client())
+		sink := &NullSink{}
+		if err := p.Play(context.Background(), srv.URL+"/plain", sink); !errors.Is(err, ErrStreamEnded) {
+			t.Fatalf("Play error = %v, want ErrStreamEnded", err)
+		}
+		if sink.Frames() == 0 {
+			t.Error("no audio reached the sink")
+		}
+		st := p.Stats()
+		if st.Codec != CodecMP3 || st.BytesRead == 0 || st.BytesRead > int64(len(data)) {
+			t.Errorf("unexpected stats %+v", st)
+		}
+		plainBytes = st.BytesRead
+		if st.Headers["icy-name"] != "Test FM" {
+			t.Errorf("icy headers = %v", st.Headers)
+		}
+	})
+
+	t.Run("icy to wav", func(t *testing.T) {
+		p := NewPipeline(srv.Client())
+		path := filepath.Join(t.TempDir(), "out.wav")
+		sink, err := CreateWAV(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Play(context.Background(), srv.URL+"/icy", sink); !errors.Is(err, ErrStreamEnded) {
+			t.Fatalf("Play error = %v, want ErrStreamEnded", err)
+		}
+		if got := p.Stats().Title; got != "Artist - Song" {
+			t.Errorf("title = %q", got)
+		}
+		// Metadata must be stripped before decoding.
+		if got := p.Stats().BytesRead; got != plainBytes {
+			t.Errorf("read %d audio bytes, want %d as without metadata", got, plainBytes)
+		}
+
+		wav, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(wav) <= 44 || string(wav[:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+			t.Fatalf("not a wav file (%d bytes)", len(wav))
+		}
+		if size := binary.LittleEndian.Uint32(wav[40:]); int(size) != len(wav)-44 {
+			t.Errorf("data size %d, want %d", size, len(wav)-44)
+		}
+		if rate := binary.LittleEndian.Uint32(wav[24:]); int(rate) != OutputFormat.SampleRate {
+			t.Errorf("sample rate %d", rate)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p := NewPipeline(srv.Client())
+		if err := p.Play(ctx, srv.URL+"/plain", &NullSink{}); err == nil {
+			t.Error("expected an error connecting with a cancelled context")
+		}
+	})
+}
+
+func TestPipelineUnsupported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/flac")
+		w.Write([]byte("fLaC not really flac"))
+	}))
+	defer srv.Close()
+
+	err := NewPipeline(srv.Client()).Play(context.Background(), srv.URL, &NullSink{})
+	if !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("Play error = %v, want ErrUnsupportedCodec", err)
+	}
+}
+
+func TestPipelineDASHUnsupported(t *testing.T) {
+	// The manifest is never fetched: the extension is enough.
+	err := NewPipeline(http.DefaultClient).Play(context.Background(), "http://127.0.0.1:1/live.mpd", &NullSink{})
+	if !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("Play error = %v, want ErrUnsupportedCodec", err)
+	}
+}
+
+func TestPipelineHLS(t *testing.T) {
+	data, err := os.ReadFile("testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := len(data) / 2
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/live":
+			// No extension: detected from the Content-Type.
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			w.Write([]byte("#EXTM3U\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=64000,CODECS=\"mp4a.40.34\"\nlow.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=128000,CODECS=\"mp4a.40.34\"\nhigh.m3u8\n"))
+		case "/high.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\na.mp3\n#EXTINF:1,\nb.mp3\n#EXT-X-ENDLIST\n"))
+		case "/a.mp3":
+			w.Write(data[:half])
+		case "/b.mp3":
+			w.Write(data[half:])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPipeline(srv.Client())
+	sink := &NullSink{}
+	if err := p.Play(context.Background(), srv.URL+"/live", sink); !errors.Is(err, ErrStreamEnded) {
+		t.Fatalf("Play error = %v, want ErrStreamEnded", err)
+	}
+	st := p.Stats()
+	if st.Codec != CodecMP3 || st.Variant == nil || st.Variant.Bandwidth != 128000 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if sink.Frames() == 0 {
+		t.Error("no audio reached the sink")
+	}
+}
+
+func TestPipelineHLSWithoutPlaylistType(t *testing.T) {
+	data, err := os.ReadFile("testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/live":
+			// Neither extension nor Content-Type say HLS.
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\na.mp3\n#EXT-X-ENDLIST\n"))
+		case "/a.mp3":
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPipeline(srv.Client())
+	sink := &NullSink{}
+	if err := p.Play(context.Background(), srv.URL+"/live", sink); !errors.Is(err, ErrStreamEnded) {
+		t.Fatalf("Play error = %v, want ErrStreamEnded", err)
+	}
+	if sink.Frames() == 0 {
+		t.Error("no audio reached the sink")
+	}
+}
+
+func TestPipelineStationList(t *testing.T) {
+	data, err := os.ReadFile("testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/radio.pls":
+			w.Header().Set("Content-Type", "audio/x-scpls")
+			w.Write([]byte("[playlist]\nNumberOfEntries=2\nFile1=/down\nTitle1=Down\nFile2=list.m3u\nVersion=2\n"))
+		case "/list.m3u":
+			// Plain M3U shares its Content-Type with HLS.
+			w.Header().Set("Content-Type", "audio/x-mpegurl")
+			w.Write([]byte("#EXTM3U\n#EXTINF:-1,Tone\n/stream\n"))
+		case "/stream":
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPipeline(srv.Client())
+	sink := &NullSink{}
+	if err := p.Play(context.Background(), srv.URL+"/radio.pls", sink); !errors.Is(err, ErrStreamEnded) {
+		t.Fatalf("Play error = %v, want ErrStreamEnded", err)
+	}
+	if st := p.Stats(); st.Codec != CodecMP3 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if sink.Frames() == 0 {
+		t.Error("no audio reached the sink")
+	}
+}
+
+type countingFilter struct{ samples int }
+
+func (f *countingFilter) Process(samples []float32) {
+	f.samples += len(samples)
+	for i := range samples {
+		samples[i] = 0
+	}
+}
+
+func TestPipelineFilter(t *testing.T) {
+	data, err := os.ReadFile("testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	p := NewPipeline(srv.Client())
+	filter := &countingFilter{}
+	p.SetFilter(filter)
+
+	path := filepath.Join(t.TempDir(), "out.wav")
+	sink, err := CreateWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Play(context.Background(), srv.URL, sink); !errors.Is(err, ErrStreamEnded) {
+		t.Fatalf("Play error = %v", err)
+	}
+
+	wav, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.samples != (len(wav)-44)/2 {
+		t.Errorf("filter saw %d samples, sink got %d", filter.samples, (len(wav)-44)/2)
+	}
+	if !bytes.Equal(wav[44:], make([]byte, len(wav)-44)) {
+		t.Error("filter output did not reach the sink")
+	}
+}
@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// NewDecoder returns a decoder for codec: MP3 and Ogg Vorbis. AAC, Opus
+// and FLAC streams need mpv.
+func NewDecoder(codec string, r io.Reader) (Decoder, error) {
+	switch codec {
+	case CodecMP3:
+		d, err := mp3.NewDecoder(r)
+		if err != nil {
+			return nil, fmt.Errorf("decoding mp3: %w", err)
+		}
+		return &mp3Decoder{d: d}, nil
+	case CodecOgg:
+		return newVorbisDecoder(r)
+	}
+	return nil, unsupported(codec)
+}
+
+// mp3Decoder adapts go-mp3, which always yields 16-bit stereo.
+type mp3Decoder struct {
+	d   *mp3.Decoder
+	buf []byte
+}
+
+func (m *mp3Decoder) Format() Format {
+	return Format{SampleRate: m.d.SampleRate(), Channels: 2}
+}
+
+func (m *mp3Decoder) Read(out []float32) (int, error) {
+	if cap(m.buf) < len(out)*2 {
+		m.buf = make([]byte, len(out)*2)
+	}
+	// Whole stereo frames only.
+	buf := m.buf[:len(out)*2&^3]
+
+	n, err := m.d.Read(buf)
+	n &^= 1
+	for i := 0; i < n; i += 2 {
+		out[i/2] = float32(int16(binary.LittleEndian.Uint16(buf[i:]))) / 32768
+	}
+	return n / 2, err
+}
+
+// vorbisDecoder plays Ogg Vorbis. Icecast starts a new logical stream,
+// with fresh headers, for every track; when one ends the next is read
+// from the same connection.
+type vorbisDecoder struct {
+	src    io.Reader
+	r      *oggvorbis.Reader
+	format Format
+}
+
+func newVorbisDecoder(r io.Reader) (Decoder, error) {
+	// Opus shares the Ogg container; tell it apart by its header packet.
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(64); bytes.Contains(head, []byte("OpusHead")) {
+		return nil, unsupported("opus")
+	}
+
+	vr, err := oggvorbis.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("decoding ogg vorbis: %w", err)
+	}
+	return &vorbisDecoder{
+		src:    br,
+		r:      vr,
+		format: Format{SampleRate: vr.SampleRate(), Channels: vr.Channels()},
+	}, nil
+}
+
+func (v *vorbisDecoder) Format() Format {
+	return v.format
+}
+
+func (v *vorbisDecoder) Read(out []float32) (int, error) {
+	n, err := v.r.Read(out)
+	if !errors.Is(err, io.EOF) {
+		return n, err
+	}
+	if n > 0 {
+		return n, nil
+	}
+
+	next, err := oggvorbis.NewReader(v.src)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, io.EOF
+		}
+		return 0, fmt.Errorf("decoding next ogg vorbis stream: %w", err)
+	}
+	// The resampler was set up for the first track's format.
+	if f := (Format{SampleRate: next.SampleRate(), Channels: next.Channels()}); f != v.format {
+		return 0, fmt.Errorf("ogg stream changed format from %+v to %+v", v.format, f)
+	}
+	v.r = next
+	return v.r.Read(out)
+}
@@ -0,0 +1,190 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"radio/internal/hls"
+	"radio/internal/icy"
+	"radio/internal/netconf"
+)
+
+// ErrStreamEnded is returned when the server closes a stream, which for
+// live radio means playback stopped on its own.
+var ErrStreamEnded = errors.New("stream ended")
+
+// OutputFormat is what decoded audio is converted to before the sink.
+var OutputFormat = Format{SampleRate: 44100, Channels: 2}
+
+// Stats describes a running pipeline.
+type Stats struct {
+	Codec       string
+	Source      Format
+	BytesRead   int64
+	Started     time.Time
+	Title       string
+	Headers     map[string]string
+	ContentType string
+	// Variant is the HLS rendition being played, if any.
+	Variant *hls.Variant
+}
+
+// Bitrate is the average stream bitrate since start, in bits per second.
+func (s Stats) Bitrate() int {
+	elapsed := time.Since(s.Started).Seconds()
+	if elapsed < 1 {
+		return 0
+	}
+	return int(float64(s.BytesRead*8) / elapsed)
+}
+
+// Filter changes decoded audio in OutputFormat, in place.
+type Filter interface {
+	Process(samples []float32)
+}
+
+// Pipeline plays one stream at a time into a sink.
+type Pipeline struct {
+	// MaxBandwidth caps the HLS variant chosen, in bits per second; 0
+	// picks the best one.
+	MaxBandwidth int
+
+	client *http.Client
+	gain   atomic.Uint32 // float32 bits
+
+	mu     sync.Mutex
+	stats  Stats
+	filter Filter
+}
+
+func NewPipeline(client *http.Client) *Pipeline {
+	if client == nil {
+		client = netconf.Default().Client(0)
+	}
+	p := &Pipeline{client: client}
+	p.SetGain(1)
+	return p
+}
+
+// SetClient replaces the HTTP client for streams started later.
+func (p *Pipeline) SetClient(client *http.Client) {
+	p.mu.Lock()
+	p.client = client
+	p.mu.Unlock()
+}
+
+// SetGain scales the output; 1 is unchanged. It applies immediately.
+func (p *Pipeline) SetGain(g float32) {
+	p.gain.Store(math.Float32bits(g))
+}
+
+// SetFilter applies f to everything played from now on; nil removes it.
+func (p *Pipeline) SetFilter(f Filter) {
+	p.mu.Lock()
+	p.filter = f
+	p.mu.Unlock()
+}
+
+func (p *Pipeline) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Play streams url into sink until the stream ends, fails or ctx is done.
+// It returns nil when stopped through ctx.
+func (p *Pipeline) Play(ctx context.Context, url string, sink Sink) error {
+	onMeta := func(meta string) {
+		if title, ok := icy.ParseStreamTitle(meta); ok {
+			p.mu.Lock()
+			p.stats.Title = title
+			p.mu.Unlock()
+		}
+	}
+
+	p.mu.Lock()
+	client := p.client
+	p.mu.Unlock()
+	stream, err := OpenStream(ctx, client, url, p.MaxBandwidth, onMeta)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	counted := &countingReader{r: stream, p: p}
+	dec, err := NewDecoder(stream.Codec, counted)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.stats = Stats{
+		Codec:       stream.Codec,
+		Source:      dec.Format(),
+		Started:     time.Now(),
+		Headers:     stream.Headers,
+		ContentType: stream.ContentType,
+		Variant:     stream.Variant,
+	}
+	p.mu.Unlock()
+
+	if err := sink.Open(OutputFormat); err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	src := newResampler(dec, OutputFormat)
+	buf := make([]float32, 4096)
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		n, err := src.Read(buf)
+		if n > 0 {
+			p.mu.Lock()
+			filter := p.filter
+			p.mu.Unlock()
+			if filter != nil {
+				filter.Process(buf[:n])
+			}
+
+			gain := math.Float32frombits(p.gain.Load())
+			if gain != 1 {
+				for i := range buf[:n] {
+					buf[i] *= gain
+				}
+			}
+			if werr := sink.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, io.EOF) {
+				return ErrStreamEnded
+			}
+			return err
+		}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	p *Pipeline
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.p.mu.Lock()
+	c.p.stats.BytesRead += int64(n)
+	c.p.mu.Unlock()
+	return n, err
+}
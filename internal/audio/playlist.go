@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxStationListDepth bounds how many station lists are followed before
+// reaching a stream, in case lists point at each other.
+const maxStationListDepth = 3
+
+// isStationList reports whether the body is a .pls or plain .m3u list of
+// stream URLs, which many directories link to instead of the stream. It
+// is called after isHLSPlaylist, so an #EXTM3U body here is not HLS.
+func isStationList(br *bufio.Reader, rawURL, contentType string) bool {
+	head := listHead(br)
+	if isPLS(head) || bytes.HasPrefix(head, []byte("#EXTM3U")) {
+		return true
+	}
+	// Bare lists of URLs are only taken as such when the server says so.
+	if !isStationListURL(rawURL) && !isStationListType(contentType) {
+		return false
+	}
+	return bytes.HasPrefix(head, []byte("http://")) || bytes.HasPrefix(head, []byte("https://"))
+}
+
+// listHead is the start of the body without byte order mark or leading
+// blank lines.
+func listHead(br *bufio.Reader) []byte {
+	head, _ := br.Peek(512)
+	return bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\uFEFF")), " \t\r\n")
+}
+
+func isPLS(head []byte) bool {
+	return len(head) >= len("[playlist]") && strings.EqualFold(string(head[:len("[playlist]")]), "[playlist]")
+}
+
+func isStationListURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	return ext == ".pls" || ext == ".m3u"
+}
+
+func isStationListType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "audio/x-scpls", "audio/scpls", "application/pls+xml", "audio/mpegurl", "audio/x-mpegurl":
+		return true
+	}
+	return false
+}
+
+// stationListEntries reads the stream URLs from a .pls or .m3u list, in
+// order. Relative entries are resolved against base.
+func stationListEntries(r io.Reader, base *url.URL) ([]string, error) {
+	br := bufio.NewReader(r)
+	pls := isPLS(listHead(br))
+
+	var entries []string
+	sc := bufio.NewScanner(br)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\uFEFF"))
+		if pls {
+			// PLS lists streams as File1=..., File2=...
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || !strings.HasPrefix(strings.ToLower(key), "file") {
+				continue
+			}
+			line = strings.TrimSpace(value)
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := base.Parse(line)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		entries = append(entries, u.String())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading playlist: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jfreymuth/pulse"
+)
+
+// PulseSink plays through PulseAudio, or PipeWire's PulseAudio server,
+// speaking the native protocol in Go so no C libraries are needed.
+type PulseSink struct {
+	// Device is a sink name; empty uses the server default.
+	Device string
+
+	client *pulse.Client
+	stream *pulse.PlaybackStream
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []float32
+	closed  bool
+}
+
+// pulseBuffer is how many samples Write may queue before blocking, about
+// a quarter second at the output format.
+const pulseBuffer = 44100 / 2
+
+func (s *PulseSink) Open(f Format) error {
+	client, err := pulse.NewClient(pulse.ClientApplicationName("radio"))
+	if err != nil {
+		return fmt.Errorf("connecting to PulseAudio: %w", err)
+	}
+
+	opts := []pulse.PlaybackOption{
+		pulse.PlaybackSampleRate(f.SampleRate),
+		pulse.PlaybackLatency(0.2),
+		pulse.PlaybackMediaName("radio"),
+	}
+	if f.Channels == 1 {
+		opts = append(opts, pulse.PlaybackMono)
+	} else {
+		opts = append(opts, pulse.PlaybackStereo)
+	}
+	if s.Device != "" {
+		sink, err := client.SinkByID(s.Device)
+		if err != nil {
+			client.Close()
+			return fmt.Errorf("finding output device %q: %w", s.Device, err)
+		}
+		opts = append(opts, pulse.PlaybackSink(sink))
+	}
+
+	s.cond = sync.NewCond(&s.mu)
+	s.closed = false
+	s.pending = nil
+
+	stream, err := client.NewPlayback(pulse.Float32Reader(s.read), opts...)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("opening PulseAudio stream: %w", err)
+	}
+	s.client = client
+	s.stream = stream
+	stream.Start()
+	return nil
+}
+
+// read is called by the pulse client when the server wants more audio.
+func (s *PulseSink) read(out []float32) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, pulse.EndOfData
+	}
+	n := copy(out, s.pending)
+	s.pending = s.pending[n:]
+	// Underrun: play silence rather than stall the server.
+	for i := n; i < len(out); i++ {
+		out[i] = 0
+	}
+	s.cond.Broadcast()
+	return len(out), nil
+}
+
+func (s *PulseSink) Write(samples []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.pending) > pulseBuffer && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return errors.New("output closed")
+	}
+	if err := s.stream.Error(); err != nil {
+		return fmt.Errorf("PulseAudio: %w", err)
+	}
+	s.pending = append(s.pending, samples...)
+	return nil
+}
+
+func (s *PulseSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	if s.stream != nil {
+		s.stream.Stop()
+		s.stream.Close()
+	}
+	if s.client != nil {
+		s.client.Close()
+	}
+	return nil
+}
+
+// PulseDevice is a PulseAudio sink; Name is what PulseSink.Device takes.
+type PulseDevice struct {
+	Name        string
+	Description string
+}
+
+// PulseDevices lists the sinks of the PulseAudio server.
+func PulseDevices() ([]PulseDevice, error) {
+	client, err := pulse.NewClient(pulse.ClientApplicationName("radio"))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to PulseAudio: %w", err)
+	}
+	defer client.Close()
+
+	sinks, err := client.ListSinks()
+	if err != nil {
+		return nil, fmt.Errorf("listing PulseAudio sinks: %w", err)
+	}
+	devices := make([]PulseDevice, 0, len(sinks))
+	for _, s := range sinks {
+		devices = append(devices, PulseDevice{Name: s.ID(), Description: s.Name()})
+	}
+	return devices, nil
+}
@@ -0,0 +1,98 @@
+package audio
+
+import "io"
+
+// resampler converts a decoder's output to another rate and channel count
+// by linear interpolation, which is transparent enough for radio.
+type resampler struct {
+	src Decoder
+	in  Format
+	out Format
+
+	buf  []float32
+	pos  float64 // read position in source frames, relative to buf
+	step float64
+	eof  error
+}
+
+func newResampler(src Decoder, out Format) Decoder {
+	in := src.Format()
+	if in == out {
+		return src
+	}
+	return &resampler{src: src, in: in, out: out, step: float64(in.SampleRate) / float64(out.SampleRate)}
+}
+
+func (r *resampler) Format() Format {
+	return r.out
+}
+
+// fill makes sure at least two source frames are buffered past pos.
+func (r *resampler) fill() {
+	for r.eof == nil && len(r.buf)/r.in.Channels < int(r.pos)+2 {
+		// Drop consumed frames before reading more.
+		if drop := int(r.pos); drop > 0 {
+			r.buf = append(r.buf[:0], r.buf[drop*r.in.Channels:]...)
+			r.pos -= float64(drop)
+		}
+		chunk := make([]float32, 1024*r.in.Channels)
+		n, err := r.src.Read(chunk)
+		r.buf = append(r.buf, chunk[:n]...)
+		if err != nil {
+			r.eof = err
+		}
+	}
+}
+
+func (r *resampler) sample(frame, ch int) float32 {
+	if r.in.Channels == 1 {
+		return r.buf[frame]
+	}
+	if ch >= r.in.Channels {
+		ch = r.in.Channels - 1
+	}
+	return r.buf[frame*r.in.Channels+ch]
+}
+
+// frameValue is channel ch of the output at the current position, mixing
+// down to mono when needed.
+func (r *resampler) frameValue(ch int) float32 {
+	i := int(r.pos)
+	frac := float32(r.pos - float64(i))
+
+	value := func(frame int) float32 {
+		if r.out.Channels == 1 && r.in.Channels > 1 {
+			var sum float32
+			for c := 0; c < r.in.Channels; c++ {
+				sum += r.buf[frame*r.in.Channels+c]
+			}
+			return sum / float32(r.in.Channels)
+		}
+		return r.sample(frame, ch)
+	}
+
+	a := value(i)
+	if (i+1)*r.in.Channels >= len(r.buf) {
+		return a
+	}
+	return a + (value(i+1)-a)*frac
+}
+
+func (r *resampler) Read(out []float32) (int, error) {
+	n := 0
+	for n+r.out.Channels <= len(out) {
+		r.fill()
+		if int(r.pos)*r.in.Channels >= len(r.buf) {
+			if r.eof == nil {
+				r.eof = io.EOF
+			}
+			return n, r.eof
+		}
+		for ch := 0; ch < r.out.Channels; ch++ {
+			out[n+ch] = r.frameValue(ch)
+		}
+		n += r.out.Channels
+		r.pos += r.step
+	}
+	return n, nil
+}
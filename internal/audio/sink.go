@@ -0,0 +1,135 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// NullSink discards audio, counting what it was given. With Realtime set
+// it sleeps as a device would, which keeps a live stream from being read
+// faster than it plays.
+type NullSink struct {
+	Realtime bool
+
+	mu      sync.Mutex
+	format  Format
+	samples int64
+}
+
+func (s *NullSink) Open(f Format) error {
+	s.mu.Lock()
+	s.format = f
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *NullSink) Write(samples []float32) error {
+	s.mu.Lock()
+	s.samples += int64(len(samples))
+	f := s.format
+	s.mu.Unlock()
+
+	if s.Realtime && f.SampleRate > 0 && f.Channels > 0 {
+		time.Sleep(time.Duration(len(samples)/f.Channels) * time.Second / time.Duration(f.SampleRate))
+	}
+	return nil
+}
+
+func (s *NullSink) Close() error { return nil }
+
+// Frames is how many sample frames were written.
+func (s *NullSink) Frames() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.format.Channels == 0 {
+		return 0
+	}
+	return s.samples / int64(s.format.Channels)
+}
+
+// WAVSink writes 16-bit PCM WAV. The header sizes are filled in on Close,
+// which needs a seekable file.
+type WAVSink struct {
+	w      io.WriteSeeker
+	closer io.Closer
+	format Format
+	bytes  uint32
+	buf    []byte
+}
+
+func NewWAVSink(w io.WriteSeeker) *WAVSink {
+	return &WAVSink{w: w}
+}
+
+// CreateWAV opens a WAV sink writing to path.
+func CreateWAV(path string) (*WAVSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &WAVSink{w: f, closer: f}, nil
+}
+
+func (s *WAVSink) Open(f Format) error {
+	s.format = f
+	return s.writeHeader()
+}
+
+func (s *WAVSink) writeHeader() error {
+	f := s.format
+	h := make([]byte, 44)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], 36+s.bytes)
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], uint16(f.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(f.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(f.SampleRate*f.Channels*2))
+	binary.LittleEndian.PutUint16(h[32:], uint16(f.Channels*2))
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], s.bytes)
+	_, err := s.w.Write(h)
+	return err
+}
+
+func (s *WAVSink) Write(samples []float32) error {
+	if s.format.Channels == 0 {
+		return errors.New("wav sink not opened")
+	}
+	if cap(s.buf) < len(samples)*2 {
+		s.buf = make([]byte, len(samples)*2)
+	}
+	buf := s.buf[:len(samples)*2]
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(toInt16(v)))
+	}
+	n, err := s.w.Write(buf)
+	s.bytes += uint32(n)
+	return err
+}
+
+func (s *WAVSink) Close() error {
+	var err error
+	if s.format.Channels > 0 {
+		if _, err = s.w.Seek(0, io.SeekStart); err == nil {
+			err = s.writeHeader()
+		}
+	}
+	if s.closer != nil {
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func toInt16(v float32) int16 {
+	return int16(math.Max(-32768, math.Min(32767, float64(v)*32767)))
+}
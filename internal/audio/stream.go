@@ -0,0 +1,184 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+
+	"radio/internal/dash"
+	"radio/internal/hls"
+	"radio/internal/icy"
+)
+
+// Stream is an open station stream with in-band metadata removed.
+type Stream struct {
+	io.Reader
+	Codec       string
+	ContentType string
+	// Headers holds the icy-* response headers, lower-cased.
+	Headers map[string]string
+	// Variant is the rendition picked for an HLS stream.
+	Variant *hls.Variant
+
+	body io.Closer
+}
+
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
+
+// OpenStream requests url asking for ICY metadata, which is passed to
+// onMeta as it arrives. HLS playlists are followed, choosing the best
+// variant within maxBandwidth bits per second (0 for no limit), and so
+// are .pls and .m3u station lists.
+func OpenStream(ctx context.Context, client *http.Client, url string, maxBandwidth int, onMeta func(string)) (*Stream, error) {
+	return openStream(ctx, client, url, maxBandwidth, onMeta, 0)
+}
+
+func openStream(ctx context.Context, client *http.Client, url string, maxBandwidth int, onMeta func(string), depth int) (*Stream, error) {
+	if hls.IsPlaylistURL(url) {
+		return openHLS(ctx, client, url, maxBandwidth)
+	}
+	// DASH radio is AAC in fragmented MP4, which is not decoded here.
+	if dash.IsManifestURL(url) {
+		return nil, unsupported("DASH")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("connecting to stream: unexpected status %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if dash.IsManifestType(contentType) {
+		resp.Body.Close()
+		return nil, unsupported("DASH")
+	}
+
+	s := &Stream{
+		ContentType: contentType,
+		Headers:     make(map[string]string),
+		body:        resp.Body,
+	}
+	for k, v := range resp.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "icy-") && len(v) > 0 {
+			s.Headers[k] = v[0]
+		}
+	}
+
+	br := bufio.NewReaderSize(resp.Body, 8192)
+	// Some servers send playlists as text/plain or octet-stream; the
+	// station's hls flag is the only other hint, so look at the body.
+	// audio/x-mpegurl is used for plain M3U station lists too.
+	list := isStationList(br, url, contentType)
+	if isHLSPlaylist(br) || hls.IsPlaylistType(contentType) && !list {
+		resp.Body.Close()
+		return openHLS(ctx, client, url, maxBandwidth)
+	}
+	if list {
+		defer resp.Body.Close()
+		return followStationList(ctx, client, url, br, maxBandwidth, onMeta, depth)
+	}
+	head, _ := br.Peek(4)
+	s.Codec = DetectCodec(s.ContentType, head)
+	s.Reader = br
+	// Metadata is stripped above the buffer, so onMeta is called when
+	// reading reaches it, not when the buffer happens to read ahead.
+	if metaint, _ := strconv.Atoi(resp.Header.Get("icy-metaint")); metaint > 0 {
+		s.Reader = icy.NewReader(br, metaint, onMeta)
+	}
+	return s, nil
+}
+
+// followStationList opens the first stream in a station list that
+// answers; later entries are usually mirrors.
+func followStationList(ctx context.Context, client *http.Client, listURL string, body io.Reader, maxBandwidth int, onMeta func(string), depth int) (*Stream, error) {
+	if depth >= maxStationListDepth {
+		return nil, fmt.Errorf("playlist %s: too many nested playlists", listURL)
+	}
+	base, err := neturl.Parse(listURL)
+	if err != nil {
+		return nil, fmt.Errorf("playlist %s: %w", listURL, err)
+	}
+	entries, err := stationListEntries(io.LimitReader(body, 64<<10), base)
+	if err != nil {
+		return nil, fmt.Errorf("playlist %s: %w", listURL, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("playlist %s lists no streams", listURL)
+	}
+
+	var s *Stream
+	for _, entry := range entries {
+		if s, err = openStream(ctx, client, entry, maxBandwidth, onMeta, depth+1); err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return s, err
+}
+
+// isHLSPlaylist reports whether the body is an HLS playlist: an M3U file
+// using HLS tags, which plain M3U station lists don't have.
+func isHLSPlaylist(br *bufio.Reader) bool {
+	head, _ := br.Peek(512)
+	return bytes.HasPrefix(head, []byte("#EXTM3U")) && bytes.Contains(head, []byte("#EXT-X-"))
+}
+
+// openHLS reads an HLS stream. Transport stream segments are demuxed to
+// their audio; packed audio segments are used as they are.
+func openHLS(ctx context.Context, client *http.Client, url string, maxBandwidth int) (*Stream, error) {
+	hs, err := hls.Open(ctx, client, url, maxBandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("opening HLS stream: %w", err)
+	}
+
+	s := &Stream{
+		ContentType: "application/vnd.apple.mpegurl",
+		Headers:     make(map[string]string),
+		Variant:     hs.Variant,
+		body:        hs,
+	}
+
+	br := bufio.NewReaderSize(hs, 8192)
+	head, err := br.Peek(4)
+	if err != nil {
+		hs.Close()
+		return nil, fmt.Errorf("reading HLS stream: %w", err)
+	}
+	if head[0] != 0x47 {
+		s.Codec = DetectCodec("", head)
+		s.Reader = br
+		return s, nil
+	}
+
+	ts := hls.NewTSReader(br)
+	if err := ts.Probe(); err != nil {
+		hs.Close()
+		return nil, fmt.Errorf("reading HLS stream: %w", err)
+	}
+	switch ts.StreamType {
+	case hls.StreamMPEG1Audio, hls.StreamMPEG2Audio:
+		s.Codec = CodecMP3
+	case hls.StreamAACADTS, hls.StreamAACLATM:
+		s.Codec = CodecAAC
+	}
+	s.Reader = ts
+	return s, nil
+}
@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net"
 	"net/http"
 	"net/url"
 	"time"
+
+	"radio/internal/netconf"
 )
 
 type Station struct {
 	Name        string `json:"name"`
 	URL         string `json:"url"`
 	Country     string `json:"country"`
+	CountryCode string `json:"countrycode"`
 	Codec       string `json:"codec"`
 	Bitrate     int    `json:"bitrate"`
 	Tags        string `json:"tags"`
@@ -22,41 +26,79 @@ type Station struct {
 	Homepage    string `json:"homepage"`
 	Favicon     string `json:"favicon"`
 	ClickCount  int    `json:"clickcount"`
+	Votes       int    `json:"votes"`
 	LastCheckOK int    `json:"lastcheckok"`
+	// HLS is 1 for stations that stream over HLS.
+	HLS int `json:"hls"`
 }
 
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	limiter    *limiter
+
+	// MaxRetries is how often a read is retried after a network error,
+	// 429 or 5xx.
+	MaxRetries int
+	// BaseDelay is the first backoff, doubled per retry up to MaxDelay.
+	// A longer Retry-After from the server is honored up to MaxDelay;
+	// beyond that the request fails with ErrRateLimited or ErrServer.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
 }
 
+const (
+	defaultMaxRetries = 3
+	defaultBaseDelay  = 500 * time.Millisecond
+	defaultMaxDelay   = 10 * time.Second
+	// The API is run by volunteers; stay well below anything abusive.
+	defaultRate  = 2
+	defaultBurst = 5
+)
+
 func (s Station) FilterValue() string {
 	return s.Name
 }
 
-func NewClient(baseURL string, timeout time.Duration) *Client {
+// NewClient talks to the radio-browser API. A nil network uses the proxy
+// environment variables and the system CAs.
+func NewClient(baseURL string, timeout time.Duration, network *netconf.Network) *Client {
 	if baseURL == "" {
 		baseURL = "https://de1.api.radio-browser.info/json"
 	}
+	if network == nil {
+		network = netconf.Default()
+	}
+
+	transport := network.Transport()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.MaxIdleConns = 100
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	transport.ExpectContinueTimeout = 1 * time.Second
 
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout:   5 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).DialContext,
-				MaxIdleConns:          100,
-				IdleConnTimeout:       90 * time.Second,
-				TLSHandshakeTimeout:   5 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-			},
+			Timeout:   timeout,
+			Transport: transport,
 		},
+		limiter:    newLimiter(defaultRate, defaultBurst),
+		MaxRetries: defaultMaxRetries,
+		BaseDelay:  defaultBaseDelay,
+		MaxDelay:   defaultMaxDelay,
 	}
 }
 
+// SetRateLimit allows perSecond requests on average and burst at once;
+// 0 turns limiting off.
+func (c *Client) SetRateLimit(perSecond float64, burst int) {
+	c.limiter = newLimiter(perSecond, burst)
+}
+
 func (c *Client) SearchStations(ctx context.Context, filters map[string]string) ([]Station, error) {
 	if len(filters) == 0 {
 		return nil, errors.New("filters must not be empty")
@@ -67,28 +109,83 @@ func (c *Client) SearchStations(ctx context.Context, filters map[string]string)
 		query.Set(k, v)
 	}
 
-	endpoint := fmt.Sprintf("%s/stations/search?%s", c.baseURL, query.Encode())
+	var stations []Station
+	if err := c.get(ctx, "/stations/search", query, &stations); err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// get fetches path and decodes the JSON answer into v. Being idempotent,
+// it is retried with backoff when that may help.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := c.getOnce(ctx, endpoint, v)
+		if err == nil || !retryable(err) || attempt >= c.MaxRetries {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > 0 {
+			if se.RetryAfter > c.MaxDelay {
+				return err
+			}
+			delay = se.RetryAfter
+			if se.StatusCode == http.StatusTooManyRequests {
+				c.limiter.pause(delay)
+			}
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff doubles BaseDelay per attempt up to MaxDelay, with jitter so
+// clients do not retry in step.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.BaseDelay << attempt
+	if d <= 0 || d > c.MaxDelay {
+		d = c.MaxDelay
+	}
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+func (c *Client) getOnce(ctx context.Context, endpoint string, v any) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("creating request: %w", err)
 	}
-
 	req.Header.Set("User-Agent", "RadioTerminal/1.0")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("performing request: %w", err)
+		// A search that was superseded is not a network problem.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+		return &NetworkError{Err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
-	var stations []Station
-	if err := json.NewDecoder(resp.Body).Decode(&stations); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
 	}
-
-	return stations, nil
+	return nil
 }
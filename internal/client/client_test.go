@@ -2,9 +2,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -26,7 +28,7 @@ func TestClient_SearchStations(t *testing.T) {
 		}))
 		defer server.Close()
 
-		client := NewClient(server.URL, 5*time.Second)
+		client := NewClient(server.URL, 5*time.Second, nil)
 		stations, err := client.SearchStations(context.Background(), map[string]string{
 			"name": "rock",
 		})
@@ -45,7 +47,7 @@ func TestClient_SearchStations(t *testing.T) {
 
 	// Пустой query
 	t.Run("empty query", func(t *testing.T) {
-		client := NewClient("http://example.com", 5*time.Second)
+		client := NewClient("http://example.com", 5*time.Second, nil)
 		_, err := client.SearchStations(context.Background(), map[string]string{})
 		if err == nil {
 			t.Fatal("expected error for empty query, got nil")
@@ -58,7 +60,7 @@ func TestClient_SearchStations(t *testing.T) {
 		}))
 		defer server.Close()
 
-		client := NewClient(server.URL, 5*time.Second)
+		client := NewClient(server.URL, 5*time.Second, nil)
 		_, err := client.SearchStations(context.Background(), map[string]string{
 			"name": "rock",
 		})
@@ -75,7 +77,7 @@ func TestClient_SearchStations(t *testing.T) {
 		}))
 		defer server.Close()
 
-		client := NewClient(server.URL, 5*time.Second)
+		client := NewClient(server.URL, 5*time.Second, nil)
 		_, err := client.SearchStations(context.Background(), map[string]string{
 			"name": "rock",
 		})
@@ -85,7 +87,7 @@ func TestClient_SearchStations(t *testing.T) {
 	})
 
 	t.Run("request error", func(t *testing.T) {
-		client := NewClient("http://invalid-host", 1*time.Second)
+		client := NewClient("http://invalid-host", 1*time.Second, nil)
 		_, err := client.SearchStations(context.Background(), map[string]string{
 			"name": "rock",
 		})
@@ -94,3 +96,157 @@ func TestClient_SearchStations(t *testing.T) {
 		}
 	})
 }
+
+// statusServer answers with the given statuses in turn, then 200 with an
+// empty list.
+func statusServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "nope", statuses[n-1])
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func fastClient(url string) *Client {
+	c := NewClient(url, 5*time.Second, nil)
+	c.BaseDelay = time.Millisecond
+	c.SetRateLimit(0, 0)
+	return c
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	search := func(c *Client) error {
+		_, err := c.SearchStations(context.Background(), map[string]string{"name": "rock"})
+		return err
+	}
+
+	t.Run("not found is not retried", func(t *testing.T) {
+		server, calls := statusServer(t, "", http.StatusNotFound)
+		err := search(fastClient(server.URL))
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected 1 call, got %d", calls.Load())
+		}
+	})
+
+	t.Run("server errors are retried", func(t *testing.T) {
+		server, calls := statusServer(t, "", http.StatusServiceUnavailable, http.StatusBadGateway)
+		if err := search(fastClient(server.URL)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("expected 3 calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("retries give up", func(t *testing.T) {
+		server, calls := statusServer(t, "", 500, 500, 500, 500, 500)
+		c := fastClient(server.URL)
+		c.MaxRetries = 2
+		if err := search(c); !errors.Is(err, ErrServer) {
+			t.Fatalf("expected ErrServer, got %v", err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("expected 3 calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("retry-after is honored", func(t *testing.T) {
+		server, calls := statusServer(t, "1", http.StatusTooManyRequests)
+		start := time.Now()
+		if err := search(fastClient(server.URL)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+			t.Errorf("retried after %s, before Retry-After", elapsed)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("expected 2 calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("long retry-after fails at once", func(t *testing.T) {
+		server, calls := statusServer(t, "120", http.StatusTooManyRequests)
+		err := search(fastClient(server.URL))
+		var se *StatusError
+		if !errors.Is(err, ErrRateLimited) || !errors.As(err, &se) || se.RetryAfter != 2*time.Minute {
+			t.Fatalf("expected ErrRateLimited with a 2m Retry-After, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected 1 call, got %d", calls.Load())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		}))
+		defer server.Close()
+
+		c := NewClient(server.URL, 50*time.Millisecond, nil)
+		c.MaxRetries = 0
+		err := search(c)
+		if !errors.Is(err, ErrTimeout) || !errors.Is(err, ErrNetwork) {
+			t.Fatalf("expected a timeout network error, got %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		server, _ := statusServer(t, "", 503, 503, 503)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := fastClient(server.URL).SearchStations(ctx, map[string]string{"name": "rock"})
+		if !errors.Is(err, context.Canceled) || errors.Is(err, ErrNetwork) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-5":                            0,
+		"soon":                          0,
+		"Wed, 01 May 2024 12:00:45 GMT": 45 * time.Second,
+		"Wed, 01 May 2024 11:00:00 GMT": 0,
+	}
+	for h, want := range tests {
+		if got := parseRetryAfter(h, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", h, got, want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(2, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if wait := l.reserve(now); wait != 0 {
+			t.Fatalf("burst request %d waited %s", i, wait)
+		}
+	}
+	if wait := l.reserve(now); wait != 500*time.Millisecond {
+		t.Errorf("third request should wait 500ms, got %s", wait)
+	}
+	if wait := l.reserve(now.Add(time.Second)); wait != 0 {
+		t.Errorf("after refilling, got %s", wait)
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMissingFileUsesDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.Backend != "json" {
+		t.Errorf("expected json backend, got %q", cfg.Storage.Backend)
+	}
+	if cfg.Storage.ResolvedPath() != "./jsonfile/favorites.json" {
+		t.Errorf("unexpected default path: %s", cfg.Storage.ResolvedPath())
+	}
+}
+
+func TestLoadOverridesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"storage": {"backend": "sqlite"}}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.Backend != "sqlite" {
+		t.Errorf("expected sqlite backend, got %q", cfg.Storage.Backend)
+	}
+	if cfg.Storage.ResolvedPath() != "./jsonfile/radio.db" {
+		t.Errorf("unexpected sqlite path: %s", cfg.Storage.ResolvedPath())
+	}
+}
+
+func TestLoadInvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+}
+
+func TestStreamConfigOverride(t *testing.T) {
+	global := StreamConfig{CacheMB: 32, TimeoutSeconds: 10, UserAgent: "radio"}
+	got := global.Override(StreamConfig{TimeoutSeconds: 30, Proxy: "http://proxy:3128"})
+
+	want := StreamConfig{CacheMB: 32, TimeoutSeconds: 30, UserAgent: "radio", Proxy: "http://proxy:3128"}
+	if got != want {
+		t.Errorf("Override = %+v, want %+v", got, want)
+	}
+	if global.Override(StreamConfig{}) != global {
+		t.Error("an empty override should change nothing")
+	}
+}
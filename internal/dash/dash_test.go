@@ -0,0 +1,84 @@
+package dash
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const liveManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" profiles="urn:dvb:dash:profile:dvb-dash:2014">
+  <Period id="1">
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" audioSamplingRate="48000">
+      <SegmentTemplate timescale="48000" media="$RepresentationID$/$Number$.m4s" initialization="$RepresentationID$/init.mp4" duration="307200"/>
+      <Representation id="96k" bandwidth="96000" codecs="mp4a.40.5"/>
+      <Representation id="320k" bandwidth="320000" codecs="mp4a.40.2" audioSamplingRate="44100"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="v" bandwidth="1500000" codecs="avc1.4d401f"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(liveManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Live || len(m.Audio) != 2 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	low, high := m.Audio[0], m.Audio[1]
+	if low.ID != "96k" || low.Bandwidth != 96000 || low.Codecs != "mp4a.40.5" || low.SampleRate != 48000 {
+		t.Errorf("unexpected representation %+v", low)
+	}
+	if high.SampleRate != 44100 {
+		t.Errorf("representation sample rate %d, want its own 44100", high.SampleRate)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"not xml":  "#EXTM3U\n",
+		"no audio": `<MPD><Period><AdaptationSet mimeType="video/mp4"><Representation id="v" bandwidth="1"/></AdaptationSet></Period></MPD>`,
+		"empty":    `<MPD></MPD>`,
+	} {
+		if _, err := Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/live.mpd" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dash+xml")
+		w.Write([]byte(liveManifest))
+	}))
+	defer srv.Close()
+
+	m, err := Fetch(context.Background(), srv.Client(), srv.URL+"/live.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Audio) != 2 {
+		t.Errorf("got %d audio representations, want 2", len(m.Audio))
+	}
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/gone.mpd"); err == nil {
+		t.Error("expected an error for a missing manifest")
+	}
+}
+
+func TestIsManifest(t *testing.T) {
+	if !IsManifestURL("https://example.com/live/stream.MPD?token=1") || IsManifestURL("https://example.com/live.m3u8") {
+		t.Error("IsManifestURL misjudged a URL")
+	}
+	if !IsManifestType("application/dash+xml; charset=utf-8") || IsManifestType("audio/mpeg") {
+		t.Error("IsManifestType misjudged a type")
+	}
+}
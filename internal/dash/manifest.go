@@ -0,0 +1,145 @@
+// Package dash reads MPEG-DASH manifests far enough to list the audio
+// representations of a station, so one can be picked by bandwidth. The
+// segments themselves are left to mpv.
+package dash
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Representation is one encoding of the audio a manifest offers.
+type Representation struct {
+	ID         string
+	Bandwidth  int
+	Codecs     string
+	SampleRate int
+}
+
+// Manifest is the part of an MPD this package uses.
+type Manifest struct {
+	// Live is set for dynamic presentations.
+	Live bool
+	// Audio lists the audio representations of the last period, which is
+	// the one playing in a live presentation.
+	Audio []Representation
+}
+
+type mpdXML struct {
+	XMLName xml.Name    `xml:"MPD"`
+	Type    string      `xml:"type,attr"`
+	Periods []periodXML `xml:"Period"`
+}
+
+type periodXML struct {
+	AdaptationSets []adaptationSetXML `xml:"AdaptationSet"`
+}
+
+type adaptationSetXML struct {
+	ContentType     string              `xml:"contentType,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	SampleRate      int                 `xml:"audioSamplingRate,attr"`
+	Representations []representationXML `xml:"Representation"`
+}
+
+type representationXML struct {
+	ID         string `xml:"id,attr"`
+	Bandwidth  int    `xml:"bandwidth,attr"`
+	MimeType   string `xml:"mimeType,attr"`
+	Codecs     string `xml:"codecs,attr"`
+	SampleRate int    `xml:"audioSamplingRate,attr"`
+}
+
+// IsManifestURL reports whether rawURL names a DASH manifest by extension.
+func IsManifestURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(u.Path), ".mpd")
+}
+
+// IsManifestType reports whether a Content-Type is a DASH manifest.
+func IsManifestType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/dash+xml"
+}
+
+// Parse reads a manifest. Attributes set on an adaptation set apply to
+// its representations unless they set their own.
+func Parse(r io.Reader) (*Manifest, error) {
+	var doc mpdXML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("not a DASH manifest: %w", err)
+	}
+	if len(doc.Periods) == 0 {
+		return nil, errors.New("DASH manifest has no periods")
+	}
+
+	m := &Manifest{Live: doc.Type == "dynamic"}
+	for _, as := range doc.Periods[len(doc.Periods)-1].AdaptationSets {
+		for _, rx := range as.Representations {
+			rep := Representation{
+				ID:         rx.ID,
+				Bandwidth:  rx.Bandwidth,
+				Codecs:     firstNonEmpty(rx.Codecs, as.Codecs),
+				SampleRate: rx.SampleRate,
+			}
+			if rep.SampleRate == 0 {
+				rep.SampleRate = as.SampleRate
+			}
+			if isAudio(as.ContentType, firstNonEmpty(rx.MimeType, as.MimeType)) {
+				m.Audio = append(m.Audio, rep)
+			}
+		}
+	}
+	if len(m.Audio) == 0 {
+		return nil, errors.New("DASH manifest has no audio")
+	}
+	return m, nil
+}
+
+func isAudio(contentType, mimeType string) bool {
+	return contentType == "audio" || strings.HasPrefix(mimeType, "audio/")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Fetch downloads and parses the manifest at rawURL.
+func Fetch(ctx context.Context, client *http.Client, rawURL string) (*Manifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching manifest: unexpected status %d", resp.StatusCode)
+	}
+
+	m, err := Parse(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", rawURL, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,115 @@
+// Package eq holds equalizer settings and applies them, either as an mpv
+// audio filter chain or directly to samples for the native player.
+package eq
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Bands are the centre frequencies of the ten bands, one octave apart.
+var Bands = [10]float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+// MaxGain bounds band gains, in dB either way.
+const MaxGain = 12
+
+// Gains are per-band gains in dB.
+type Gains [10]float64
+
+type Preset struct {
+	Name  string
+	Gains Gains
+}
+
+// Custom is the preset name for hand-tuned gains.
+const Custom = "Custom"
+
+var Presets = []Preset{
+	{Name: "Flat"},
+	{Name: "Bass", Gains: Gains{6, 5, 4, 2, 0, 0, 0, 0, 0, 0}},
+	{Name: "Treble", Gains: Gains{0, 0, 0, 0, 0, 1, 2, 4, 5, 6}},
+	{Name: "Vocal", Gains: Gains{-2, -2, -1, 0, 2, 4, 4, 2, 0, -1}},
+	{Name: "Rock", Gains: Gains{4, 3, 2, 0, -1, -1, 0, 2, 3, 4}},
+}
+
+// Settings is what is applied to playback and remembered per station.
+type Settings struct {
+	Preset string `json:"preset"`
+	Gains  Gains  `json:"gains"`
+	// Normalize evens out loudness within a stream.
+	Normalize bool `json:"normalize"`
+}
+
+// Flat leaves the audio unchanged.
+func Flat() Settings {
+	return Settings{Preset: Presets[0].Name}
+}
+
+// FromPreset returns the settings for a named preset, ignoring case.
+func FromPreset(name string) (Settings, bool) {
+	for _, p := range Presets {
+		if strings.EqualFold(p.Name, name) {
+			return Settings{Preset: p.Name, Gains: p.Gains}, true
+		}
+	}
+	return Settings{}, false
+}
+
+// NextPreset switches to the preset after the current one, keeping
+// normalization as it is. Custom gains move on to the first preset.
+func (s Settings) NextPreset() Settings {
+	next := 0
+	for i, p := range Presets {
+		if p.Name == s.Preset {
+			next = (i + 1) % len(Presets)
+		}
+	}
+	return Settings{Preset: Presets[next].Name, Gains: Presets[next].Gains, Normalize: s.Normalize}
+}
+
+// AdjustBand changes one band by delta dB, making the settings Custom.
+func (s Settings) AdjustBand(band int, delta float64) Settings {
+	if band < 0 || band >= len(s.Gains) {
+		return s
+	}
+	s.Gains[band] = math.Max(-MaxGain, math.Min(MaxGain, s.Gains[band]+delta))
+	s.Preset = Custom
+	return s
+}
+
+// Active reports whether the settings change the audio at all.
+func (s Settings) Active() bool {
+	return s.Normalize || s.Gains != Gains{}
+}
+
+// Label is a short description for the status bar, e.g. "Bass + norm".
+func (s Settings) Label() string {
+	name := s.Preset
+	if name == "" {
+		name = Custom
+	}
+	if s.Normalize {
+		name += " + norm"
+	}
+	return name
+}
+
+// MPVFilter is the value for mpv's --af option: one peaking equalizer per
+// non-zero band and dynaudnorm for normalization. It is empty when the
+// settings do nothing.
+func MPVFilter(s Settings) string {
+	var filters []string
+	for i, g := range s.Gains {
+		if g != 0 {
+			filters = append(filters, fmt.Sprintf("equalizer=f=%g:t=o:w=1:g=%g", Bands[i], g))
+		}
+	}
+	if s.Normalize {
+		filters = append(filters, "dynaudnorm=f=250:g=15")
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return "lavfi=[" + strings.Join(filters, ",") + "]"
+}
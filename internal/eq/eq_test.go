@@ -0,0 +1,105 @@
+package eq
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMPVFilter(t *testing.T) {
+	if got := MPVFilter(Flat()); got != "" {
+		t.Errorf("flat filter = %q, want empty", got)
+	}
+
+	s := Flat().AdjustBand(0, 3).AdjustBand(9, -2.5)
+	s.Normalize = true
+	want := "lavfi=[equalizer=f=31:t=o:w=1:g=3,equalizer=f=16000:t=o:w=1:g=-2.5,dynaudnorm=f=250:g=15]"
+	if got := MPVFilter(s); got != want {
+		t.Errorf("MPVFilter = %q\nwant %q", got, want)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	s, ok := FromPreset("bass")
+	if !ok || s.Preset != "Bass" || s.Gains[0] != 6 {
+		t.Fatalf("FromPreset(bass) = %+v, %v", s, ok)
+	}
+	if _, ok := FromPreset("nope"); ok {
+		t.Error("expected an unknown preset to fail")
+	}
+
+	s.Normalize = true
+	seen := map[string]bool{}
+	for range Presets {
+		seen[s.Preset] = true
+		s = s.NextPreset()
+		if !s.Normalize {
+			t.Fatal("NextPreset dropped normalization")
+		}
+	}
+	if len(seen) != len(Presets) || s.Preset != "Bass" {
+		t.Errorf("cycling visited %v and ended on %q", seen, s.Preset)
+	}
+
+	custom := s.AdjustBand(2, 100)
+	if custom.Preset != Custom || custom.Gains[2] != MaxGain {
+		t.Errorf("AdjustBand = %+v", custom)
+	}
+	if next := custom.NextPreset(); next.Preset != Presets[0].Name {
+		t.Errorf("Custom cycled to %q", next.Preset)
+	}
+	if Flat().Active() || !custom.Active() {
+		t.Error("Active misreported")
+	}
+}
+
+// sineGain runs seconds of a sine through p and returns output amplitude
+// over input amplitude in the last half second.
+func sineGain(p *Processor, freq float64, amp float32, seconds int) float64 {
+	const rate = 44100
+	var peak float64
+	buf := make([]float32, rate)
+	for n := 0; n < seconds*rate; n += rate / 2 {
+		for i := 0; i < rate/2; i++ {
+			v := amp * float32(math.Sin(2*math.Pi*freq*float64(n+i)/rate))
+			buf[2*i], buf[2*i+1] = v, v
+		}
+		p.Process(buf)
+		peak = 0
+		for _, v := range buf {
+			peak = math.Max(peak, math.Abs(float64(v)))
+		}
+	}
+	return peak / float64(amp)
+}
+
+func TestProcessorGain(t *testing.T) {
+	s := Flat().AdjustBand(5, 6)
+	tests := []struct {
+		freq, want float64
+	}{
+		{1000, math.Pow(10, 6.0/20)},
+		{62, 1},
+		{16000, 1},
+	}
+	for _, tt := range tests {
+		got := sineGain(NewProcessor(s, 44100, 2), tt.freq, 0.1, 1)
+		if math.Abs(got-tt.want) > 0.05 {
+			t.Errorf("%g Hz: gain %.3f, want %.3f", tt.freq, got, tt.want)
+		}
+	}
+
+	if got := sineGain(NewProcessor(Flat(), 44100, 2), 440, 0.5, 1); math.Abs(got-1) > 1e-6 {
+		t.Errorf("flat processor changed the level: %.4f", got)
+	}
+}
+
+func TestNormalizerRaisesQuietAudio(t *testing.T) {
+	s := Flat()
+	s.Normalize = true
+	if got := sineGain(NewProcessor(s, 44100, 2), 440, 0.02, 10); got < 4 {
+		t.Errorf("quiet signal gain %.2f, want it boosted", got)
+	}
+	if got := sineGain(NewProcessor(s, 44100, 2), 440, 0.9, 10); got > 1 {
+		t.Errorf("loud signal gain %.2f, want no boost", got)
+	}
+}
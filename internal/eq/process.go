@@ -0,0 +1,127 @@
+package eq
+
+import "math"
+
+// Processor applies Settings to interleaved float32 samples in place.
+type Processor struct {
+	channels int
+	// bands holds a filter per band and channel, skipping flat bands.
+	bands [][]biquad
+	norm  *normalizer
+}
+
+// NewProcessor prepares the filters for one sample rate and channel count.
+func NewProcessor(s Settings, sampleRate, channels int) *Processor {
+	p := &Processor{channels: channels}
+	for i, g := range s.Gains {
+		if g == 0 || Bands[i] >= float64(sampleRate)/2 {
+			continue
+		}
+		coeffs := peaking(Bands[i], g, float64(sampleRate))
+		perChannel := make([]biquad, channels)
+		for c := range perChannel {
+			perChannel[c] = coeffs
+		}
+		p.bands = append(p.bands, perChannel)
+	}
+	if s.Normalize {
+		p.norm = newNormalizer(sampleRate)
+	}
+	return p
+}
+
+func (p *Processor) Process(samples []float32) {
+	for i := range samples {
+		ch := i % p.channels
+		x := float64(samples[i])
+		for _, band := range p.bands {
+			x = band[ch].process(x)
+		}
+		samples[i] = float32(x)
+	}
+	if p.norm != nil {
+		p.norm.process(samples, p.channels)
+	}
+	for i, v := range samples {
+		samples[i] = float32(math.Max(-1, math.Min(1, float64(v))))
+	}
+}
+
+// biquad is a second-order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// peaking is the RBJ cookbook peaking filter, one octave wide.
+func peaking(freq, gainDB, sampleRate float64) biquad {
+	a := math.Pow(10, gainDB/40)
+	w0 := 2 * math.Pi * freq / sampleRate
+	alpha := math.Sin(w0) * math.Sinh(math.Ln2/2*w0/math.Sin(w0))
+	cos := math.Cos(w0)
+
+	a0 := 1 + alpha/a
+	return biquad{
+		b0: (1 + alpha*a) / a0,
+		b1: -2 * cos / a0,
+		b2: (1 - alpha*a) / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha/a) / a0,
+	}
+}
+
+// normalizer is a slow automatic gain control that pulls the level
+// towards a target, like mpv's dynaudnorm but much simpler.
+type normalizer struct {
+	target  float64
+	maxGain float64
+	// attack and release are per-sample smoothing factors for the level
+	// estimate when it rises and falls.
+	attack, release float64
+	level           float64
+}
+
+func newNormalizer(sampleRate int) *normalizer {
+	coeff := func(seconds float64) float64 {
+		return 1 - math.Exp(-1/(seconds*float64(sampleRate)))
+	}
+	return &normalizer{
+		target:  0.2, // about -14 dBFS RMS
+		maxGain: 8,   // +18 dB
+		attack:  coeff(0.5),
+		release: coeff(3),
+		level:   0.2,
+	}
+}
+
+func (n *normalizer) process(samples []float32, channels int) {
+	for i := 0; i+channels <= len(samples); i += channels {
+		var power float64
+		for c := 0; c < channels; c++ {
+			v := float64(samples[i+c])
+			power += v * v
+		}
+		rms := math.Sqrt(power / float64(channels))
+
+		k := n.release
+		if rms > n.level {
+			k = n.attack
+		}
+		n.level += (rms - n.level) * k
+
+		gain := n.maxGain
+		if n.level > 0 {
+			gain = math.Min(n.maxGain, n.target/n.level)
+		}
+		for c := 0; c < channels; c++ {
+			samples[i+c] = float32(float64(samples[i+c]) * gain)
+		}
+	}
+}
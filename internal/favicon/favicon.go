@@ -0,0 +1,143 @@
+// Package favicon fetches station logos and draws them in the terminal.
+package favicon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"radio/internal/storage"
+)
+
+// maxSize caps downloads; station logos are small and some "favicons" are
+// full-size photos.
+const maxSize = 2 << 20
+
+// maxDimension caps the width and height of images decoded, as a small
+// compressed file can still claim a huge canvas.
+const maxDimension = 4096
+
+var ErrNoFavicon = errors.New("station has no favicon")
+
+// Cache downloads favicons and keeps them on disk for TTL.
+type Cache struct {
+	dir    string
+	client *http.Client
+	TTL    time.Duration
+}
+
+func NewCache(dir string, client *http.Client) *Cache {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Cache{dir: dir, client: client, TTL: 7 * 24 * time.Hour}
+}
+
+// DefaultDir is the favicon cache under the user's cache directory.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "radio", "favicons")
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16]))
+}
+
+// Get returns the decoded favicon, from disk when fresh.
+func (c *Cache) Get(ctx context.Context, url string) (image.Image, error) {
+	if url == "" {
+		return nil, ErrNoFavicon
+	}
+
+	path := c.path(url)
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < c.TTL {
+		if data, err := os.ReadFile(path); err == nil {
+			if img, err := Decode(data); err == nil {
+				return img, nil
+			}
+		}
+	}
+
+	data, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err == nil {
+		_ = storage.WriteFileAtomic(path, data, 0644)
+	}
+	return img, nil
+}
+
+func (c *Cache) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching favicon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching favicon: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading favicon: %w", err)
+	}
+	if len(data) > maxSize {
+		return nil, errors.New("favicon too large")
+	}
+	return data, nil
+}
+
+// Decode reads PNG, JPEG, GIF and ICO images.
+func Decode(data []byte) (image.Image, error) {
+	if isICO(data) {
+		return decodeICO(data)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding favicon: %w", err)
+	}
+	if err := checkDimensions(cfg); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding favicon: %w", err)
+	}
+	return img, nil
+}
+
+func checkDimensions(cfg image.Config) error {
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return fmt.Errorf("decoding favicon: image too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+	return nil
+}
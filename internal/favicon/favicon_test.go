@@ -0,0 +1,192 @@
+package favicon
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func testImage(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func pngBytes(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// icoFile wraps entries in an .ico directory; each entry is (size, data).
+func icoFile(sizes []int, entries [][]byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, uint16(len(entries))})
+	offset := 6 + 16*len(entries)
+	for i, e := range entries {
+		buf.Write([]byte{byte(sizes[i]), byte(sizes[i]), 0, 0})
+		binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(e)), uint32(offset)})
+		offset += len(e)
+	}
+	for _, e := range entries {
+		buf.Write(e)
+	}
+	return buf.Bytes()
+}
+
+func dib32(w, h int, c color.NRGBA) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{40, uint32(w), uint32(h * 2)})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+	binary.Write(&buf, binary.LittleEndian, make([]uint32, 6))
+	for i := 0; i < w*h; i++ {
+		buf.Write([]byte{c.B, c.G, c.R, c.A})
+	}
+	// AND mask, ignored for 32-bit.
+	buf.Write(make([]byte, ((w+31)/32*4)*h))
+	return buf.Bytes()
+}
+
+func TestDecodeICO(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	data := icoFile([]int{16, 32}, [][]byte{dib32(16, 16, red), pngBytes(t, testImage(32, 32, blue))})
+	img, err := Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Bounds().Dx() != 32 {
+		t.Errorf("expected the largest entry, got %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	data = icoFile([]int{16}, [][]byte{dib32(16, 16, red)})
+	img, err = Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(3, 3)).(color.NRGBA); c != red {
+		t.Errorf("expected red, got %v", c)
+	}
+}
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	if _, err := Decode([]byte("<svg></svg>")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestDecodeRejectsHugeImages(t *testing.T) {
+	// A one-pixel-high strip compresses to almost nothing.
+	data := pngBytes(t, testImage(maxDimension+1, 1, color.NRGBA{A: 255}))
+	if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("expected a size error, got %v", err)
+	}
+	if _, err := Decode(pngBytes(t, testImage(maxDimension, 1, color.NRGBA{A: 255}))); err != nil {
+		t.Errorf("expected %d pixels wide to decode, got %v", maxDimension, err)
+	}
+}
+
+func TestCacheFetchesOnce(t *testing.T) {
+	var hits atomic.Int32
+	body := pngBytes(t, testImage(8, 8, color.NRGBA{G: 255, A: 255}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	c := NewCache(t.TempDir(), nil)
+	for i := 0; i < 2; i++ {
+		img, err := c.Get(context.Background(), srv.URL+"/logo.png")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if img.Bounds().Dx() != 8 {
+			t.Errorf("unexpected size %v", img.Bounds())
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected one download, got %d", hits.Load())
+	}
+
+	if _, err := c.Get(context.Background(), ""); err != ErrNoFavicon {
+		t.Errorf("expected ErrNoFavicon, got %v", err)
+	}
+}
+
+func TestRenderHalfBlock(t *testing.T) {
+	out := Render(testImage(20, 10, color.NRGBA{R: 255, A: 255}), HalfBlock, 8, 4)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(lines))
+	}
+	if n := strings.Count(lines[0], "▀"); n != 8 {
+		t.Errorf("expected 8 cells per row, got %d", n)
+	}
+	if !strings.Contains(out, "38;2;255;0;0") {
+		t.Error("expected red foreground in the middle rows")
+	}
+}
+
+func TestRenderKittyChunks(t *testing.T) {
+	// Noise does not compress, so the PNG spans several chunks.
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	rng := rand.New(rand.NewSource(1))
+	rng.Read(img.Pix)
+	out := Render(img, Kitty, 10, 5)
+	if !strings.HasPrefix(out, "\x1b_Ga=T,f=100") {
+		t.Fatalf("unexpected start %q", out[:20])
+	}
+	if strings.Count(out, "\x1b_G") < 2 {
+		t.Error("expected the image to be chunked")
+	}
+	if !strings.Contains(out, "m=0;") {
+		t.Error("expected a final chunk")
+	}
+}
+
+func TestRenderSixel(t *testing.T) {
+	out := Render(testImage(4, 4, color.NRGBA{R: 255, A: 255}), Sixel, 2, 1)
+	if !strings.HasPrefix(out, "\x1bPq\"1;1;16;16") || !strings.Contains(out, "\x1b\\") {
+		t.Errorf("unexpected sixel output %q", out)
+	}
+}
+
+func TestDetectProtocol(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	tests := []struct {
+		vars     map[string]string
+		expected Protocol
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{map[string]string{"TERM_PROGRAM": "iTerm.app"}, ITerm2},
+		{map[string]string{"TERM": "foot"}, Sixel},
+		{map[string]string{"TERM": "xterm-256color"}, HalfBlock},
+		{map[string]string{"TERM": "xterm-kitty", "RADIO_IMAGE_PROTOCOL": "halfblock"}, HalfBlock},
+	}
+	for _, tt := range tests {
+		if got := DetectProtocol(env(tt.vars)); got != tt.expected {
+			t.Errorf("DetectProtocol(%v) = %s, expected %s", tt.vars, got, tt.expected)
+		}
+	}
+}
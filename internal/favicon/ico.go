@@ -0,0 +1,123 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+func isICO(data []byte) bool {
+	return len(data) >= 6 && binary.LittleEndian.Uint16(data[0:]) == 0 && binary.LittleEndian.Uint16(data[2:]) == 1
+}
+
+// decodeICO picks the largest image in an .ico file. Entries are either
+// embedded PNGs or BMP DIBs; only uncompressed 24 and 32-bit DIBs are
+// supported, which covers what sites serve today.
+func decodeICO(data []byte) (image.Image, error) {
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errors.New("decoding favicon: truncated ico")
+	}
+
+	best, bestSize := -1, -1
+	for i := 0; i < count; i++ {
+		e := data[6+16*i:]
+		w, h := int(e[0]), int(e[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		if w*h > bestSize {
+			best, bestSize = i, w*h
+		}
+	}
+
+	e := data[6+16*best:]
+	size := int(binary.LittleEndian.Uint32(e[8:]))
+	offset := int(binary.LittleEndian.Uint32(e[12:]))
+	if offset < 0 || size <= 0 || offset+size > len(data) {
+		return nil, errors.New("decoding favicon: ico entry out of range")
+	}
+	entry := data[offset : offset+size]
+
+	if bytes.HasPrefix(entry, pngMagic) {
+		cfg, err := png.DecodeConfig(bytes.NewReader(entry))
+		if err != nil {
+			return nil, fmt.Errorf("decoding favicon: %w", err)
+		}
+		if err := checkDimensions(cfg); err != nil {
+			return nil, err
+		}
+		img, err := png.Decode(bytes.NewReader(entry))
+		if err != nil {
+			return nil, fmt.Errorf("decoding favicon: %w", err)
+		}
+		return img, nil
+	}
+	return decodeDIB(entry)
+}
+
+func decodeDIB(d []byte) (image.Image, error) {
+	if len(d) < 40 {
+		return nil, errors.New("decoding favicon: truncated bitmap")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(d[0:]))
+	width := int(int32(binary.LittleEndian.Uint32(d[4:])))
+	// The height covers the colour rows and the AND mask.
+	height := int(int32(binary.LittleEndian.Uint32(d[8:]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(d[14:]))
+	compression := binary.LittleEndian.Uint32(d[16:])
+
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 {
+		return nil, fmt.Errorf("decoding favicon: bad bitmap size %dx%d", width, height)
+	}
+	if compression != 0 || (bpp != 24 && bpp != 32) {
+		return nil, fmt.Errorf("decoding favicon: unsupported %d-bit bitmap", bpp)
+	}
+
+	bytesPerPixel := bpp / 8
+	stride := (width*bytesPerPixel + 3) &^ 3
+	pixels := d[headerSize:]
+	if len(pixels) < stride*height {
+		return nil, errors.New("decoding favicon: truncated bitmap")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		// Rows are stored bottom-up.
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			p := row[x*bytesPerPixel:]
+			a := uint8(255)
+			if bpp == 32 {
+				a = p[3]
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: p[2], G: p[1], B: p[0], A: a})
+		}
+	}
+
+	// Old 32-bit icons leave the alpha byte zero and rely on the mask.
+	if bpp == 32 && allTransparent(img) {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+	}
+	return img, nil
+}
+
+func allTransparent(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
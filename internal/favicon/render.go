@@ -0,0 +1,206 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// Protocol is how an image is drawn in the terminal.
+type Protocol int
+
+const (
+	HalfBlock Protocol = iota
+	Kitty
+	ITerm2
+	Sixel
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case Kitty:
+		return "kitty"
+	case ITerm2:
+		return "iterm2"
+	case Sixel:
+		return "sixel"
+	}
+	return "halfblock"
+}
+
+// DetectProtocol guesses what the terminal supports from its environment.
+// RADIO_IMAGE_PROTOCOL overrides the guess, since sixel support cannot be
+// told from the environment reliably.
+func DetectProtocol(getenv func(string) string) Protocol {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	switch strings.ToLower(getenv("RADIO_IMAGE_PROTOCOL")) {
+	case "kitty":
+		return Kitty
+	case "iterm2", "iterm":
+		return ITerm2
+	case "sixel":
+		return Sixel
+	case "halfblock", "blocks":
+		return HalfBlock
+	}
+
+	term := getenv("TERM")
+	program := getenv("TERM_PROGRAM")
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty":
+		return Kitty
+	case program == "iTerm.app" || program == "WezTerm":
+		return ITerm2
+	case strings.Contains(term, "sixel") || term == "foot" || term == "mlterm" || program == "mlterm":
+		return Sixel
+	}
+	return HalfBlock
+}
+
+// Cell size assumed when converting a cell box to pixels for sixel.
+const (
+	cellWidth  = 8
+	cellHeight = 16
+)
+
+// Render draws img in a box of cols x rows terminal cells. The result ends
+// with the cursor on the line below the image.
+func Render(img image.Image, p Protocol, cols, rows int) string {
+	switch p {
+	case Kitty:
+		return renderKitty(img, cols, rows)
+	case ITerm2:
+		return renderITerm2(img, cols, rows)
+	case Sixel:
+		return renderSixel(scale(img, cols*cellWidth, rows*cellHeight, color.Black))
+	}
+	return renderHalfBlock(img, cols, rows)
+}
+
+func encodePNG(img image.Image) string {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// renderKitty transmits the PNG in 4096-byte chunks as the protocol
+// requires. Reusing one image id makes a redraw replace the previous one.
+func renderKitty(img image.Image, cols, rows int) string {
+	data := encodePNG(img)
+
+	var b strings.Builder
+	for i := 0; i < len(data); i += 4096 {
+		end := i + 4096
+		more := 1
+		if end >= len(data) {
+			end, more = len(data), 0
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,i=7,p=1,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, data[i:end])
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+	// C=1 leaves the cursor in place; reserve the rows ourselves.
+	b.WriteString(strings.Repeat("\n", rows))
+	return b.String()
+}
+
+func renderITerm2(img image.Image, cols, rows int) string {
+	return fmt.Sprintf("\x1b]1337;File=inline=1;width=%d;height=%d;preserveAspectRatio=1:%s\a\n", cols, rows, encodePNG(img))
+}
+
+// renderHalfBlock uses "▀" with the upper pixel as foreground and the lower
+// as background, so each cell shows two pixels.
+func renderHalfBlock(img image.Image, cols, rows int) string {
+	px := scale(img, cols, rows*2, color.Black)
+
+	var b strings.Builder
+	for y := 0; y < rows*2; y += 2 {
+		for x := 0; x < cols; x++ {
+			top := px.NRGBAAt(x, y)
+			bottom := px.NRGBAAt(x, y+1)
+			fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+// scale resizes img to w x h by averaging, keeping its aspect ratio and
+// centring it, and flattens transparency onto bg.
+func scale(img image.Image, w, h int, bg color.Color) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	br, bgG, bb, _ := bg.RGBA()
+	for i := 0; i < len(out.Pix); i += 4 {
+		out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = uint8(br>>8), uint8(bgG>>8), uint8(bb>>8), 255
+	}
+
+	src := img.Bounds()
+	if src.Dx() == 0 || src.Dy() == 0 {
+		return out
+	}
+
+	// Fit inside w x h.
+	tw, th := w, src.Dy()*w/src.Dx()
+	if th > h {
+		tw, th = src.Dx()*h/src.Dy(), h
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+	ox, oy := (w-tw)/2, (h-th)/2
+
+	for y := 0; y < th; y++ {
+		y0 := src.Min.Y + y*src.Dy()/th
+		y1 := src.Min.Y + (y+1)*src.Dy()/th
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < tw; x++ {
+			x0 := src.Min.X + x*src.Dx()/tw
+			x1 := src.Min.X + (x+1)*src.Dx()/tw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(img.At(sx, sy)).(color.NRGBA)
+					r += uint32(c.R) * uint32(c.A)
+					g += uint32(c.G) * uint32(c.A)
+					b += uint32(c.B) * uint32(c.A)
+					a += uint32(c.A)
+					n++
+				}
+			}
+
+			alpha := a / n
+			var pr, pg, pb uint32
+			if a > 0 {
+				pr, pg, pb = r/a, g/a, b/a
+			}
+			blend := func(fg uint32, bgc uint32) uint8 {
+				return uint8((fg*alpha + (bgc>>8)*(255-alpha)) / 255)
+			}
+			out.SetNRGBA(ox+x, oy+y, color.NRGBA{R: blend(pr, br), G: blend(pg, bgG), B: blend(pb, bb), A: 255})
+		}
+	}
+	return out
+}
+
+// ClearKitty removes images drawn by Render from a Kitty terminal; other
+// protocols are overwritten by the next frame.
+const ClearKitty = "\x1b_Ga=d,d=i,i=7,q=2\x1b\\"
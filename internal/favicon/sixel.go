@@ -0,0 +1,86 @@
+package favicon
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// renderSixel encodes img with a fixed 6x6x6 colour cube, which is plenty
+// for a logo and avoids a quantisation pass.
+func renderSixel(img *image.NRGBA) string {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	var b strings.Builder
+	b.WriteString("\x1bPq")
+	fmt.Fprintf(&b, "\"1;1;%d;%d", w, h)
+
+	index := func(x, y int) int {
+		c := img.NRGBAAt(x, y)
+		return int(c.R)*5/255*36 + int(c.G)*5/255*6 + int(c.B)*5/255
+	}
+
+	used := make([]bool, 216)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			used[index(x, y)] = true
+		}
+	}
+	for i, ok := range used {
+		if ok {
+			// Sixel colours are percentages.
+			fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20)
+		}
+	}
+
+	for band := 0; band < h; band += 6 {
+		first := true
+		for c := 0; c < 216; c++ {
+			if !used[c] {
+				continue
+			}
+			row := make([]byte, w)
+			hasBits := false
+			for x := 0; x < w; x++ {
+				var bits byte
+				for i := 0; i < 6 && band+i < h; i++ {
+					if index(x, band+i) == c {
+						bits |= 1 << i
+					}
+				}
+				row[x] = '?' + bits
+				hasBits = hasBits || bits != 0
+			}
+			if !hasBits {
+				continue
+			}
+			if !first {
+				b.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&b, "#%d", c)
+			writeRLE(&b, row)
+		}
+		b.WriteByte('-')
+	}
+
+	b.WriteString("\x1b\\\n")
+	return b.String()
+}
+
+// writeRLE writes a sixel row using "!<count><char>" for runs.
+func writeRLE(b *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(b, "!%d%c", n, row[i])
+		} else {
+			b.Write(row[i:j])
+		}
+		i = j
+	}
+}
@@ -0,0 +1,72 @@
+package favsync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"radio/internal/storage"
+)
+
+// DirRemote syncs through a plain directory, typically one shared by a
+// network mount or a file sync tool.
+type DirRemote struct {
+	Path string
+}
+
+func NewDirRemote(path string) *DirRemote {
+	return &DirRemote{Path: path}
+}
+
+func (r *DirRemote) Name() string {
+	return "dir:" + r.Path
+}
+
+func (r *DirRemote) file() string {
+	return filepath.Join(r.Path, FileName)
+}
+
+func (r *DirRemote) Pull(ctx context.Context) (*Snapshot, string, error) {
+	data, err := os.ReadFile(r.file())
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", r.file(), err)
+	}
+
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return snap, contentRevision(data), nil
+}
+
+func (r *DirRemote) Push(ctx context.Context, snap *Snapshot, revision string) error {
+	if err := os.MkdirAll(r.Path, os.ModePerm); err != nil {
+		return fmt.Errorf("creating %s: %w", r.Path, err)
+	}
+
+	current, err := os.ReadFile(r.file())
+	switch {
+	case os.IsNotExist(err):
+		if revision != "" {
+			return ErrConflict
+		}
+	case err != nil:
+		return fmt.Errorf("reading %s: %w", r.file(), err)
+	case contentRevision(current) != revision:
+		return ErrConflict
+	}
+
+	data, err := encodeSnapshot(snap)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.WriteFileAtomic(r.file(), data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", r.file(), err)
+	}
+	return nil
+}
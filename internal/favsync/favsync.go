@@ -0,0 +1,179 @@
+package favsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"radio/internal/storage"
+	"radio/pkg/logger"
+)
+
+type Strategy string
+
+const (
+	// LastWriterWins settles a conflicting station by its newest change.
+	LastWriterWins Strategy = "lww"
+	// ThreeWay keeps the local version of a conflicting station and
+	// reports the conflict.
+	ThreeWay Strategy = "three-way"
+)
+
+const maxAttempts = 3
+
+// Result summarizes one sync run.
+type Result struct {
+	// Pulled are the changes applied to the local store.
+	Pulled storage.ChangeEvent
+	// Pushed is true when the remote was updated.
+	Pushed    bool
+	Conflicts []storage.Conflict
+}
+
+// Syncer reconciles a Store with a Remote. The last snapshot both sides
+// agreed on is kept in the store's settings as the merge base.
+type Syncer struct {
+	store    storage.Store
+	remote   Remote
+	strategy Strategy
+	device   string
+	now      func() time.Time
+}
+
+func New(store storage.Store, remote Remote, strategy Strategy) *Syncer {
+	if strategy == "" {
+		strategy = LastWriterWins
+	}
+	device, _ := os.Hostname()
+	return &Syncer{
+		store:    store,
+		remote:   remote,
+		strategy: strategy,
+		device:   device,
+		now:      time.Now,
+	}
+}
+
+func (s *Syncer) baseKey() string {
+	return "sync.base." + s.remote.Name()
+}
+
+func (s *Syncer) loadBase() map[string]storage.FavoriteStation {
+	base := make(map[string]storage.FavoriteStation)
+	raw, ok := s.store.GetSetting(s.baseKey())
+	if !ok {
+		return base
+	}
+	if err := json.Unmarshal([]byte(raw), &base); err != nil {
+		logger.Log.Warn().Err(err).Msg("Discarding unreadable sync base")
+		return make(map[string]storage.FavoriteStation)
+	}
+	return base
+}
+
+func (s *Syncer) saveBase(favorites map[string]storage.FavoriteStation) error {
+	data, err := json.Marshal(favorites)
+	if err != nil {
+		return err
+	}
+	return s.store.SetSetting(s.baseKey(), string(data))
+}
+
+func (s *Syncer) resolver(remoteSavedAt time.Time) storage.Resolver {
+	if s.strategy == ThreeWay {
+		return storage.PreferLocal
+	}
+
+	now := s.now()
+	return func(c storage.Conflict) (storage.FavoriteStation, bool) {
+		// A deletion carries no timestamp of its own; date it by when the
+		// side that deleted it last saved.
+		localAt, remoteAt := now, remoteSavedAt
+		if c.Local != nil {
+			localAt = c.Local.UpdatedAt
+		}
+		if c.Remote != nil {
+			remoteAt = c.Remote.UpdatedAt
+		}
+		if remoteAt.After(localAt) {
+			return storage.PreferRemote(c)
+		}
+		return storage.PreferLocal(c)
+	}
+}
+
+// Sync pulls the remote snapshot, merges it with local favorites and pushes
+// the result back, retrying when another device pushed in between.
+func (s *Syncer) Sync(ctx context.Context) (Result, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := s.syncOnce(ctx)
+		if errors.Is(err, ErrConflict) && attempt < maxAttempts {
+			logger.Log.Info().Msgf("Remote %s changed during sync, retrying (%d/%d)", s.remote.Name(), attempt, maxAttempts)
+			continue
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Msgf("Sync with %s failed", s.remote.Name())
+			return res, err
+		}
+		logger.Log.Info().Msgf("Synced with %s: %d added, %d removed, %d updated, %d conflicts, pushed=%v",
+			s.remote.Name(), len(res.Pulled.Added), len(res.Pulled.Removed), len(res.Pulled.Updated), len(res.Conflicts), res.Pushed)
+		return res, nil
+	}
+}
+
+func (s *Syncer) syncOnce(ctx context.Context) (Result, error) {
+	var res Result
+
+	snap, revision, err := s.remote.Pull(ctx)
+	if err != nil {
+		return res, fmt.Errorf("pulling from %s: %w", s.remote.Name(), err)
+	}
+
+	remote := make(map[string]storage.FavoriteStation)
+	var remoteSavedAt time.Time
+	if snap != nil {
+		remote = snap.Favorites
+		remoteSavedAt = snap.SavedAt
+	}
+
+	base := s.loadBase()
+	local := s.store.FavoritesSnapshot()
+
+	merged, conflicts := storage.MergeFavorites(base, local, remote, s.resolver(remoteSavedAt))
+	res.Conflicts = conflicts
+
+	// The remote goes first: if the push fails or loses a race, nothing
+	// changes locally and the next attempt merges against the same base.
+	if snap == nil || !storage.DiffFavorites(remote, merged).Empty() {
+		out := &Snapshot{
+			Version:   snapshotVersion,
+			SavedAt:   s.now().UTC(),
+			Device:    s.device,
+			Favorites: merged,
+		}
+		switch err := s.remote.Push(ctx, out, revision); {
+		case errors.Is(err, ErrUnchanged):
+		case err != nil:
+			return res, err
+		default:
+			res.Pushed = true
+		}
+	}
+
+	// Only what the sync changed is applied, so favorites added or
+	// removed while the remote was busy survive; the next sync pushes them.
+	if pulled := storage.DiffFavorites(local, merged); !pulled.Empty() {
+		if err := s.store.PatchFavorites(local, merged); err != nil {
+			return res, fmt.Errorf("applying remote changes: %w", err)
+		}
+		res.Pulled = pulled
+	}
+
+	if err := s.saveBase(merged); err != nil {
+		return res, fmt.Errorf("saving sync base: %w", err)
+	}
+	return res, nil
+}
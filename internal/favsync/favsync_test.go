@@ -0,0 +1,219 @@
+package favsync
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+	"radio/internal/storage"
+)
+
+func newStore(t *testing.T) storage.Store {
+	t.Helper()
+	s, err := storage.NewStorage(filepath.Join(t.TempDir(), "favorites.json"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	return s
+}
+
+func station(url, name string) client.Station {
+	return client.Station{URL: url, Name: name, Bitrate: 128}
+}
+
+func mustSync(t *testing.T, s *Syncer) Result {
+	t.Helper()
+	res, err := s.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	return res
+}
+
+func TestSyncTwoDevicesThroughDir(t *testing.T) {
+	remote := NewDirRemote(t.TempDir())
+	laptop, desktop := newStore(t), newStore(t)
+	laptopSync := New(laptop, remote, LastWriterWins)
+	desktopSync := New(desktop, remote, LastWriterWins)
+
+	laptop.AddFavorite(station("http://a", "A"))
+	if res := mustSync(t, laptopSync); !res.Pushed {
+		t.Error("expected first sync to push")
+	}
+
+	desktop.AddFavorite(station("http://b", "B"))
+	res := mustSync(t, desktopSync)
+	if len(res.Pulled.Added) != 1 || res.Pulled.Added[0] != "http://a" {
+		t.Errorf("expected desktop to pull A, got %+v", res.Pulled)
+	}
+	if !res.Pushed {
+		t.Error("expected desktop to push B")
+	}
+
+	mustSync(t, laptopSync)
+	if !laptop.IsFavorite("http://b") {
+		t.Error("expected laptop to receive B")
+	}
+
+	laptop.RemoveFavorite("http://a")
+	mustSync(t, laptopSync)
+	mustSync(t, desktopSync)
+	if desktop.IsFavorite("http://a") {
+		t.Error("expected deletion to propagate to desktop")
+	}
+
+	if res := mustSync(t, desktopSync); res.Pushed || !res.Pulled.Empty() {
+		t.Errorf("expected no-op sync when nothing changed, got %+v", res)
+	}
+}
+
+func TestSyncConflictStrategies(t *testing.T) {
+	setup := func(t *testing.T, strategy Strategy) (storage.Store, storage.Store, *Syncer, *Syncer) {
+		remote := NewDirRemote(t.TempDir())
+		a, b := newStore(t), newStore(t)
+		sa, sb := New(a, remote, strategy), New(b, remote, strategy)
+
+		a.AddFavorite(station("http://x", "Original"))
+		mustSync(t, sa)
+		mustSync(t, sb)
+
+		// b edits first, a edits later: a is the last writer.
+		b.AddFavorite(station("http://x", "From B"))
+		time.Sleep(5 * time.Millisecond)
+		a.AddFavorite(station("http://x", "From A"))
+		mustSync(t, sb)
+		return a, b, sa, sb
+	}
+
+	t.Run("last writer wins", func(t *testing.T) {
+		a, b, sa, sb := setup(t, LastWriterWins)
+		res := mustSync(t, sa)
+		if len(res.Conflicts) != 1 {
+			t.Fatalf("expected 1 conflict, got %d", len(res.Conflicts))
+		}
+		mustSync(t, sb)
+		for _, s := range []storage.Store{a, b} {
+			if got := s.FavoritesSnapshot()["http://x"].Name; got != "From A" {
+				t.Errorf("expected newer edit to win, got %q", got)
+			}
+		}
+	})
+
+	t.Run("three-way keeps local", func(t *testing.T) {
+		a, _, sa, _ := setup(t, ThreeWay)
+		res := mustSync(t, sa)
+		if len(res.Conflicts) != 1 {
+			t.Fatalf("expected 1 conflict, got %d", len(res.Conflicts))
+		}
+		if got := a.FavoritesSnapshot()["http://x"].Name; got != "From A" {
+			t.Errorf("expected local edit to be kept, got %q", got)
+		}
+	})
+}
+
+// racingRemote lets another device push between our pull and push once.
+type racingRemote struct {
+	Remote
+	race func()
+}
+
+func (r *racingRemote) Push(ctx context.Context, snap *Snapshot, revision string) error {
+	if r.race != nil {
+		race := r.race
+		r.race = nil
+		race()
+	}
+	return r.Remote.Push(ctx, snap, revision)
+}
+
+func TestSyncRetriesOnConflict(t *testing.T) {
+	dir := NewDirRemote(t.TempDir())
+	other := newStore(t)
+	other.AddFavorite(station("http://other", "Other"))
+	otherSync := New(other, dir, LastWriterWins)
+
+	local := newStore(t)
+	local.AddFavorite(station("http://local", "Local"))
+	remote := &racingRemote{Remote: dir, race: func() { mustSync(t, otherSync) }}
+
+	mustSync(t, New(local, remote, LastWriterWins))
+
+	if !local.IsFavorite("http://other") {
+		t.Error("expected retry to pull the racing push")
+	}
+	snap, _, _ := dir.Pull(context.Background())
+	if _, ok := snap.Favorites["http://local"]; !ok {
+		t.Error("expected retry to push local favorite")
+	}
+}
+
+func TestSyncKeepsEditsMadeDuringSync(t *testing.T) {
+	dir := NewDirRemote(t.TempDir())
+	other := newStore(t)
+	other.AddFavorite(station("http://other", "Other"))
+	mustSync(t, New(other, dir, LastWriterWins))
+
+	local := newStore(t)
+	local.AddFavorite(station("http://local", "Local"))
+	local.AddFavorite(station("http://gone", "Gone"))
+	remote := &racingRemote{Remote: dir, race: func() {
+		// The user edits favorites while the push is in flight.
+		local.AddFavorite(station("http://during", "During"))
+		local.RemoveFavorite("http://gone")
+	}}
+	s := New(local, remote, LastWriterWins)
+	mustSync(t, s)
+
+	for _, url := range []string{"http://other", "http://local", "http://during"} {
+		if !local.IsFavorite(url) {
+			t.Errorf("expected %s to be a favorite after sync", url)
+		}
+	}
+	if local.IsFavorite("http://gone") {
+		t.Error("expected favorite removed during sync to stay removed")
+	}
+
+	if res := mustSync(t, s); !res.Pushed {
+		t.Error("expected next sync to push the edits made during the last one")
+	}
+	snap, _, _ := dir.Pull(context.Background())
+	if _, ok := snap.Favorites["http://during"]; !ok {
+		t.Error("expected favorite added during sync to reach the remote")
+	}
+	if _, ok := snap.Favorites["http://gone"]; ok {
+		t.Error("expected favorite removed during sync to be removed on the remote")
+	}
+}
+
+// failingRemote rejects every push.
+type failingRemote struct {
+	Remote
+}
+
+func (r failingRemote) Push(ctx context.Context, snap *Snapshot, revision string) error {
+	return errors.New("push rejected")
+}
+
+func TestFailedPushLeavesLocalUntouched(t *testing.T) {
+	dir := NewDirRemote(t.TempDir())
+	other := newStore(t)
+	other.AddFavorite(station("http://other", "Other"))
+	mustSync(t, New(other, dir, LastWriterWins))
+
+	local := newStore(t)
+	local.AddFavorite(station("http://local", "Local"))
+	s := New(local, failingRemote{dir}, LastWriterWins)
+	if _, err := s.Sync(context.Background()); err == nil {
+		t.Fatal("expected sync to fail")
+	}
+
+	if local.IsFavorite("http://other") {
+		t.Error("expected remote changes not to be applied after a failed push")
+	}
+	if _, ok := local.GetSetting(s.baseKey()); ok {
+		t.Error("expected the merge base not to move after a failed push")
+	}
+}
@@ -0,0 +1,150 @@
+package favsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitRemote syncs through a file committed to a git repository, using the
+// git command line in a local working copy. Concurrent pushes surface as
+// non-fast-forward rejections and are reported as ErrConflict.
+type GitRemote struct {
+	URL     string
+	Branch  string
+	WorkDir string
+}
+
+func NewGitRemote(url, branch, workDir string) *GitRemote {
+	if branch == "" {
+		branch = "main"
+	}
+	return &GitRemote{URL: url, Branch: branch, WorkDir: workDir}
+}
+
+func (r *GitRemote) Name() string {
+	return "git:" + r.URL
+}
+
+func (r *GitRemote) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (r *GitRemote) ensureClone(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(r.WorkDir, ".git")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.WorkDir), os.ModePerm); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "git", "clone", "--quiet", r.URL, r.WorkDir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (r *GitRemote) remoteRef() string {
+	return "refs/remotes/origin/" + r.Branch
+}
+
+func (r *GitRemote) Pull(ctx context.Context) (*Snapshot, string, error) {
+	if err := r.ensureClone(ctx); err != nil {
+		return nil, "", err
+	}
+	if _, err := r.git(ctx, "fetch", "--quiet", "origin"); err != nil {
+		return nil, "", err
+	}
+
+	revision, err := r.git(ctx, "rev-parse", "--verify", "--quiet", r.remoteRef())
+	if err != nil {
+		// The branch does not exist yet: nothing has been pushed.
+		return nil, "", nil
+	}
+	if _, err := r.git(ctx, "checkout", "--quiet", "-B", r.Branch, r.remoteRef()); err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.WorkDir, FileName))
+	if os.IsNotExist(err) {
+		return nil, revision, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return snap, revision, nil
+}
+
+func (r *GitRemote) Push(ctx context.Context, snap *Snapshot, revision string) error {
+	if err := r.ensureClone(ctx); err != nil {
+		return err
+	}
+
+	if revision == "" {
+		if _, err := r.git(ctx, "symbolic-ref", "HEAD", "refs/heads/"+r.Branch); err != nil {
+			return err
+		}
+	} else if _, err := r.git(ctx, "checkout", "--quiet", "-B", r.Branch, revision); err != nil {
+		return err
+	}
+
+	data, err := encodeSnapshot(snap)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.WorkDir, FileName), data, 0644); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx, "add", FileName); err != nil {
+		return err
+	}
+
+	if status, err := r.git(ctx, "status", "--porcelain", "--", FileName); err != nil {
+		return err
+	} else if status == "" {
+		return ErrUnchanged
+	}
+
+	commit := []string{"commit", "--quiet", "-m", "Sync favorites from " + snap.Device}
+	if email, _ := r.git(ctx, "config", "user.email"); email == "" {
+		commit = append([]string{"-c", "user.name=Terminal Radio", "-c", "user.email=radio@localhost"}, commit...)
+	}
+	if _, err := r.git(ctx, commit...); err != nil {
+		return err
+	}
+
+	if _, err := r.git(ctx, "push", "--quiet", "origin", "HEAD:refs/heads/"+r.Branch); err != nil {
+		if isPushRejected(err) {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func isPushRejected(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "rejected") || strings.Contains(msg, "fetch first") || strings.Contains(msg, "non-fast-forward")
+}
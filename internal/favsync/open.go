@@ -0,0 +1,51 @@
+package favsync
+
+import (
+	"fmt"
+	"net/http"
+
+	"radio/internal/config"
+	"radio/internal/storage"
+)
+
+const defaultGitWorkDir = "./jsonfile/sync-git"
+
+// Open builds a Syncer from config. It returns nil when sync is disabled.
+// The WebDAV remote talks through client.
+func Open(store storage.Store, cfg config.SyncConfig, client *http.Client) (*Syncer, error) {
+	var remote Remote
+	switch cfg.Remote {
+	case "":
+		return nil, nil
+	case "dir":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("sync remote %q needs a path", cfg.Remote)
+		}
+		remote = NewDirRemote(cfg.Path)
+	case "git":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("sync remote %q needs a url", cfg.Remote)
+		}
+		workDir := cfg.Path
+		if workDir == "" {
+			workDir = defaultGitWorkDir
+		}
+		remote = NewGitRemote(cfg.URL, cfg.Branch, workDir)
+	case "webdav":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("sync remote %q needs a url", cfg.Remote)
+		}
+		remote = NewWebDAVRemote(cfg.URL, cfg.Username, cfg.Password, client)
+	default:
+		return nil, fmt.Errorf("unknown sync remote %q", cfg.Remote)
+	}
+
+	strategy := Strategy(cfg.Strategy)
+	switch strategy {
+	case "", LastWriterWins, ThreeWay:
+	default:
+		return nil, fmt.Errorf("unknown sync strategy %q", cfg.Strategy)
+	}
+
+	return New(store, remote, strategy), nil
+}
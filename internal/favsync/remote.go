@@ -0,0 +1,70 @@
+package favsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"radio/internal/storage"
+)
+
+// FileName is the name of the snapshot file kept on every remote.
+const FileName = "favorites-sync.json"
+
+const snapshotVersion = 1
+
+var (
+	// ErrConflict is returned by Push when the remote changed since the
+	// revision the snapshot was based on.
+	ErrConflict = errors.New("remote changed since last pull")
+	// ErrUnchanged is returned by Push when the remote already holds the
+	// snapshot, so nothing was written.
+	ErrUnchanged = errors.New("remote already up to date")
+)
+
+// Snapshot is the favorites set as stored on a remote.
+type Snapshot struct {
+	Version   int                                `json:"version"`
+	SavedAt   time.Time                          `json:"saved_at"`
+	Device    string                             `json:"device"`
+	Favorites map[string]storage.FavoriteStation `json:"favorites"`
+}
+
+// Remote is a place favorites are synced through.
+type Remote interface {
+	Name() string
+	// Pull returns the current snapshot, or nil if the remote has none yet,
+	// and an opaque revision to pass back to Push.
+	Pull(ctx context.Context) (snap *Snapshot, revision string, err error)
+	// Push stores snap if the remote is still at revision, otherwise it
+	// returns ErrConflict. It returns ErrUnchanged if there was nothing to
+	// store.
+	Push(ctx context.Context, snap *Snapshot, revision string) error
+}
+
+func encodeSnapshot(snap *Snapshot) ([]byte, error) {
+	return json.MarshalIndent(snap, "", "  ")
+}
+
+func decodeSnapshot(data []byte) (*Snapshot, error) {
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if snap.Version > snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is newer than supported %d", snap.Version, snapshotVersion)
+	}
+	if snap.Favorites == nil {
+		snap.Favorites = make(map[string]storage.FavoriteStation)
+	}
+	return &snap, nil
+}
+
+func contentRevision(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,174 @@
+package favsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"radio/internal/storage"
+)
+
+func testSnapshot(name string) *Snapshot {
+	return &Snapshot{
+		Version: snapshotVersion,
+		SavedAt: time.Now().UTC(),
+		Device:  "test",
+		Favorites: map[string]storage.FavoriteStation{
+			"http://a": {URL: "http://a", Name: name},
+		},
+	}
+}
+
+// testRemoteContract checks the Pull/Push behaviour every Remote shares.
+// Two instances point at the same remote to act as two devices.
+func testRemoteContract(t *testing.T, a, b Remote) {
+	ctx := context.Background()
+
+	snap, rev, err := a.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Pull on empty remote failed: %v", err)
+	}
+	if snap != nil {
+		t.Fatalf("expected no snapshot on empty remote, got %+v", snap)
+	}
+
+	if err := a.Push(ctx, testSnapshot("first"), rev); err != nil {
+		t.Fatalf("first Push failed: %v", err)
+	}
+
+	snap, revB, err := b.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if snap == nil || snap.Favorites["http://a"].Name != "first" {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	_, revA, _ := a.Pull(ctx)
+	if err := b.Push(ctx, testSnapshot("second"), revB); err != nil {
+		t.Fatalf("second Push failed: %v", err)
+	}
+
+	if err := a.Push(ctx, testSnapshot("stale"), revA); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale push, got %v", err)
+	}
+
+	snap, _, err = a.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if snap.Favorites["http://a"].Name != "second" {
+		t.Errorf("expected second snapshot to win, got %q", snap.Favorites["http://a"].Name)
+	}
+}
+
+func TestDirRemote(t *testing.T) {
+	dir := t.TempDir()
+	testRemoteContract(t, NewDirRemote(dir), NewDirRemote(dir))
+}
+
+func TestGitRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, out)
+	}
+
+	a := NewGitRemote(bare, "main", filepath.Join(root, "a"))
+	b := NewGitRemote(bare, "main", filepath.Join(root, "b"))
+	testRemoteContract(t, a, b)
+
+	// Pushing what the remote already has must not be reported as a push.
+	ctx := context.Background()
+	snap := testSnapshot("third")
+	_, rev, _ := a.Pull(ctx)
+	if err := a.Push(ctx, snap, rev); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	_, rev, _ = a.Pull(ctx)
+	if err := a.Push(ctx, snap, rev); !errors.Is(err, ErrUnchanged) {
+		t.Errorf("expected ErrUnchanged for identical push, got %v", err)
+	}
+}
+
+// davServer is a minimal WebDAV stand-in: GET and PUT of single files with
+// strong ETags and If-Match / If-None-Match preconditions.
+type davServer struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	version int
+	etags   map[string]string
+}
+
+func (d *davServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, exists := d.files[r.URL.Path]
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", d.etags[r.URL.Path])
+		w.Write(data)
+	case http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && (!exists || m != d.etags[r.URL.Path]) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		d.version++
+		d.files[r.URL.Path] = body
+		d.etags[r.URL.Path] = fmt.Sprintf(`"v%d"`, d.version)
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestWebDAVRemote(t *testing.T) {
+	server := httptest.NewServer(&davServer{files: map[string][]byte{}, etags: map[string]string{}})
+	defer server.Close()
+
+	url := server.URL + "/radio/"
+	a := NewWebDAVRemote(url, "user", "secret", server.Client())
+	b := NewWebDAVRemote(url, "user", "secret", server.Client())
+	testRemoteContract(t, a, b)
+}
+
+func TestWebDAVRemoteAuthFailure(t *testing.T) {
+	server := httptest.NewServer(&davServer{files: map[string][]byte{}, etags: map[string]string{}})
+	defer server.Close()
+
+	r := NewWebDAVRemote(server.URL, "user", "wrong", server.Client())
+	if _, _, err := r.Pull(context.Background()); err == nil {
+		t.Fatal("expected error for bad credentials")
+	}
+}
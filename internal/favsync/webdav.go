@@ -0,0 +1,116 @@
+package favsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WebDAVRemote syncs through a single file on a WebDAV server. Concurrent
+// writers are detected with ETag preconditions.
+type WebDAVRemote struct {
+	// URL of the collection that holds the snapshot file.
+	URL      string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+func NewWebDAVRemote(url, username, password string, client *http.Client) *WebDAVRemote {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebDAVRemote{URL: url, Username: username, Password: password, Client: client}
+}
+
+func (r *WebDAVRemote) Name() string {
+	return "webdav:" + r.URL
+}
+
+func (r *WebDAVRemote) fileURL() string {
+	return strings.TrimRight(r.URL, "/") + "/" + FileName
+}
+
+func (r *WebDAVRemote) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.fileURL(), body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+	return req, nil
+}
+
+func (r *WebDAVRemote) Pull(ctx context.Context) (*Snapshot, string, error) {
+	req, err := r.newRequest(ctx, http.MethodGet, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("performing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading response: %w", err)
+	}
+
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	revision := resp.Header.Get("ETag")
+	if revision == "" {
+		revision = contentRevision(data)
+	}
+	return snap, revision, nil
+}
+
+func (r *WebDAVRemote) Push(ctx context.Context, snap *Snapshot, revision string) error {
+	data, err := encodeSnapshot(snap)
+	if err != nil {
+		return err
+	}
+
+	req, err := r.newRequest(ctx, http.MethodPut, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if revision == "" {
+		req.Header.Set("If-None-Match", "*")
+	} else if strings.HasPrefix(revision, `"`) || strings.HasPrefix(revision, `W/"`) {
+		req.Header.Set("If-Match", revision)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("performing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed:
+		return ErrConflict
+	default:
+		return fmt.Errorf("unexpected status: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+}
@@ -0,0 +1,299 @@
+// Package health probes station streams to tell live ones from dead ones.
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"radio/internal/netconf"
+)
+
+// Result is what a probe found out about a stream.
+type Result struct {
+	URL   string
+	Alive bool
+	// Reason says why the stream was judged dead.
+	Reason      string
+	Status      int
+	ContentType string
+	// Format is what the first bytes look like: "mp3", "aac", "ogg",
+	// "flac", "playlist", "hls" or "" when unrecognised.
+	Format string
+	ICY    ICYHeaders
+	// Server is "Icecast", "Shoutcast", "HLS" or the raw Server header.
+	Server string
+	// Bitrate is the measured rate in kbps; 0 when the probe did not read
+	// long enough to tell, e.g. for playlists.
+	Bitrate int
+	Latency time.Duration
+	Checked time.Time
+}
+
+type ICYHeaders struct {
+	Name    string
+	Genre   string
+	URL     string
+	Bitrate int
+	// Description is icy-description, often a station slogan.
+	Description string
+}
+
+// Checker probes streams. The zero value is not usable; use New.
+type Checker struct {
+	client *http.Client
+
+	// Workers is how many streams are probed at once.
+	Workers int
+	// Interval is the minimum time between starting two probes, so large
+	// lists do not hammer servers or the network.
+	Interval time.Duration
+	// Timeout bounds one probe, including the time spent measuring.
+	Timeout time.Duration
+	// Sample is how long audio is read to measure the bitrate.
+	Sample time.Duration
+}
+
+func New(client *http.Client) *Checker {
+	if client == nil {
+		client = netconf.Default().Client(0)
+	}
+	return &Checker{
+		client:   client,
+		Workers:  4,
+		Interval: 100 * time.Millisecond,
+		Timeout:  10 * time.Second,
+		Sample:   3 * time.Second,
+	}
+}
+
+// CheckAll probes urls with a worker pool, sending each result as it is
+// ready. The channel is closed once all are done or ctx is cancelled.
+func (c *Checker) CheckAll(ctx context.Context, urls []string) <-chan Result {
+	results := make(chan Result)
+	jobs := make(chan string)
+
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				res := c.Check(ctx, u)
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		var tick <-chan time.Time
+		if c.Interval > 0 {
+			ticker := time.NewTicker(c.Interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for i, u := range urls {
+			if i > 0 && tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- u:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// Check probes a single stream.
+func (c *Checker) Check(ctx context.Context, url string) Result {
+	res := Result{URL: url, Checked: time.Now()}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return res.dead(fmt.Sprintf("invalid URL: %v", err))
+	}
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if isICYStatus(err) {
+		// A Shoutcast v1 server answered, through a client that cannot
+		// read its status line. It is up, even if nothing more is known.
+		res.Alive = true
+		res.Server = "Shoutcast"
+		res.Latency = time.Since(start)
+		return res
+	}
+	if err != nil {
+		return res.dead(fmt.Sprintf("connecting: %v", err))
+	}
+	defer resp.Body.Close()
+
+	res.Latency = time.Since(start)
+	res.Status = resp.StatusCode
+	res.ContentType = resp.Header.Get("Content-Type")
+	res.ICY = ICYHeaders{
+		Name:  resp.Header.Get("icy-name"),
+		Genre: resp.Header.Get("icy-genre"),
+		URL:   resp.Header.Get("icy-url"),
+
+		Description: resp.Header.Get("icy-description"),
+	}
+	res.ICY.Bitrate, _ = strconv.Atoi(strings.TrimSpace(strings.Split(resp.Header.Get("icy-br"), ",")[0]))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return res.dead(fmt.Sprintf("HTTP %d", resp.StatusCode))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.ContentType)
+	if mediaType == "text/html" {
+		return res.dead("web page, not a stream")
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadAtLeast(resp.Body, head, 4)
+	head = head[:n]
+	if n == 0 {
+		if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return res.dead("no data")
+		}
+		return res.dead(fmt.Sprintf("reading: %v", err))
+	}
+
+	res.Format = sniff(head, mediaType)
+	res.Server = serverType(resp.Header, res.Format)
+	switch res.Format {
+	case "playlist", "hls":
+		// The audio lives behind another URL; answering with a playlist is
+		// as alive as we can tell from here.
+		res.Alive = true
+		return res
+	case "":
+		if !isAudioType(mediaType) {
+			return res.dead(fmt.Sprintf("unrecognised content %q", mediaType))
+		}
+	}
+
+	res.Alive = true
+	res.Bitrate = c.measure(ctx, resp.Body)
+	return res
+}
+
+// measure reads the stream for the sample time and returns kbps. Servers
+// often send a burst of buffered audio on connect, so the clock starts
+// after the first read.
+func (c *Checker) measure(ctx context.Context, body io.Reader) int {
+	buf := make([]byte, 16*1024)
+	if _, err := body.Read(buf); err != nil {
+		return 0
+	}
+
+	start := time.Now()
+	deadline := start.Add(c.Sample)
+	var total int64
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		n, err := body.Read(buf)
+		total += int64(n)
+		if err != nil {
+			break
+		}
+	}
+
+	elapsed := time.Since(start)
+	if elapsed < 500*time.Millisecond || total == 0 {
+		return 0
+	}
+	return int(float64(total*8) / elapsed.Seconds() / 1000)
+}
+
+// isICYStatus reports whether err is net/http rejecting an "ICY 200 OK"
+// status line.
+func isICYStatus(err error) bool {
+	return err != nil && strings.Contains(err.Error(), `malformed HTTP version "ICY"`)
+}
+
+func (r Result) dead(reason string) Result {
+	r.Alive = false
+	r.Reason = reason
+	return r
+}
+
+func serverType(h http.Header, format string) string {
+	server := h.Get("Server")
+	lower := strings.ToLower(server + " " + h.Get("icy-notice1") + " " + h.Get("icy-notice2"))
+	switch {
+	case format == "hls":
+		return "HLS"
+	case strings.Contains(lower, "icecast"):
+		return "Icecast"
+	case strings.Contains(lower, "shoutcast"):
+		return "Shoutcast"
+	}
+	return server
+}
+
+func isAudioType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "audio/") ||
+		mediaType == "application/ogg" || mediaType == "video/mp2t"
+}
+
+// sniff recognises common stream formats from their first bytes.
+func sniff(head []byte, mediaType string) string {
+	text := bytes.ToLower(bytes.TrimSpace(head))
+	switch {
+	case bytes.HasPrefix(text, []byte("#extm3u")) && bytes.Contains(text, []byte("#ext-x-")):
+		return "hls"
+	case bytes.HasPrefix(text, []byte("#extm3u")), bytes.HasPrefix(text, []byte("[playlist]")),
+		bytes.HasPrefix(text, []byte("http://")), bytes.HasPrefix(text, []byte("https://")),
+		bytes.HasPrefix(text, []byte("<?xml")) && strings.Contains(mediaType, "xspf"):
+		return "playlist"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return "ogg"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "flac"
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return "mp3"
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0:
+		return "aac"
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return "mp3"
+	case len(head) >= 1 && head[0] == 0x47 && mediaType == "video/mp2t":
+		return "aac"
+	}
+	return ""
+}
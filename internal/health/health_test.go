@@ -0,0 +1,250 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// streamHandler serves mp3-looking audio at roughly kbps until the client
+// goes away.
+func streamHandler(kbps int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Test FM")
+		w.Header().Set("icy-genre", "Jazz")
+		w.Header().Set("icy-br", "128")
+		w.Header().Set("Server", "Icecast 2.4.4")
+		w.WriteHeader(http.StatusOK)
+
+		chunk := make([]byte, kbps*1000/8/10)
+		chunk[0], chunk[1] = 0xFF, 0xFB
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
+
+func newTestChecker() *Checker {
+	c := New(nil)
+	c.Sample = time.Second
+	c.Timeout = 5 * time.Second
+	c.Interval = 0
+	return c
+}
+
+func TestCheckAliveStream(t *testing.T) {
+	srv := httptest.NewServer(streamHandler(128))
+	defer srv.Close()
+
+	res := newTestChecker().Check(context.Background(), srv.URL)
+	if !res.Alive {
+		t.Fatalf("expected alive, got %q", res.Reason)
+	}
+	if res.Format != "mp3" {
+		t.Errorf("expected mp3, got %q", res.Format)
+	}
+	if res.ICY.Name != "Test FM" || res.ICY.Genre != "Jazz" || res.ICY.Bitrate != 128 {
+		t.Errorf("unexpected ICY headers %+v", res.ICY)
+	}
+	if res.Server != "Icecast" {
+		t.Errorf("expected Icecast, got %q", res.Server)
+	}
+	if res.Bitrate < 90 || res.Bitrate > 170 {
+		t.Errorf("expected about 128 kbps, measured %d", res.Bitrate)
+	}
+}
+
+func TestCheckDeadStreams(t *testing.T) {
+	tests := map[string]http.HandlerFunc{
+		"not found": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"html": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html>gone</html>")
+		},
+		"empty": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "audio/mpeg")
+		},
+		"unknown": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"error":"offline"}`)
+		},
+	}
+
+	for name, handler := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			res := newTestChecker().Check(context.Background(), srv.URL)
+			if res.Alive {
+				t.Fatal("expected dead")
+			}
+			if res.Reason == "" {
+				t.Error("expected a reason")
+			}
+		})
+	}
+
+	res := newTestChecker().Check(context.Background(), "http://127.0.0.1:1/")
+	if res.Alive {
+		t.Error("expected an unreachable server to be dead")
+	}
+}
+
+func TestCheckPlaylist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-mpegurl")
+		fmt.Fprint(w, "#EXTM3U\nhttp://example.com/stream\n")
+	}))
+	defer srv.Close()
+
+	res := newTestChecker().Check(context.Background(), srv.URL)
+	if !res.Alive || res.Format != "playlist" {
+		t.Errorf("expected a live playlist, got alive=%v format=%q", res.Alive, res.Format)
+	}
+}
+
+func TestCheckAllLimitsWorkers(t *testing.T) {
+	var active, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	c.Workers = 3
+
+	var urls []string
+	for i := 0; i < 12; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", srv.URL, i))
+	}
+
+	seen := make(map[string]bool)
+	for res := range c.CheckAll(context.Background(), urls) {
+		seen[res.URL] = true
+	}
+	if len(seen) != len(urls) {
+		t.Errorf("expected %d results, got %d", len(urls), len(seen))
+	}
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 concurrent probes, saw %d", peak.Load())
+	}
+}
+
+func TestCheckAllRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	c := newTestChecker()
+	c.Workers = 10
+	c.Interval = 50 * time.Millisecond
+
+	start := time.Now()
+	for range c.CheckAll(context.Background(), []string{srv.URL, srv.URL, srv.URL, srv.URL, srv.URL}) {
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected probes to be spaced out, all done in %s", elapsed)
+	}
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		head     []byte
+		expected string
+	}{
+		{[]byte("OggS\x00\x02"), "ogg"},
+		{[]byte("ID3\x04\x00"), "mp3"},
+		{[]byte{0xFF, 0xF1, 0x50, 0x80}, "aac"},
+		{[]byte{0xFF, 0xFB, 0x90, 0x00}, "mp3"},
+		{[]byte("#EXTM3U\n#EXT-X-VERSION:3\n"), "hls"},
+		{[]byte("[playlist]\nFile1=http://x\n"), "playlist"},
+		{[]byte("hello"), ""},
+	}
+	for _, tt := range tests {
+		if got := sniff(tt.head, ""); got != tt.expected {
+			t.Errorf("sniff(%q) = %q, expected %q", tt.head, got, tt.expected)
+		}
+	}
+}
+
+// icyListener answers every connection like a Shoutcast v1 server, with
+// an "ICY 200 OK" status line that net/http does not accept by itself.
+func icyListener(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+					return
+				}
+				fmt.Fprint(conn, "ICY 200 OK\r\nicy-name: Shout FM\r\nicy-br: 64\r\nContent-Type: audio/mpeg\r\n\r\n")
+				chunk := make([]byte, 800)
+				chunk[0], chunk[1] = 0xFF, 0xFB
+				for {
+					if _, err := conn.Write(chunk); err != nil {
+						return
+					}
+					time.Sleep(100 * time.Millisecond)
+				}
+			}()
+		}
+	}()
+	return "http://" + ln.Addr().String() + "/"
+}
+
+func TestCheckICYStatusLine(t *testing.T) {
+	url := icyListener(t)
+
+	res := newTestChecker().Check(context.Background(), url)
+	if !res.Alive {
+		t.Fatalf("expected alive, got %q", res.Reason)
+	}
+	if res.Format != "mp3" || res.ICY.Name != "Shout FM" || res.ICY.Bitrate != 64 {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	// A client without the ICY-aware transport cannot read the response,
+	// but the station must not be reported dead for it.
+	c := newTestChecker()
+	c.client = &http.Client{}
+	if res := c.Check(context.Background(), url); !res.Alive {
+		t.Errorf("expected plain client to report alive, got %q", res.Reason)
+	}
+}
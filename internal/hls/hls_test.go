@@ -0,0 +1,279 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const masterPlaylist = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-STREAM-INF:BANDWIDTH=56000,CODECS="mp4a.40.5"
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=136000,AVERAGE-BANDWIDTH=128000,CODECS="mp4a.40.2"
+high/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=900000,CODECS="avc1.42e00a,mp4a.40.2"
+video/index.m3u8
+`
+
+func TestParseMaster(t *testing.T) {
+	base, _ := url.Parse("http://example.com/live/master.m3u8")
+	p, err := Parse(strings.NewReader(masterPlaylist), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Master || len(p.Variants) != 3 {
+		t.Fatalf("unexpected playlist %+v", p)
+	}
+	v := p.Variants[1]
+	if v.URI != "http://example.com/live/high/index.m3u8" || v.Bandwidth != 136000 || v.AverageBandwidth != 128000 || v.Codecs != "mp4a.40.2" {
+		t.Errorf("unexpected variant %+v", v)
+	}
+	if got := p.Variants[2].Codecs; got != "avc1.42e00a,mp4a.40.2" {
+		t.Errorf("quoted codecs = %q", got)
+	}
+	if got := v.Label(); got != "128 kbps • mp4a.40.2" {
+		t.Errorf("Label() = %q", got)
+	}
+}
+
+func TestParseMedia(t *testing.T) {
+	p, err := Parse(strings.NewReader(`#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:41
+#EXTINF:6.0,
+seg41.ts
+#EXTINF:5.5,title
+http://cdn.example.com/seg42.ts
+#EXT-X-ENDLIST
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Master || !p.Ended || p.TargetDuration != 6*time.Second || len(p.Segments) != 2 {
+		t.Fatalf("unexpected playlist %+v", p)
+	}
+	if s := p.Segments[1]; s.Sequence != 42 || s.Duration != 5500*time.Millisecond || s.URI != "http://cdn.example.com/seg42.ts" {
+		t.Errorf("unexpected segment %+v", s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"no header":  "seg.ts\n",
+		"plain m3u":  "#EXTM3U\n#EXTINF:-1,Station\nhttp://example.com/stream\n",
+		"encrypted":  "#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"k\"\n#EXTINF:6,\na.ts\n",
+		"bad extinf": "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:abc,\na.ts\n",
+	}
+	for name, body := range tests {
+		if _, err := Parse(strings.NewReader(body), nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSelectVariant(t *testing.T) {
+	p, _ := Parse(strings.NewReader(masterPlaylist), nil)
+	tests := []struct {
+		max  int
+		want int
+	}{
+		{0, 136000},
+		{200000, 136000},
+		{100000, 56000},
+		{1000, 56000},
+	}
+	for _, tt := range tests {
+		if got := SelectVariant(p.Variants, tt.max).Bandwidth; got != tt.want {
+			t.Errorf("SelectVariant(max %d) bandwidth = %d, want %d", tt.max, got, tt.want)
+		}
+	}
+
+	video := []Variant{{URI: "a", Bandwidth: 500}, {URI: "b", Bandwidth: 900}}
+	if got := SelectVariant(video, 0).URI; got != "b" {
+		t.Errorf("without codecs picked %q", got)
+	}
+}
+
+func TestIsPlaylist(t *testing.T) {
+	if !IsPlaylistURL("http://a/b/live.M3U8?token=1") || IsPlaylistURL("http://a/stream.mp3") {
+		t.Error("IsPlaylistURL misclassified")
+	}
+	if !IsPlaylistType("application/vnd.apple.mpegurl; charset=utf-8") || IsPlaylistType("audio/mpeg") {
+		t.Error("IsPlaylistType misclassified")
+	}
+}
+
+// muxTS wraps payload in a minimal transport stream: PAT, PMT and one PES
+// packet on PID 0x101.
+func muxTS(streamType byte, payload []byte) []byte {
+	var out bytes.Buffer
+	psi := func(pid int, table []byte) {
+		pkt := make([]byte, tsPacketSize)
+		for i := range pkt {
+			pkt[i] = 0xFF
+		}
+		pkt[0], pkt[1], pkt[2], pkt[3] = 0x47, 0x40|byte(pid>>8), byte(pid), 0x10
+		pkt[4] = 0 // pointer field
+		copy(pkt[5:], table)
+		out.Write(pkt)
+	}
+	psi(0, []byte{0x00, 0xB0, 13, 0, 1, 0xC1, 0, 0, 0, 1, 0xE1, 0x00, 0, 0, 0, 0})
+	psi(0x100, []byte{0x02, 0xB0, 18, 0, 1, 0xC1, 0, 0, 0xE1, 0x01, 0xF0, 0, streamType, 0xE1, 0x01, 0xF0, 0, 0, 0, 0, 0})
+
+	pes := append([]byte{0, 0, 1, 0xC0, 0, 0, 0x80, 0, 0}, payload...)
+	for first := true; len(pes) > 0; first = false {
+		n := min(184, len(pes))
+		pkt := []byte{0x47, 0x01, 0x01, 0x10}
+		if first {
+			pkt[1] |= 0x40
+		}
+		if n < 184 {
+			// Pad the last packet with an adaptation field.
+			stuffing := 183 - n
+			pkt[3] = 0x30
+			pkt = append(pkt, byte(stuffing))
+			if stuffing > 0 {
+				pkt = append(pkt, 0)
+				pkt = append(pkt, bytes.Repeat([]byte{0xFF}, stuffing-1)...)
+			}
+		}
+		pkt = append(pkt, pes[:n]...)
+		pes = pes[n:]
+		out.Write(pkt)
+	}
+	return out.Bytes()
+}
+
+func TestTSReader(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 100)
+	ts := append(muxTS(StreamAACADTS, payload), muxTS(StreamAACADTS, []byte("tail"))...)
+
+	r := NewTSReader(bytes.NewReader(ts))
+	if err := r.Probe(); err != nil {
+		t.Fatal(err)
+	}
+	if r.StreamType != StreamAACADTS {
+		t.Errorf("StreamType = %#x", r.StreamType)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := string(payload) + "tail"; string(got) != want {
+		t.Errorf("payload mismatch: got %d bytes, want %d", len(got), len(want))
+	}
+
+	if err := NewTSReader(strings.NewReader("not a transport stream")).Probe(); err == nil {
+		t.Error("expected Probe to fail without a PMT")
+	}
+}
+
+// liveServer serves a master playlist and a sliding live window of
+// segments that advances by one on every playlist request.
+type liveServer struct {
+	mu       sync.Mutex
+	next     int
+	requests []string
+}
+
+func (l *liveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, r.URL.Path)
+
+	switch r.URL.Path {
+	case "/master.m3u8":
+		fmt.Fprint(w, masterPlaylist)
+	case "/high/index.m3u8":
+		l.next++
+		first := max(0, l.next-5)
+		fmt.Fprintf(w, "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+		for i := first; i < l.next+4; i++ {
+			fmt.Fprintf(w, "#EXTINF:1.0,\nseg%d.ts\n", i)
+		}
+	default:
+		var n int
+		if _, err := fmt.Sscanf(r.URL.Path, "/high/seg%d.ts", &n); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(muxTS(StreamMPEG1Audio, []byte(fmt.Sprintf("[%d]", n))))
+	}
+}
+
+func TestStreamLive(t *testing.T) {
+	srv := httptest.NewServer(&liveServer{})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := Open(ctx, srv.Client(), srv.URL+"/master.m3u8", 200000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Variant == nil || s.Variant.Bandwidth != 136000 {
+		t.Fatalf("chose variant %+v", s.Variant)
+	}
+
+	ts := NewTSReader(s)
+	var got string
+	buf := make([]byte, 64)
+	// The first playlist has seg0-seg4; playback starts three from the
+	// end and must pick up seg5 after a reload.
+	for !strings.Contains(got, "[5]") {
+		n, err := ts.Read(buf)
+		if err != nil {
+			t.Fatalf("Read after %q: %v", got, err)
+		}
+		got += string(buf[:n])
+	}
+	if !strings.HasPrefix(got, "[2][3][4][5]") {
+		t.Errorf("got %q, want segments from the live edge in order", got)
+	}
+	if ts.StreamType != StreamMPEG1Audio {
+		t.Errorf("StreamType = %#x", ts.StreamType)
+	}
+}
+
+func TestStreamEnded(t *testing.T) {
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x05"), "xxxxx"...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vod.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\na.aac\n#EXTINF:10,\nmissing.aac\n#EXTINF:10,\nb.aac\n#EXT-X-ENDLIST\n")
+		case "/a.aac":
+			w.Write(append(id3, "AAA"...))
+		case "/b.aac":
+			w.Write([]byte("BBB"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := Open(context.Background(), srv.Client(), srv.URL+"/vod.m3u8", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Variant != nil {
+		t.Errorf("media playlist should have no variant, got %+v", s.Variant)
+	}
+	got, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "AAABBB" {
+		t.Errorf("got %q, want ID3 stripped and the missing segment skipped", got)
+	}
+}
@@ -0,0 +1,259 @@
+// Package hls reads HTTP Live Streaming radio: it parses master and media
+// playlists, picks a variant and turns a live playlist into one continuous
+// stream of segment data.
+package hls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrEncrypted is returned for playlists whose segments are encrypted,
+// which only mpv can play.
+var ErrEncrypted = errors.New("encrypted HLS streams are not supported")
+
+// Variant is one rendition listed in a master playlist.
+type Variant struct {
+	URI              string
+	Bandwidth        int
+	AverageBandwidth int
+	Codecs           string
+}
+
+// Label describes the variant for display, e.g. "128 kbps • mp4a.40.2".
+func (v Variant) Label() string {
+	bw := v.AverageBandwidth
+	if bw == 0 {
+		bw = v.Bandwidth
+	}
+	label := fmt.Sprintf("%d kbps", bw/1000)
+	if v.Codecs != "" {
+		label += " • " + v.Codecs
+	}
+	return label
+}
+
+// Segment is one media file in a media playlist.
+type Segment struct {
+	URI      string
+	Duration time.Duration
+	Sequence int
+}
+
+// Playlist is a parsed master or media playlist. Master playlists only
+// have Variants; media playlists only have Segments.
+type Playlist struct {
+	Master         bool
+	Variants       []Variant
+	Segments       []Segment
+	TargetDuration time.Duration
+	MediaSequence  int
+	// Ended is set by EXT-X-ENDLIST; live playlists never end.
+	Ended bool
+}
+
+// IsPlaylistURL reports whether rawURL names an HLS playlist by extension.
+func IsPlaylistURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(u.Path), ".m3u8")
+}
+
+// IsPlaylistType reports whether a Content-Type is an HLS playlist.
+// audio/x-mpegurl is also used for plain M3U files, so callers should
+// still check the body with Parse.
+func IsPlaylistType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/vnd.apple.mpegurl", "application/x-mpegurl", "audio/mpegurl", "audio/x-mpegurl":
+		return true
+	}
+	return false
+}
+
+// Parse reads a playlist. Relative URIs are resolved against base.
+func Parse(r io.Reader, base *url.URL) (*Playlist, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if !sc.Scan() || strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\uFEFF")) != "#EXTM3U" {
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("reading playlist: %w", err)
+		}
+		return nil, errors.New("not an HLS playlist: missing #EXTM3U")
+	}
+
+	p := &Playlist{}
+	var (
+		pendingVariant *Variant
+		duration       time.Duration
+		hlsTags        bool
+	)
+	resolve := func(ref string) (string, error) {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("bad playlist URI %q: %w", ref, err)
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		return u.String(), nil
+	}
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			uri, err := resolve(line)
+			if err != nil {
+				return nil, err
+			}
+			if pendingVariant != nil {
+				pendingVariant.URI = uri
+				p.Variants = append(p.Variants, *pendingVariant)
+				pendingVariant = nil
+				continue
+			}
+			p.Segments = append(p.Segments, Segment{
+				URI:      uri,
+				Duration: duration,
+				Sequence: p.MediaSequence + len(p.Segments),
+			})
+			duration = 0
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+		if strings.HasPrefix(tag, "#EXT-X-") {
+			hlsTags = true
+		}
+		switch tag {
+		case "#EXT-X-STREAM-INF":
+			attrs := parseAttributes(value)
+			v := Variant{Codecs: attrs["CODECS"]}
+			v.Bandwidth, _ = strconv.Atoi(attrs["BANDWIDTH"])
+			v.AverageBandwidth, _ = strconv.Atoi(attrs["AVERAGE-BANDWIDTH"])
+			pendingVariant = &v
+			p.Master = true
+		case "#EXTINF":
+			secs, _, _ := strings.Cut(value, ",")
+			f, err := strconv.ParseFloat(secs, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad segment duration %q", secs)
+			}
+			duration = time.Duration(f * float64(time.Second))
+		case "#EXT-X-TARGETDURATION":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad target duration %q", value)
+			}
+			p.TargetDuration = time.Duration(n) * time.Second
+		case "#EXT-X-MEDIA-SEQUENCE":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad media sequence %q", value)
+			}
+			p.MediaSequence = n
+		case "#EXT-X-ENDLIST":
+			p.Ended = true
+		case "#EXT-X-KEY":
+			if method := parseAttributes(value)["METHOD"]; method != "" && method != "NONE" {
+				return nil, ErrEncrypted
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading playlist: %w", err)
+	}
+
+	if !hlsTags && !p.Master {
+		return nil, errors.New("not an HLS playlist: plain M3U")
+	}
+	if p.Master && len(p.Variants) == 0 {
+		return nil, errors.New("master playlist has no variants")
+	}
+	return p, nil
+}
+
+// parseAttributes splits an attribute list such as
+// BANDWIDTH=128000,CODECS="mp4a.40.2,mp4a.40.5" into its values.
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[strings.TrimSpace(key)] = value
+		s = strings.TrimPrefix(rest, ",")
+	}
+	return attrs
+}
+
+// SelectVariant picks the highest-bandwidth variant within maxBandwidth
+// bits per second (0 means no limit), or the lowest one if none fits.
+// Audio-only variants are preferred when the playlist has any.
+func SelectVariant(variants []Variant, maxBandwidth int) Variant {
+	candidates := variants
+	var audioOnly []Variant
+	for _, v := range variants {
+		if v.Codecs != "" && !hasVideo(v.Codecs) {
+			audioOnly = append(audioOnly, v)
+		}
+	}
+	if len(audioOnly) > 0 {
+		candidates = audioOnly
+	}
+
+	best, lowest := -1, 0
+	for i, v := range candidates {
+		if v.Bandwidth < candidates[lowest].Bandwidth {
+			lowest = i
+		}
+		if maxBandwidth > 0 && v.Bandwidth > maxBandwidth {
+			continue
+		}
+		if best < 0 || v.Bandwidth > candidates[best].Bandwidth {
+			best = i
+		}
+	}
+	if best < 0 {
+		best = lowest
+	}
+	return candidates[best]
+}
+
+func hasVideo(codecs string) bool {
+	for _, c := range strings.Split(codecs, ",") {
+		c = strings.TrimSpace(c)
+		for _, prefix := range []string{"avc", "hvc", "hev", "vp0", "vp9", "av01"} {
+			if strings.HasPrefix(c, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
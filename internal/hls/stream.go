@@ -0,0 +1,273 @@
+package hls
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"radio/pkg/logger"
+)
+
+const (
+	// liveEdgeSegments is how many segments from the end of a live
+	// playlist playback starts, as the HLS spec recommends.
+	liveEdgeSegments = 3
+	// maxReloadFailures is how many playlist reloads in a row may fail
+	// before the stream gives up.
+	maxReloadFailures = 3
+)
+
+// Stream is the segments of a media playlist read back to back. For live
+// playlists it reloads the playlist as segments are used up.
+type Stream struct {
+	// Variant is the rendition chosen from a master playlist, or nil if
+	// the URL was a media playlist.
+	Variant *Variant
+
+	ctx      context.Context
+	client   *http.Client
+	mediaURL string
+
+	queue      []Segment
+	lastSeq    int
+	ended      bool
+	target     time.Duration
+	nextReload time.Time
+	failures   int
+
+	seg io.ReadCloser
+}
+
+// Resolve fetches rawURL and, if it is a master playlist, picks a variant
+// with SelectVariant. It returns the media playlist's URL and contents.
+func Resolve(ctx context.Context, client *http.Client, rawURL string, maxBandwidth int) (string, *Variant, *Playlist, error) {
+	p, err := fetchPlaylist(ctx, client, rawURL)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if !p.Master {
+		return rawURL, nil, p, nil
+	}
+
+	v := SelectVariant(p.Variants, maxBandwidth)
+	media, err := fetchPlaylist(ctx, client, v.URI)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if media.Master {
+		return "", nil, nil, errors.New("variant is another master playlist")
+	}
+	return v.URI, &v, media, nil
+}
+
+// Open starts reading the stream at rawURL. Reads block until segments
+// are available and stop when ctx is done.
+func Open(ctx context.Context, client *http.Client, rawURL string, maxBandwidth int) (*Stream, error) {
+	mediaURL, variant, p, err := Resolve(ctx, client, rawURL, maxBandwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		Variant:  variant,
+		ctx:      ctx,
+		client:   client,
+		mediaURL: mediaURL,
+		lastSeq:  -1,
+	}
+	segments := p.Segments
+	if !p.Ended && len(segments) > liveEdgeSegments {
+		segments = segments[len(segments)-liveEdgeSegments:]
+	}
+	s.update(p, segments)
+	return s, nil
+}
+
+// update queues segments not seen before and schedules the next reload.
+func (s *Stream) update(p *Playlist, segments []Segment) {
+	added := 0
+	for _, seg := range segments {
+		if seg.Sequence > s.lastSeq {
+			s.queue = append(s.queue, seg)
+			s.lastSeq = seg.Sequence
+			added++
+		}
+	}
+	s.ended = p.Ended
+	s.target = p.TargetDuration
+	if s.target <= 0 {
+		s.target = 5 * time.Second
+	}
+	// An unchanged playlist is retried after half the target duration.
+	wait := s.target
+	if added == 0 {
+		wait /= 2
+	}
+	s.nextReload = time.Now().Add(wait)
+}
+
+func (s *Stream) Read(b []byte) (int, error) {
+	for {
+		if s.seg == nil {
+			if err := s.nextSegment(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := s.seg.Read(b)
+		if err == io.EOF {
+			s.seg.Close()
+			s.seg = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		if err != nil {
+			s.seg.Close()
+			s.seg = nil
+			if s.ctx.Err() != nil {
+				return n, s.ctx.Err()
+			}
+			logger.Log.Warn().Err(err).Msg("HLS segment interrupted")
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, nil
+	}
+}
+
+// nextSegment opens the next queued segment, reloading the playlist when
+// the queue is empty. Segments that fail to load are skipped.
+func (s *Stream) nextSegment() error {
+	for {
+		if len(s.queue) == 0 {
+			if s.ended {
+				return io.EOF
+			}
+			if err := s.reload(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		seg := s.queue[0]
+		s.queue = s.queue[1:]
+		body, err := s.get(seg.URI)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return s.ctx.Err()
+			}
+			logger.Log.Warn().Err(err).Str("segment", seg.URI).Msg("skipping HLS segment")
+			continue
+		}
+		s.seg = body
+		return nil
+	}
+}
+
+func (s *Stream) reload() error {
+	timer := time.NewTimer(time.Until(s.nextReload))
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case <-timer.C:
+	}
+
+	p, err := fetchPlaylist(s.ctx, s.client, s.mediaURL)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		s.failures++
+		if s.failures >= maxReloadFailures {
+			return fmt.Errorf("reloading playlist: %w", err)
+		}
+		logger.Log.Warn().Err(err).Msg("HLS playlist reload failed, retrying")
+		s.nextReload = time.Now().Add(s.target / 2)
+		return nil
+	}
+	s.failures = 0
+	s.update(p, p.Segments)
+	return nil
+}
+
+// get opens a segment, dropping the ID3 tag that packed-audio segments
+// start with so the codec data comes first.
+func (s *Stream) get(rawURL string) (io.ReadCloser, error) {
+	resp, err := get(s.ctx, s.client, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(resp.Body)
+	if err := skipID3(br); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{br, resp.Body}, nil
+}
+
+func (s *Stream) Close() error {
+	if s.seg != nil {
+		err := s.seg.Close()
+		s.seg = nil
+		return err
+	}
+	return nil
+}
+
+func skipID3(br *bufio.Reader) error {
+	head, err := br.Peek(10)
+	if err != nil || string(head[:3]) != "ID3" {
+		return nil
+	}
+	size := int(head[6]&0x7F)<<21 | int(head[7]&0x7F)<<14 | int(head[8]&0x7F)<<7 | int(head[9]&0x7F)
+	if head[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	if _, err := br.Discard(10 + size); err != nil {
+		return fmt.Errorf("reading ID3 tag: %w", err)
+	}
+	return nil
+}
+
+func get(ctx context.Context, client *http.Client, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func fetchPlaylist(ctx context.Context, client *http.Client, rawURL string) (*Playlist, error) {
+	resp, err := get(ctx, client, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching playlist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Relative URIs resolve against the final URL, after redirects.
+	p, err := Parse(io.LimitReader(resp.Body, 1<<20), resp.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing playlist %s: %w", rawURL, err)
+	}
+	return p, nil
+}
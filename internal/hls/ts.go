@@ -0,0 +1,183 @@
+package hls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MPEG-TS stream types for the audio codecs radio uses.
+const (
+	StreamMPEG1Audio = 0x03
+	StreamMPEG2Audio = 0x04
+	StreamAACADTS    = 0x0F
+	StreamAACLATM    = 0x11
+)
+
+const tsPacketSize = 188
+
+// TSReader extracts the first audio elementary stream from an MPEG
+// transport stream, as used by most HLS segments. Segments read back to
+// back form one valid transport stream, so it can sit on top of a Stream.
+type TSReader struct {
+	r *bufio.Reader
+	// StreamType is the audio stream's type from the PMT, known after
+	// Probe or the first Read.
+	StreamType byte
+
+	pmtPID   int
+	audioPID int
+	packet   [tsPacketSize]byte
+	pending  []byte
+}
+
+func NewTSReader(r io.Reader) *TSReader {
+	return &TSReader{r: bufio.NewReader(r), pmtPID: -1, audioPID: -1}
+}
+
+// Probe reads until the audio stream has been found in the PMT.
+func (t *TSReader) Probe() error {
+	for t.audioPID < 0 {
+		if err := t.readPacket(); err != nil {
+			if err == io.EOF {
+				return errors.New("no audio stream in transport stream")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TSReader) Read(b []byte) (int, error) {
+	for len(t.pending) == 0 {
+		if err := t.readPacket(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+// readPacket reads one packet, resynchronising on the 0x47 sync byte if
+// the stream got out of step.
+func (t *TSReader) readPacket() error {
+	for {
+		c, err := t.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if c == 0x47 {
+			break
+		}
+	}
+	t.packet[0] = 0x47
+	if _, err := io.ReadFull(t.r, t.packet[1:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+		return err
+	}
+
+	pkt := t.packet[:]
+	start := pkt[1]&0x40 != 0
+	pid := int(pkt[1]&0x1F)<<8 | int(pkt[2])
+	control := pkt[3] >> 4 & 0x3
+
+	if control&0x1 == 0 {
+		return nil // adaptation field only
+	}
+	payload := pkt[4:]
+	if control&0x2 != 0 {
+		skip := 1 + int(pkt[4])
+		if skip > len(payload) {
+			return nil
+		}
+		payload = payload[skip:]
+	}
+
+	switch {
+	case pid == 0 && start:
+		t.parsePAT(section(payload))
+	case pid == t.pmtPID && start && t.audioPID < 0:
+		return t.parsePMT(section(payload))
+	case pid == t.audioPID:
+		if start {
+			payload = pesPayload(payload)
+		}
+		t.pending = append(t.pending, payload...)
+	}
+	return nil
+}
+
+// section skips the pointer field in front of a PSI table.
+func section(payload []byte) []byte {
+	if len(payload) == 0 || 1+int(payload[0]) > len(payload) {
+		return nil
+	}
+	return payload[1+int(payload[0]):]
+}
+
+// tableBody returns a PSI table's contents after the 8-byte header, up to
+// the CRC.
+func tableBody(s []byte) []byte {
+	if len(s) < 8 {
+		return nil
+	}
+	end := 3 + (int(s[1]&0x0F)<<8 | int(s[2])) - 4
+	if end > len(s) || end < 8 {
+		return nil
+	}
+	return s[8:end]
+}
+
+func (t *TSReader) parsePAT(s []byte) {
+	body := tableBody(s)
+	for ; len(body) >= 4; body = body[4:] {
+		program := int(body[0])<<8 | int(body[1])
+		if program != 0 {
+			t.pmtPID = int(body[2]&0x1F)<<8 | int(body[3])
+			return
+		}
+	}
+}
+
+func (t *TSReader) parsePMT(s []byte) error {
+	body := tableBody(s)
+	if len(body) < 4 {
+		return nil
+	}
+	infoLen := int(body[2]&0x0F)<<8 | int(body[3])
+	if 4+infoLen > len(body) {
+		return nil
+	}
+	for es := body[4+infoLen:]; len(es) >= 5; {
+		streamType := es[0]
+		pid := int(es[1]&0x1F)<<8 | int(es[2])
+		esInfoLen := int(es[3]&0x0F)<<8 | int(es[4])
+		switch streamType {
+		case StreamMPEG1Audio, StreamMPEG2Audio, StreamAACADTS, StreamAACLATM:
+			t.audioPID = pid
+			t.StreamType = streamType
+			return nil
+		}
+		if 5+esInfoLen > len(es) {
+			break
+		}
+		es = es[5+esInfoLen:]
+	}
+	return fmt.Errorf("no supported audio stream in transport stream")
+}
+
+// pesPayload skips the PES header at the start of a packet's payload.
+func pesPayload(p []byte) []byte {
+	if len(p) < 9 || p[0] != 0 || p[1] != 0 || p[2] != 1 {
+		return p
+	}
+	start := 9 + int(p[8])
+	if start > len(p) {
+		return nil
+	}
+	return p[start:]
+}
@@ -0,0 +1,63 @@
+package icy
+
+import (
+	"bytes"
+	"net"
+	"sync"
+)
+
+const (
+	icyStatus  = "ICY "
+	httpStatus = "HTTP/1.0 "
+)
+
+// Conn lets net/http read from Shoutcast v1 servers, which answer with an
+// "ICY 200 OK" status line that it rejects as a malformed HTTP version. A
+// leading "ICY " is rewritten to "HTTP/1.0 " on the first read; anything
+// else, such as a TLS handshake or a proxy's reply, passes through.
+type Conn struct {
+	net.Conn
+
+	once    sync.Once
+	pending []byte
+	err     error
+}
+
+// NewConn wraps a freshly dialled connection.
+func NewConn(c net.Conn) *Conn {
+	return &Conn{Conn: c}
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	c.once.Do(func() {
+		c.pending, c.err = c.readStatus()
+	})
+	if len(c.pending) > 0 {
+		n := copy(p, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+	if c.err != nil {
+		err := c.err
+		c.err = nil
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
+
+// readStatus reads just enough of the response to tell whether it starts
+// with an ICY status line.
+func (c *Conn) readStatus() ([]byte, error) {
+	head := make([]byte, 0, len(icyStatus))
+	for len(head) < len(icyStatus) && bytes.HasPrefix([]byte(icyStatus), head) {
+		n, err := c.Conn.Read(head[len(head):cap(head)])
+		head = head[:len(head)+n]
+		if err != nil {
+			return head, err
+		}
+	}
+	if string(head) == icyStatus {
+		return []byte(httpStatus), nil
+	}
+	return head, nil
+}
@@ -0,0 +1,70 @@
+// Package icy handles Shoutcast/Icecast in-band stream metadata.
+package icy
+
+import (
+	"io"
+	"strings"
+)
+
+// Reader strips Shoutcast/Icecast in-band metadata from a stream sent with
+// "Icy-MetaData: 1". Every metaint audio bytes the server inserts one length
+// byte (in units of 16) followed by that much metadata text.
+type Reader struct {
+	r         io.Reader
+	metaint   int
+	remaining int
+	onMeta    func(meta string)
+}
+
+// NewReader reads audio from r, the body of a response whose icy-metaint
+// header was metaint, calling onMeta with each metadata block.
+func NewReader(r io.Reader, metaint int, onMeta func(string)) *Reader {
+	if onMeta == nil {
+		onMeta = func(string) {}
+	}
+	return &Reader{r: r, metaint: metaint, remaining: metaint, onMeta: onMeta}
+}
+
+func (ir *Reader) Read(p []byte) (int, error) {
+	for ir.remaining == 0 {
+		var lenByte [1]byte
+		if _, err := io.ReadFull(ir.r, lenByte[:]); err != nil {
+			return 0, err
+		}
+		if n := int(lenByte[0]) * 16; n > 0 {
+			meta := make([]byte, n)
+			if _, err := io.ReadFull(ir.r, meta); err != nil {
+				return 0, err
+			}
+			ir.onMeta(strings.TrimRight(string(meta), "\x00"))
+		}
+		ir.remaining = ir.metaint
+	}
+
+	if len(p) > ir.remaining {
+		p = p[:ir.remaining]
+	}
+	n, err := ir.r.Read(p)
+	ir.remaining -= n
+	return n, err
+}
+
+// ParseStreamTitle extracts StreamTitle from a metadata block such as
+// "StreamTitle='Artist - Title';StreamUrl='http://...';". The title itself may
+// contain quotes, so it ends at the first "';" rather than the first "'".
+func ParseStreamTitle(meta string) (string, bool) {
+	const key = "StreamTitle='"
+	start := strings.Index(meta, key)
+	if start < 0 {
+		return "", false
+	}
+	rest := meta[start+len(key):]
+	end := strings.Index(rest, "';")
+	if end < 0 {
+		end = strings.LastIndex(rest, "'")
+		if end < 0 {
+			end = len(rest)
+		}
+	}
+	return strings.TrimSpace(rest[:end]), true
+}
@@ -0,0 +1,81 @@
+package icy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestParseStreamTitle(t *testing.T) {
+	tests := []struct {
+		meta, want string
+		ok         bool
+	}{
+		{"StreamTitle='Artist - Song';StreamUrl='';", "Artist - Song", true},
+		{"StreamTitle='Guns N' Roses - Don't Cry';", "Guns N' Roses - Don't Cry", true},
+		{"StreamTitle='';", "", true},
+		{"StreamUrl='x';", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseStreamTitle(tt.meta)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseStreamTitle(%q) = %q, %v; want %q, %v", tt.meta, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReaderStripsMetadata(t *testing.T) {
+	meta := "StreamTitle='A - B';"
+	block := append([]byte{byte((len(meta) + 15) / 16)}, []byte(meta)...)
+	block = append(block, make([]byte, 16-len(meta)%16)...)
+
+	var stream bytes.Buffer
+	stream.WriteString("aaaa")
+	stream.Write(block)
+	stream.WriteString("bbbb")
+	stream.WriteByte(0)
+	stream.WriteString("cc")
+
+	var got []string
+	data, err := io.ReadAll(NewReader(&stream, 4, func(m string) { got = append(got, m) }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "aaaabbbbcc" {
+		t.Errorf("unexpected audio %q", data)
+	}
+	if len(got) != 1 || got[0] != meta {
+		t.Errorf("unexpected metadata %q", got)
+	}
+}
+
+func TestConnRewritesICYStatus(t *testing.T) {
+	tests := []struct {
+		chunks []string
+		want   string
+	}{
+		{[]string{"ICY 200 OK\r\n\r\naudio"}, "HTTP/1.0 200 OK\r\n\r\naudio"},
+		{[]string{"IC", "Y 200 OK\r\n"}, "HTTP/1.0 200 OK\r\n"},
+		{[]string{"HTTP/1.1 200 OK\r\n"}, "HTTP/1.1 200 OK\r\n"},
+		{[]string{"\x16\x03\x01"}, "\x16\x03\x01"},
+		{[]string{"IC"}, "IC"},
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		go func() {
+			for _, chunk := range tt.chunks {
+				server.Write([]byte(chunk))
+			}
+			server.Close()
+		}()
+
+		got, err := io.ReadAll(NewConn(client))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("chunks %q read as %q, want %q", tt.chunks, got, tt.want)
+		}
+	}
+}
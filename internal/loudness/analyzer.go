@@ -0,0 +1,123 @@
+package loudness
+
+import "math"
+
+// Analyzer measures interleaved float32 samples directly: it K-weights
+// each channel, forms overlapping 400 ms blocks and feeds a Meter. Its
+// Process method leaves the samples unchanged, so it can sit at the start
+// of a filter chain.
+type Analyzer struct {
+	Meter *Meter
+
+	channels int
+	filters  []kWeighting
+	// sub holds the mean square of the last four 100 ms sub-blocks per
+	// channel; a block is their average.
+	sub       [][4]float64
+	subIndex  int
+	subFilled int
+	acc       []float64
+	accFrames int
+	subFrames int
+}
+
+func NewAnalyzer(sampleRate, channels int) *Analyzer {
+	a := &Analyzer{
+		Meter:     NewMeter(),
+		channels:  channels,
+		filters:   make([]kWeighting, channels),
+		sub:       make([][4]float64, channels),
+		acc:       make([]float64, channels),
+		subFrames: int(float64(sampleRate) * BlockStep.Seconds()),
+	}
+	for c := range a.filters {
+		a.filters[c] = newKWeighting(float64(sampleRate))
+	}
+	return a
+}
+
+func (a *Analyzer) Process(samples []float32) {
+	for i := 0; i+a.channels <= len(samples); i += a.channels {
+		for c := 0; c < a.channels; c++ {
+			y := a.filters[c].process(float64(samples[i+c]))
+			a.acc[c] += y * y
+		}
+		a.accFrames++
+		if a.accFrames == a.subFrames {
+			a.endSubBlock()
+		}
+	}
+}
+
+func (a *Analyzer) endSubBlock() {
+	for c := range a.acc {
+		a.sub[c][a.subIndex] = a.acc[c] / float64(a.accFrames)
+		a.acc[c] = 0
+	}
+	a.accFrames = 0
+	a.subIndex = (a.subIndex + 1) % 4
+	if a.subFilled < 4 {
+		a.subFilled++
+		if a.subFilled < 4 {
+			return
+		}
+	}
+
+	// Left, right and centre all weigh 1; surround channels do not occur
+	// in radio streams.
+	var energy float64
+	for c := range a.sub {
+		energy += (a.sub[c][0] + a.sub[c][1] + a.sub[c][2] + a.sub[c][3]) / 4
+	}
+	a.Meter.AddBlock(toLUFS(energy))
+}
+
+// kWeighting is the BS.1770 pre-filter: a high shelf modelling the head
+// followed by a high-pass, as two biquads.
+type kWeighting struct {
+	shelf, highpass biquad
+}
+
+func newKWeighting(rate float64) kWeighting {
+	var k kWeighting
+
+	// Coefficients derived for any sample rate, as in libebur128.
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	kk := math.Tan(math.Pi * f0 / rate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + kk/q + kk*kk
+	k.shelf = biquad{
+		b0: (vh + vb*kk/q + kk*kk) / a0,
+		b1: 2 * (kk*kk - vh) / a0,
+		b2: (vh - vb*kk/q + kk*kk) / a0,
+		a1: 2 * (kk*kk - 1) / a0,
+		a2: (1 - kk/q + kk*kk) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	kk = math.Tan(math.Pi * f0 / rate)
+	a0 = 1 + kk/q + kk*kk
+	k.highpass = biquad{
+		b0: 1, b1: -2, b2: 1,
+		a1: 2 * (kk*kk - 1) / a0,
+		a2: (1 - kk/q + kk*kk) / a0,
+	}
+	return k
+}
+
+func (k *kWeighting) process(x float64) float64 {
+	return k.highpass.process(k.shelf.process(x))
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
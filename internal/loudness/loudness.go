@@ -0,0 +1,120 @@
+// Package loudness measures integrated loudness as defined by EBU R128 /
+// ITU-R BS.1770 and turns it into a gain that brings stations to a common
+// level.
+package loudness
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// Target is the loudness every station is brought to, in LUFS. It is
+	// the ReplayGain 2 reference, loud enough for music radio.
+	Target = -18.0
+	// MaxGain bounds the correction either way, in dB.
+	MaxGain = 12.0
+
+	absoluteGate = -70.0
+	relativeGate = -10.0
+	// BlockDuration is the gating block length; blocks overlap by 75%.
+	BlockDuration = 400 * time.Millisecond
+	BlockStep     = 100 * time.Millisecond
+
+	histogramMin  = absoluteGate
+	histogramMax  = 5.0
+	histogramStep = 0.1
+)
+
+// Meter integrates block loudness values with R128's two-stage gating. It
+// keeps a histogram rather than every block, so it can run for hours.
+// It is safe to read while another goroutine adds blocks.
+type Meter struct {
+	mu     sync.Mutex
+	bins   []int
+	blocks int
+}
+
+func NewMeter() *Meter {
+	return &Meter{bins: make([]int, int((histogramMax-histogramMin)/histogramStep)+1)}
+}
+
+// AddBlock records the loudness of one gating block in LUFS. Blocks below
+// the absolute gate are silence and ignored.
+func (m *Meter) AddBlock(lufs float64) {
+	if math.IsNaN(lufs) || lufs < absoluteGate {
+		return
+	}
+	i := int((math.Min(lufs, histogramMax) - histogramMin) / histogramStep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bins[i]++
+	m.blocks++
+}
+
+// Blocks is how many blocks passed the absolute gate.
+func (m *Meter) Blocks() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.blocks
+}
+
+// Integrated is the gated loudness of everything added so far. It is
+// false until a block above the gates was seen.
+func (m *Meter) Integrated() (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mean := func(gate float64) (float64, bool) {
+		var energy float64
+		var n int
+		for i, count := range m.bins {
+			if count == 0 {
+				continue
+			}
+			l := histogramMin + (float64(i)+0.5)*histogramStep
+			if l < gate {
+				continue
+			}
+			energy += float64(count) * toEnergy(l)
+			n += count
+		}
+		if n == 0 {
+			return 0, false
+		}
+		return toLUFS(energy / float64(n)), true
+	}
+
+	ungated, ok := mean(absoluteGate)
+	if !ok {
+		return 0, false
+	}
+	return mean(ungated + relativeGate)
+}
+
+// Reset forgets all blocks.
+func (m *Meter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.bins {
+		m.bins[i] = 0
+	}
+	m.blocks = 0
+}
+
+// GainFor is the correction in dB that brings lufs to Target.
+func GainFor(lufs float64) float64 {
+	return math.Max(-MaxGain, math.Min(MaxGain, Target-lufs))
+}
+
+func toEnergy(lufs float64) float64 {
+	return math.Pow(10, (lufs+0.691)/10)
+}
+
+func toLUFS(energy float64) float64 {
+	if energy <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(energy)
+}
@@ -0,0 +1,93 @@
+package loudness
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns seconds of a stereo sine with peak amplitude at dBFS.
+func sine(rate int, freq, dBFS, seconds float64) []float32 {
+	amp := math.Pow(10, dBFS/20)
+	n := int(float64(rate) * seconds)
+	out := make([]float32, 2*n)
+	for i := 0; i < n; i++ {
+		v := float32(amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+		out[2*i], out[2*i+1] = v, v
+	}
+	return out
+}
+
+func TestAnalyzerReferenceTone(t *testing.T) {
+	// EBU Tech 3341 case 1: a 1 kHz stereo sine at -23 dBFS reads
+	// -23 LUFS, at any sample rate.
+	for _, rate := range []int{44100, 48000} {
+		a := NewAnalyzer(rate, 2)
+		a.Process(sine(rate, 1000, -23, 20))
+		got, ok := a.Meter.Integrated()
+		if !ok || math.Abs(got-(-23)) > 0.1 {
+			t.Errorf("%d Hz: integrated = %.2f, %v; want -23", rate, got, ok)
+		}
+	}
+}
+
+func TestAnalyzerLeavesSamples(t *testing.T) {
+	in := sine(44100, 440, -6, 0.5)
+	out := append([]float32(nil), in...)
+	NewAnalyzer(44100, 2).Process(out)
+	for i := range in {
+		if in[i] != out[i] {
+			t.Fatalf("sample %d changed", i)
+		}
+	}
+}
+
+func TestGating(t *testing.T) {
+	a := NewAnalyzer(48000, 2)
+	a.Process(sine(48000, 1000, -20, 10))
+	a.Process(make([]float32, 2*48000*10)) // silence: absolute gate
+	a.Process(sine(48000, 1000, -50, 10))  // 30 LU quieter: relative gate
+	got, ok := a.Meter.Integrated()
+	if !ok || math.Abs(got-(-20)) > 0.2 {
+		t.Errorf("integrated = %.2f, %v; want -20 with quiet parts gated", got, ok)
+	}
+}
+
+func TestMeter(t *testing.T) {
+	m := NewMeter()
+	if _, ok := m.Integrated(); ok {
+		t.Error("empty meter reported a value")
+	}
+	m.AddBlock(-80)
+	m.AddBlock(math.Inf(-1))
+	if m.Blocks() != 0 {
+		t.Errorf("blocks below the absolute gate were counted")
+	}
+	for i := 0; i < 10; i++ {
+		m.AddBlock(-14)
+		m.AddBlock(-17)
+	}
+	got, _ := m.Integrated()
+	// The energy mean of -14 and -17 is about -15.2.
+	if math.Abs(got-(-15.24)) > 0.1 {
+		t.Errorf("integrated = %.2f", got)
+	}
+	m.Reset()
+	if _, ok := m.Integrated(); ok {
+		t.Error("Reset kept blocks")
+	}
+}
+
+func TestGainFor(t *testing.T) {
+	tests := []struct{ lufs, want float64 }{
+		{-18, 0},
+		{-10, -8},
+		{-23, 5},
+		{-40, MaxGain},
+		{5, -MaxGain},
+	}
+	for _, tt := range tests {
+		if got := GainFor(tt.lufs); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("GainFor(%g) = %g, want %g", tt.lufs, got, tt.want)
+		}
+	}
+}
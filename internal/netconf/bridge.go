@@ -0,0 +1,157 @@
+package netconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"radio/pkg/logger"
+)
+
+// Bridge is an HTTP proxy on localhost that forwards everything through a
+// SOCKS5 proxy, for clients such as mpv that cannot speak SOCKS.
+type Bridge struct {
+	proxy     *url.URL
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+}
+
+// NewBridge starts a bridge to proxy on a free local port.
+func NewBridge(proxy *url.URL) (*Bridge, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting SOCKS bridge: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+
+	b := &Bridge{proxy: proxy, listener: ln, transport: transport}
+	b.server = &http.Server{Handler: b, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := b.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Log.Error().Err(err).Msg("SOCKS bridge stopped")
+		}
+	}()
+	logger.Log.Info().Str("listen", ln.Addr().String()).Str("proxy", proxy.Redacted()).Msg("SOCKS bridge started")
+	return b, nil
+}
+
+// URL is the HTTP proxy address to hand to clients.
+func (b *Bridge) URL() *url.URL {
+	return &url.URL{Scheme: "http", Host: b.listener.Addr().String()}
+}
+
+func (b *Bridge) Close() error {
+	b.transport.CloseIdleConnections()
+	return b.server.Close()
+}
+
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		b.tunnel(w, r)
+		return
+	}
+	b.forward(w, r)
+}
+
+// tunnel serves CONNECT, used for HTTPS: the client's TLS passes through
+// untouched.
+func (b *Bridge) tunnel(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	upstream, err := dialSOCKS5(ctx, b.proxy, r.Host)
+	cancel()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, rw, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// Bytes the client sent after the CONNECT are already buffered.
+		_, _ = io.Copy(upstream, rw.Reader)
+		closeWrite(upstream)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(client, upstream)
+		closeWrite(client)
+	}()
+	wg.Wait()
+	client.Close()
+	upstream.Close()
+}
+
+func closeWrite(c net.Conn) {
+	if tc, ok := c.(interface{ CloseWrite() error }); ok {
+		_ = tc.CloseWrite()
+	} else {
+		_ = c.Close()
+	}
+}
+
+// forward serves plain HTTP requests, flushing as data arrives so live
+// streams keep flowing.
+func (b *Bridge) forward(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a proxy", http.StatusBadRequest)
+		return
+	}
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	req.Header.Del("Proxy-Connection")
+	req.Header.Del("Proxy-Authorization")
+
+	resp, err := b.transport.RoundTrip(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
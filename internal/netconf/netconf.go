@@ -0,0 +1,243 @@
+// Package netconf turns the network config into HTTP transports for Go
+// code and matching options for mpv, so API calls and playback use the
+// same proxy and TLS settings.
+package netconf
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"radio/internal/config"
+	"radio/internal/icy"
+)
+
+// Network holds the parsed network config. The zero value is not usable;
+// use New or Default.
+type Network struct {
+	// proxy is the configured proxy; nil uses HTTP(S)_PROXY.
+	proxy   *url.URL
+	noProxy string
+	tls     *tls.Config
+	caFile  string
+
+	mu     sync.Mutex
+	bridge *Bridge
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultNetwork *Network
+)
+
+// Default uses the proxy environment variables and the system CAs.
+func Default() *Network {
+	defaultOnce.Do(func() {
+		defaultNetwork, _ = New(config.NetworkConfig{})
+	})
+	return defaultNetwork
+}
+
+// New checks cfg and loads its CA bundle.
+func New(cfg config.NetworkConfig) (*Network, error) {
+	n := &Network{noProxy: getenv("NO_PROXY", "no_proxy"), caFile: cfg.CAFile}
+
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", cfg.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		n.proxy = u
+	}
+
+	if cfg.CAFile != "" || cfg.InsecureSkipVerify {
+		n.tls = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA bundle: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+			n.tls.RootCAs = pool
+		}
+	}
+	return n, nil
+}
+
+func getenv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Proxy picks the proxy for req, for use as http.Transport.Proxy. Like
+// the environment variables, a configured proxy skips NO_PROXY hosts and
+// localhost.
+func (n *Network) Proxy(req *http.Request) (*url.URL, error) {
+	if n.proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	if bypass(n.noProxy, req.URL) {
+		return nil, nil
+	}
+	return n.proxy, nil
+}
+
+// Transport returns a new transport using the config, which the caller
+// may tune further. It accepts the ICY status line of Shoutcast v1
+// servers on direct connections; through a SOCKS proxy the line still
+// fails as a malformed HTTP version.
+func (n *Network) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = n.Proxy
+	if n.tls != nil {
+		t.TLSClientConfig = n.tls.Clone()
+	}
+	SetDialTimeout(t, 30*time.Second)
+	return t
+}
+
+// SetDialTimeout changes how long t waits for a connection, keeping the
+// ICY status line handling of Transport.
+func SetDialTimeout(t *http.Transport, timeout time.Duration) {
+	dial := (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return icy.NewConn(c), nil
+	}
+}
+
+// Client returns a client using the config; timeout 0 means none.
+func (n *Network) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: n.Transport(), Timeout: timeout}
+}
+
+// MPVArgs returns the options for mpv to fetch streamURL the same way.
+// mpv only speaks HTTP proxies, so SOCKS5 goes through a local Bridge.
+func (n *Network) MPVArgs(streamURL string) ([]string, error) {
+	var args []string
+	if n.tls != nil {
+		if n.tls.InsecureSkipVerify {
+			args = append(args, "--tls-verify=no")
+		} else {
+			args = append(args, "--tls-verify=yes")
+		}
+		if n.caFile != "" {
+			args = append(args, "--tls-ca-file="+n.caFile)
+		}
+	}
+
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return args, nil
+	}
+	proxy, err := n.Proxy(&http.Request{URL: u})
+	if err != nil || proxy == nil {
+		return args, err
+	}
+	if proxy.Scheme == "socks5" || proxy.Scheme == "socks5h" {
+		bridge, err := n.socksBridge(proxy)
+		if err != nil {
+			return args, err
+		}
+		proxy = bridge.URL()
+	}
+	return append(args, "--http-proxy="+proxy.String()), nil
+}
+
+// socksBridge starts the bridge on first use.
+func (n *Network) socksBridge(proxy *url.URL) (*Bridge, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.bridge != nil && n.bridge.proxy.String() == proxy.String() {
+		return n.bridge, nil
+	}
+	if n.bridge != nil {
+		_ = n.bridge.Close()
+	}
+	b, err := NewBridge(proxy)
+	if err != nil {
+		return nil, err
+	}
+	n.bridge = b
+	return b, nil
+}
+
+// Close stops the SOCKS bridge, if one was started.
+func (n *Network) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.bridge == nil {
+		return nil
+	}
+	err := n.bridge.Close()
+	n.bridge = nil
+	return err
+}
+
+// bypass reports whether u should be fetched directly: localhost, or a
+// host matching a NO_PROXY entry ("*", a domain and its subdomains, an
+// optional :port, an IP or a CIDR range).
+func bypass(noProxy string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package netconf
+
+import (
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"radio/internal/config"
+)
+
+func TestBypass(t *testing.T) {
+	tests := []struct {
+		noProxy string
+		url     string
+		want    bool
+	}{
+		{"", "http://example.com/", false},
+		{"", "http://localhost:8000/", true},
+		{"", "http://127.0.0.1/", true},
+		{"*", "http://example.com/", true},
+		{"example.com", "http://example.com/", true},
+		{"example.com", "http://stream.example.com/", true},
+		{".example.com", "http://stream.example.com/", true},
+		{"example.com", "http://notexample.com/", false},
+		{"other.org, example.com", "https://example.com/", true},
+		{"example.com:8000", "http://example.com:8000/", true},
+		{"example.com:8000", "http://example.com/", false},
+		{"10.0.0.0/8", "http://10.1.2.3/", true},
+		{"10.0.0.0/8", "http://192.168.1.2/", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := bypass(tt.noProxy, u); got != tt.want {
+			t.Errorf("bypass(%q, %s) = %v, want %v", tt.noProxy, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNewRejectsBadProxy(t *testing.T) {
+	for _, p := range []string{"ftp://proxy:21", "not a url", "http://"} {
+		if _, err := New(config.NetworkConfig{Proxy: p}); err == nil {
+			t.Errorf("New accepted proxy %q", p)
+		}
+	}
+}
+
+func TestConfiguredProxyHonorsNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "internal.lan")
+	n, err := New(config.NetworkConfig{Proxy: "http://proxy:3128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for target, want := range map[string]string{
+		"http://stream.example.com/live": "http://proxy:3128",
+		"http://radio.internal.lan/live": "",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		got, err := n.Proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got == nil && want != "") || (got != nil && got.String() != want) {
+			t.Errorf("proxy for %s = %v, want %q", target, got, want)
+		}
+	}
+}
+
+func TestMPVArgs(t *testing.T) {
+	n, err := New(config.NetworkConfig{Proxy: "http://proxy:3128", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := n.MPVArgs("https://stream.example.com/live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--tls-verify=no", "--http-proxy=http://proxy:3128"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("args %q lack %s", joined, want)
+		}
+	}
+
+	args, _ = n.MPVArgs("http://localhost:8000/live")
+	if strings.Contains(strings.Join(args, " "), "--http-proxy") {
+		t.Errorf("localhost should not be proxied: %v", args)
+	}
+}
+
+func TestCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, cert, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Default().Client(0).Get(srv.URL); err == nil {
+		t.Fatal("expected the self-signed certificate to be rejected by default")
+	}
+
+	n, err := New(config.NetworkConfig{CAFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := n.Client(0).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("with the CA bundle: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// socksServer is a minimal SOCKS5 server for tests requiring user/pass.
+func socksServer(t *testing.T, user, pass string) (addr string, connects *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	connects = new(atomic.Int32)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 512)
+				if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+					return
+				}
+				io.ReadFull(conn, buf[:buf[1]])
+				conn.Write([]byte{5, 2})
+
+				io.ReadFull(conn, buf[:2])
+				name := make([]byte, buf[1])
+				io.ReadFull(conn, name)
+				io.ReadFull(conn, buf[:1])
+				pw := make([]byte, buf[0])
+				io.ReadFull(conn, pw)
+				if string(name) != user || string(pw) != pass {
+					conn.Write([]byte{1, 1})
+					return
+				}
+				conn.Write([]byte{1, 0})
+
+				io.ReadFull(conn, buf[:4])
+				var host string
+				switch buf[3] {
+				case 1:
+					io.ReadFull(conn, buf[:4])
+					host = net.IP(buf[:4]).String()
+				case 3:
+					io.ReadFull(conn, buf[:1])
+					h := make([]byte, buf[0])
+					io.ReadFull(conn, h)
+					host = string(h)
+				}
+				io.ReadFull(conn, buf[:2])
+				port := binary.BigEndian.Uint16(buf[:2])
+
+				target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				connects.Add(1)
+				conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+	return ln.Addr().String(), connects
+}
+
+func TestBridgeThroughSOCKS5(t *testing.T) {
+	addr, connects := socksServer(t, "alice", "secret")
+	proxy, _ := url.Parse("socks5://alice:secret@" + addr)
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "plain")
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secure")
+	}))
+	defer secure.Close()
+
+	bridge, err := NewBridge(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	// A client that only knows HTTP proxies, as mpv does.
+	transport := secure.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(bridge.URL())
+	client := &http.Client{Transport: transport}
+
+	for _, tt := range []struct{ url, want string }{{plain.URL, "plain"}, {secure.URL, "secure"}} {
+		resp, err := client.Get(tt.url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.want {
+			t.Errorf("GET %s = %q, want %q", tt.url, body, tt.want)
+		}
+	}
+	if connects.Load() < 2 {
+		t.Errorf("expected both requests to go through the SOCKS5 proxy, saw %d", connects.Load())
+	}
+}
+
+func TestSOCKS5WrongPassword(t *testing.T) {
+	addr, _ := socksServer(t, "alice", "secret")
+	proxy, _ := url.Parse("socks5://alice:wrong@" + addr)
+	if _, err := dialSOCKS5(t.Context(), proxy, "example.com:80"); err == nil {
+		t.Fatal("expected authentication to fail")
+	}
+}
@@ -0,0 +1,138 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/config"
+	"radio/internal/eq"
+	"radio/internal/netconf"
+	"radio/pkg/logger"
+)
+
+// Backend plays one stream at a time. Player drives an mpv process;
+// NativePlayer decodes in Go and needs no external programs.
+type Backend interface {
+	Play(ctx context.Context, streamURL string) error
+	Stop() error
+	IsRunning() bool
+	Volume() int
+	SetVolume(v int) error
+	Fade(ctx context.Context, target int, d time.Duration) error
+	StreamInfo() (StreamInfo, error)
+	// SetEqualizer applies to the running stream and later ones.
+	SetEqualizer(s eq.Settings) error
+	// SetGainOffset corrects the level by db, on top of the volume.
+	SetGainOffset(db float64) error
+	// AudioDevices lists the outputs streams can play on.
+	AudioDevices(ctx context.Context) ([]AudioDevice, error)
+	// SetAudioDevice picks the output, "" for the default. A stream
+	// started while the device is missing plays on the default.
+	SetAudioDevice(name string) error
+	// SetStreamConfig tunes fetching and buffering of later streams.
+	SetStreamConfig(c config.StreamConfig) error
+}
+
+// Tapper is implemented by backends that can share the audio they play,
+// so the visualizer needs no decoder of its own.
+type Tapper interface {
+	// SetTap hands every block played to tap, interleaved in
+	// audio.OutputFormat, after the equalizer and before the volume; nil
+	// removes it. It reports false when the backend cannot.
+	SetTap(tap audio.Filter) bool
+}
+
+const (
+	BackendAuto   = "auto"
+	BackendMPV    = "mpv"
+	BackendNative = "native"
+)
+
+// Open returns the configured backend wrapped in a Crossfader. "auto" (or
+// empty) uses mpv when it is installed, as it plays every codec, and the
+// native player otherwise. When the native player is chosen but mpv is
+// installed, mpv plays the streams whose codec the native player cannot
+// decode. Streams are fetched through network.
+func Open(cfg config.PlayerConfig, network *netconf.Network) (*Crossfader, error) {
+	maxBandwidth := cfg.HLSMaxKbps * 1000
+
+	backend := cfg.Backend
+	if backend == "" || backend == BackendAuto {
+		backend = BackendMPV
+		if _, err := exec.LookPath("mpv"); err != nil {
+			logger.Log.Info().Msg("mpv not found, using the native player")
+			backend = BackendNative
+		}
+	}
+
+	resolved := newChoiceCache()
+	newMPV := func() Backend {
+		p := New()
+		p.maxBandwidth = maxBandwidth
+		p.network = network
+		p.resolved = resolved
+		return p
+	}
+
+	var newBackend, fallback func() Backend
+	switch backend {
+	case BackendMPV:
+		newBackend = newMPV
+	case BackendNative:
+		newBackend = func() Backend {
+			p := NewNative(streamClient(network, cfg.Stream), nil)
+			p.pipeline.MaxBandwidth = maxBandwidth
+			p.network = network
+			return p
+		}
+		if _, err := exec.LookPath("mpv"); err == nil {
+			fallback = newMPV
+		}
+	default:
+		return nil, fmt.Errorf("unknown player backend %q", backend)
+	}
+	c := NewCrossfader(newBackend)
+	c.fallback = fallback
+	_ = c.SetStreamConfig(cfg.Stream)
+	return c, nil
+}
+
+// fade ramps b from its current volume to target over d. It returns
+// early, leaving the volume where it got to, when ctx is done.
+func fade(ctx context.Context, b Backend, target int, d time.Duration) error {
+	from := b.Volume()
+	start := time.Now()
+
+	ticker := time.NewTicker(fadeStep)
+	defer ticker.Stop()
+
+	for {
+		elapsed := time.Since(start)
+		if err := b.SetVolume(rampVolume(from, target, elapsed, d)); err != nil {
+			return err
+		}
+		if elapsed >= d {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+var (
+	_ Backend = (*Player)(nil)
+	_ Backend = (*NativePlayer)(nil)
+	_ Backend = (*Crossfader)(nil)
+	_ Tapper  = (*NativePlayer)(nil)
+	_ Tapper  = (*Crossfader)(nil)
+
+	_ StreamResolver = (*Player)(nil)
+	_ StreamResolver = (*Crossfader)(nil)
+)
@@ -0,0 +1,394 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/config"
+	"radio/internal/eq"
+	"radio/pkg/logger"
+)
+
+// startTimeout bounds how long a crossfade waits for the next stream to
+// start playing before giving up and leaving the old one on.
+const startTimeout = 15 * time.Second
+
+// CrossfadeMode says which station switches fade across.
+type CrossfadeMode int
+
+const (
+	CrossfadeOff CrossfadeMode = iota
+	// CrossfadeScan fades only when auto-switch moves on.
+	CrossfadeScan
+	CrossfadeAlways
+)
+
+var crossfadeModeNames = []string{"off", "scan", "always"}
+
+func (m CrossfadeMode) String() string {
+	if int(m) < len(crossfadeModeNames) {
+		return crossfadeModeNames[m]
+	}
+	return fmt.Sprintf("crossfade(%d)", int(m))
+}
+
+// Next cycles through the modes.
+func (m CrossfadeMode) Next() CrossfadeMode {
+	return (m + 1) % CrossfadeMode(len(crossfadeModeNames))
+}
+
+func ParseCrossfadeMode(s string) (CrossfadeMode, error) {
+	for i, name := range crossfadeModeNames {
+		if strings.EqualFold(s, name) {
+			return CrossfadeMode(i), nil
+		}
+	}
+	return CrossfadeOff, fmt.Errorf("unknown crossfade mode %q", s)
+}
+
+// Sound is the per-station processing a stream starts with.
+type Sound struct {
+	EQ     eq.Settings
+	GainDB float64
+	Stream config.StreamConfig
+}
+
+// playingWaiter is implemented by backends whose Play returns before audio
+// is heard, so a crossfade can wait for the stream to buffer.
+type playingWaiter interface {
+	WaitPlaying(ctx context.Context) error
+}
+
+// Crossfader is a Backend that can switch stations without a gap: the
+// next stream starts silently in a second backend, and once it plays the
+// two are faded across. Plain Play still switches hard.
+type Crossfader struct {
+	newBackend func() Backend
+	// fallback, if set, creates the backend for streams newBackend
+	// cannot decode.
+	fallback func() Backend
+
+	// switchMu serialises Play, Crossfade and Stop.
+	switchMu sync.Mutex
+
+	mu         sync.Mutex
+	current    Backend
+	volume     int
+	eq         eq.Settings
+	gainDB     float64
+	stream     config.StreamConfig
+	device     string
+	tap        audio.Filter
+	cancelFade context.CancelFunc
+}
+
+// NewCrossfader returns a Crossfader creating a backend per stream with
+// newBackend.
+func NewCrossfader(newBackend func() Backend) *Crossfader {
+	return &Crossfader{newBackend: newBackend, volume: 100}
+}
+
+// finishFade cuts a running crossfade short: the old stream stops and
+// the new one jumps to full volume.
+func (c *Crossfader) finishFade() {
+	c.mu.Lock()
+	cancel := c.cancelFade
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// prepare creates a backend with newBackend, set up with volume and sound.
+func (c *Crossfader) prepare(newBackend func() Backend, volume int, sound Sound) Backend {
+	b := newBackend()
+	_ = b.SetVolume(volume)
+	_ = b.SetEqualizer(sound.EQ)
+	_ = b.SetGainOffset(sound.GainDB)
+	_ = b.SetStreamConfig(sound.Stream)
+	c.mu.Lock()
+	device := c.device
+	c.mu.Unlock()
+	_ = b.SetAudioDevice(device)
+	return b
+}
+
+func (c *Crossfader) sound() Sound {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Sound{EQ: c.eq, GainDB: c.gainDB, Stream: c.stream}
+}
+
+func (c *Crossfader) Play(ctx context.Context, streamURL string) error {
+	c.finishFade()
+	c.switchMu.Lock()
+	defer c.switchMu.Unlock()
+
+	c.mu.Lock()
+	old := c.current
+	c.mu.Unlock()
+	if old != nil && old.IsRunning() {
+		_ = old.Stop()
+	}
+
+	next, err := c.start(ctx, streamURL, c.Volume(), c.sound())
+	if err != nil {
+		return err
+	}
+	c.setCurrent(next)
+	return nil
+}
+
+// start plays streamURL in a new backend, retrying with the fallback one
+// when the codec is not supported.
+func (c *Crossfader) start(ctx context.Context, streamURL string, volume int, sound Sound) (Backend, error) {
+	next := c.prepare(c.newBackend, volume, sound)
+	err := next.Play(ctx, streamURL)
+	if c.fallback != nil && errors.Is(err, audio.ErrUnsupportedCodec) {
+		logger.Log.Info().Err(err).Msg("falling back to mpv")
+		next = c.prepare(c.fallback, volume, sound)
+		err = next.Play(ctx, streamURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// setCurrent makes next the backend that volume and filter changes go
+// to, moving the tap over from the old one. The tap moves under c.mu so
+// a concurrent SetTap cannot leave it on the old stream.
+func (c *Crossfader) setCurrent(next Backend) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.current
+	c.current = next
+	if c.tap == nil {
+		return
+	}
+	if t, ok := old.(Tapper); ok {
+		t.SetTap(nil)
+	}
+	if t, ok := next.(Tapper); ok {
+		t.SetTap(c.tap)
+	}
+}
+
+// Crossfade switches to streamURL over d, starting it with sound so the
+// old stream keeps its own. If nothing is playing it is a plain Play.
+// When the next stream fails to start, the old one keeps playing and the
+// error is returned. A later Play, Crossfade or Stop finishes a running
+// fade at once.
+func (c *Crossfader) Crossfade(ctx context.Context, streamURL string, d time.Duration, sound Sound) error {
+	c.mu.Lock()
+	old := c.current
+	c.mu.Unlock()
+	if d <= 0 || old == nil || !old.IsRunning() {
+		_ = c.SetEqualizer(sound.EQ)
+		_ = c.SetGainOffset(sound.GainDB)
+		_ = c.SetStreamConfig(sound.Stream)
+		return c.Play(ctx, streamURL)
+	}
+
+	c.finishFade()
+	c.switchMu.Lock()
+	defer c.switchMu.Unlock()
+
+	fadeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c.mu.Lock()
+	c.cancelFade = cancel
+	old = c.current
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.cancelFade = nil
+		c.mu.Unlock()
+	}()
+
+	volume := c.Volume()
+	// The stream outlives this call, so it must not stop with fadeCtx.
+	next, err := c.start(ctx, streamURL, 0, sound)
+	if err != nil {
+		return err
+	}
+	if w, ok := next.(playingWaiter); ok {
+		waitCtx, cancelWait := context.WithTimeout(fadeCtx, startTimeout)
+		err := w.WaitPlaying(waitCtx)
+		cancelWait()
+		if err != nil {
+			_ = next.Stop()
+			return err
+		}
+	}
+
+	// From here volume and filter changes go to the new stream.
+	c.setCurrent(next)
+	c.mu.Lock()
+	c.eq, c.gainDB, c.stream = sound.EQ, sound.GainDB, sound.Stream
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = old.Fade(fadeCtx, 0, d)
+	}()
+	_ = next.Fade(fadeCtx, volume, d)
+	wg.Wait()
+
+	_ = old.Stop()
+	// An interrupted fade leaves the new stream part way up.
+	_ = next.SetVolume(c.Volume())
+	return nil
+}
+
+func (c *Crossfader) Stop() error {
+	c.finishFade()
+	c.switchMu.Lock()
+	defer c.switchMu.Unlock()
+
+	c.mu.Lock()
+	cur := c.current
+	c.mu.Unlock()
+	if cur == nil {
+		return errors.New("no running player")
+	}
+	return cur.Stop()
+}
+
+func (c *Crossfader) IsRunning() bool {
+	c.mu.Lock()
+	cur := c.current
+	c.mu.Unlock()
+	return cur != nil && cur.IsRunning()
+}
+
+func (c *Crossfader) Volume() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.volume
+}
+
+func (c *Crossfader) SetVolume(v int) error {
+	c.mu.Lock()
+	c.volume = clampVolume(v)
+	cur := c.current
+	c.mu.Unlock()
+	if cur == nil {
+		return nil
+	}
+	return cur.SetVolume(v)
+}
+
+func (c *Crossfader) Fade(ctx context.Context, target int, d time.Duration) error {
+	return fade(ctx, c, target, d)
+}
+
+func (c *Crossfader) StreamInfo() (StreamInfo, error) {
+	c.mu.Lock()
+	cur := c.current
+	c.mu.Unlock()
+	if cur == nil {
+		return StreamInfo{}, errors.New("no running player")
+	}
+	return cur.StreamInfo()
+}
+
+func (c *Crossfader) SetEqualizer(s eq.Settings) error {
+	c.mu.Lock()
+	c.eq = s
+	cur := c.current
+	c.mu.Unlock()
+	if cur == nil {
+		return nil
+	}
+	return cur.SetEqualizer(s)
+}
+
+func (c *Crossfader) SetGainOffset(db float64) error {
+	c.mu.Lock()
+	c.gainDB = db
+	cur := c.current
+	c.mu.Unlock()
+	if cur == nil {
+		return nil
+	}
+	return cur.SetGainOffset(db)
+}
+
+// AudioDevices asks the playing backend, or a fresh one when nothing
+// plays.
+func (c *Crossfader) AudioDevices(ctx context.Context) ([]AudioDevice, error) {
+	c.mu.Lock()
+	cur := c.current
+	c.mu.Unlock()
+	if cur == nil {
+		cur = c.newBackend()
+	}
+	return cur.AudioDevices(ctx)
+}
+
+func (c *Crossfader) SetAudioDevice(name string) error {
+	c.mu.Lock()
+	cur := c.current
+	c.mu.Unlock()
+	if cur != nil {
+		if err := cur.SetAudioDevice(name); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.device = name
+	c.mu.Unlock()
+	return nil
+}
+
+// SetTap follows the current stream across switches. It reports false
+// when the backends in use cannot share their audio.
+func (c *Crossfader) SetTap(tap audio.Filter) bool {
+	c.mu.Lock()
+	cur := c.current
+	c.mu.Unlock()
+	probe := cur
+	if probe == nil {
+		probe = c.newBackend()
+	}
+	if _, ok := probe.(Tapper); !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tap = tap
+	if t, ok := c.current.(Tapper); ok {
+		t.SetTap(tap)
+	}
+	return true
+}
+
+// ResolveStream reads the playlist ahead for the backend Play will use,
+// if it resolves playlists at all.
+func (c *Crossfader) ResolveStream(ctx context.Context, streamURL string) error {
+	b := c.newBackend()
+	r, ok := b.(StreamResolver)
+	if !ok {
+		return nil
+	}
+	_ = b.SetStreamConfig(c.sound().Stream)
+	return r.ResolveStream(ctx, streamURL)
+}
+
+// SetStreamConfig applies to streams started later.
+func (c *Crossfader) SetStreamConfig(sc config.StreamConfig) error {
+	c.mu.Lock()
+	c.stream = sc
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,226 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/config"
+	"radio/internal/eq"
+)
+
+type fakeBackend struct {
+	mu      sync.Mutex
+	url     string
+	running bool
+	volume  int
+	startAt int // volume when Play was called
+	eq      eq.Settings
+	gainDB  float64
+	// fallback backends decode every codec.
+	fallback bool
+}
+
+func (f *fakeBackend) Play(ctx context.Context, url string) error {
+	if url == "bad" {
+		return errors.New("cannot connect")
+	}
+	if url == "aac" && !f.fallback {
+		return fmt.Errorf("starting player: %w", audio.ErrUnsupportedCodec)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.url, f.running, f.startAt = url, true, f.volume
+	return nil
+}
+
+func (f *fakeBackend) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = false
+	return nil
+}
+
+func (f *fakeBackend) IsRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+func (f *fakeBackend) Volume() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.volume
+}
+
+func (f *fakeBackend) SetVolume(v int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.volume = v
+	return nil
+}
+
+func (f *fakeBackend) Fade(ctx context.Context, target int, d time.Duration) error {
+	return fade(ctx, f, target, d)
+}
+
+func (f *fakeBackend) StreamInfo() (StreamInfo, error) { return StreamInfo{Codec: f.url}, nil }
+
+func (f *fakeBackend) SetEqualizer(s eq.Settings) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.eq = s
+	return nil
+}
+
+func (f *fakeBackend) SetGainOffset(db float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gainDB = db
+	return nil
+}
+
+func (f *fakeBackend) AudioDevices(context.Context) ([]AudioDevice, error) { return nil, nil }
+func (f *fakeBackend) SetAudioDevice(string) error                         { return nil }
+func (f *fakeBackend) SetStreamConfig(config.StreamConfig) error           { return nil }
+
+func newTestCrossfader() (*Crossfader, *[]*fakeBackend) {
+	var created []*fakeBackend
+	c := NewCrossfader(func() Backend {
+		b := &fakeBackend{}
+		created = append(created, b)
+		return b
+	})
+	return c, &created
+}
+
+func TestCrossfade(t *testing.T) {
+	c, created := newTestCrossfader()
+	_ = c.SetVolume(80)
+
+	if err := c.Play(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	bass, _ := eq.FromPreset("bass")
+	if err := c.Crossfade(context.Background(), "b", 400*time.Millisecond, Sound{EQ: bass, GainDB: -3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*created) != 2 {
+		t.Fatalf("created %d backends, want 2", len(*created))
+	}
+	old, next := (*created)[0], (*created)[1]
+	if old.IsRunning() || old.Volume() != 0 {
+		t.Errorf("old stream running=%v volume=%d, want stopped after fading out", old.IsRunning(), old.Volume())
+	}
+	if old.eq.Preset == "Bass" || old.gainDB != 0 {
+		t.Error("the next station's sound was applied to the old one")
+	}
+	if !next.IsRunning() || next.startAt != 0 || next.Volume() != 80 {
+		t.Errorf("next stream running=%v started at %d ended at %d", next.IsRunning(), next.startAt, next.Volume())
+	}
+	if next.eq.Preset != "Bass" || next.gainDB != -3 {
+		t.Errorf("next stream sound = %+v, %v", next.eq, next.gainDB)
+	}
+	if info, _ := c.StreamInfo(); info.Codec != "b" {
+		t.Errorf("current stream is %q", info.Codec)
+	}
+}
+
+func TestCrossfadeFailureKeepsOldStream(t *testing.T) {
+	c, created := newTestCrossfader()
+	_ = c.Play(context.Background(), "a")
+
+	if err := c.Crossfade(context.Background(), "bad", time.Second, Sound{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	old := (*created)[0]
+	if !old.IsRunning() || old.Volume() != 100 {
+		t.Errorf("old stream running=%v volume=%d, want untouched", old.IsRunning(), old.Volume())
+	}
+	if info, _ := c.StreamInfo(); info.Codec != "a" {
+		t.Errorf("current stream is %q", info.Codec)
+	}
+}
+
+func TestUnsupportedCodecFallsBack(t *testing.T) {
+	c, created := newTestCrossfader()
+	if err := c.Play(context.Background(), "aac"); !errors.Is(err, audio.ErrUnsupportedCodec) {
+		t.Fatalf("Play error = %v without a fallback, want ErrUnsupportedCodec", err)
+	}
+
+	c.fallback = func() Backend {
+		b := &fakeBackend{fallback: true}
+		*created = append(*created, b)
+		return b
+	}
+	if err := c.Play(context.Background(), "aac"); err != nil {
+		t.Fatal(err)
+	}
+	if last := (*created)[len(*created)-1]; !last.fallback || !last.IsRunning() {
+		t.Error("the stream did not move to the fallback backend")
+	}
+	if err := c.Crossfade(context.Background(), "aac", 10*time.Millisecond, Sound{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := c.StreamInfo(); info.Codec != "aac" {
+		t.Errorf("current stream is %q", info.Codec)
+	}
+}
+
+func TestCrossfadeWithNothingPlaying(t *testing.T) {
+	c, created := newTestCrossfader()
+	if err := c.Crossfade(context.Background(), "a", time.Minute, Sound{GainDB: 2}); err != nil {
+		t.Fatal(err)
+	}
+	b := (*created)[0]
+	if !b.IsRunning() || b.Volume() != 100 || b.gainDB != 2 {
+		t.Errorf("stream running=%v volume=%d gain=%v", b.IsRunning(), b.Volume(), b.gainDB)
+	}
+}
+
+func TestStopInterruptsCrossfade(t *testing.T) {
+	c, created := newTestCrossfader()
+	_ = c.Play(context.Background(), "a")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Crossfade(context.Background(), "b", time.Minute, Sound{})
+	}()
+	time.Sleep(300 * time.Millisecond)
+
+	start := time.Now()
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Crossfade: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Stop waited for the whole fade")
+	}
+	for i, b := range *created {
+		if b.IsRunning() {
+			t.Errorf("backend %d still running", i)
+		}
+	}
+}
+
+func TestParseCrossfadeMode(t *testing.T) {
+	for _, m := range []CrossfadeMode{CrossfadeOff, CrossfadeScan, CrossfadeAlways} {
+		got, err := ParseCrossfadeMode(m.String())
+		if err != nil || got != m {
+			t.Errorf("ParseCrossfadeMode(%q) = %v, %v", m.String(), got, err)
+		}
+	}
+	if _, err := ParseCrossfadeMode("sometimes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if CrossfadeAlways.Next() != CrossfadeOff {
+		t.Error("Next should wrap around")
+	}
+}
@@ -0,0 +1,314 @@
+package player
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"radio/internal/audio"
+	"radio/pkg/logger"
+)
+
+const (
+	// deviceListTimeout bounds listing devices, which for mpv starts a
+	// process.
+	deviceListTimeout = 5 * time.Second
+	// deviceCacheTTL is how old a device list gets before it is listed
+	// again when a stream starts.
+	deviceCacheTTL = 30 * time.Second
+	// deviceWatchInterval is how often a stream playing on a chosen
+	// device checks that it is still there.
+	deviceWatchInterval = 10 * time.Second
+)
+
+// AudioDevice is an output streams can play on.
+type AudioDevice struct {
+	// Name is what SetAudioDevice takes, e.g.
+	// "pulse/alsa_output.usb-Headset-00.analog-stereo" for mpv.
+	Name        string
+	Description string
+}
+
+// ErrDeviceNotFound is returned when switching to a device that is not
+// connected.
+var ErrDeviceNotFound = errors.New("audio device not found")
+
+func hasDevice(devices []AudioDevice, name string) bool {
+	for _, d := range devices {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceCache remembers the output list, so a stream can start without
+// waiting for it. A list older than deviceCacheTTL is still used, and
+// refreshed in the background.
+type deviceCache struct {
+	list func(context.Context) ([]AudioDevice, error)
+
+	mu         sync.Mutex
+	devices    []AudioDevice
+	listed     time.Time
+	refreshing bool
+}
+
+var (
+	mpvDevices   = &deviceCache{list: listMPVDevices}
+	pulseDevices = &deviceCache{list: listPulseDevices}
+)
+
+// List asks for the outputs and remembers them.
+func (c *deviceCache) List(ctx context.Context) ([]AudioDevice, error) {
+	devices, err := c.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.devices, c.listed = devices, time.Now()
+	c.mu.Unlock()
+	return devices, nil
+}
+
+// check returns the device a stream should start on: name, or the
+// default ("") when the last list does not have it. Before the first
+// list, name is tried anyway. It never waits for the list.
+func (c *deviceCache) check(name string) string {
+	if name == "" {
+		return ""
+	}
+	c.mu.Lock()
+	devices, listed := c.devices, c.listed
+	c.mu.Unlock()
+
+	if time.Since(listed) > deviceCacheTTL {
+		c.refresh()
+	}
+	if listed.IsZero() {
+		return name
+	}
+	if !hasDevice(devices, name) {
+		logger.Log.Warn().Str("device", name).Msg("Audio device not found, using the default")
+		return ""
+	}
+	return name
+}
+
+// refresh lists the devices in the background, unless that is already
+// happening.
+func (c *deviceCache) refresh() {
+	c.mu.Lock()
+	if c.refreshing {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing = true
+	c.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), deviceListTimeout)
+		defer cancel()
+		if _, err := c.List(ctx); err != nil {
+			logger.Log.Warn().Err(err).Msg("Failed to list audio devices")
+		}
+		c.mu.Lock()
+		c.refreshing = false
+		c.mu.Unlock()
+	}()
+}
+
+// gone lists the devices afresh and reports whether name is missing. A
+// list that fails says nothing, so it is not taken as gone.
+func (c *deviceCache) gone(ctx context.Context, name string) bool {
+	ctx, cancel := context.WithTimeout(ctx, deviceListTimeout)
+	defer cancel()
+	devices, err := c.List(ctx)
+	return err == nil && !hasDevice(devices, name)
+}
+
+// watch calls onGone once name disappears while a stream plays on it,
+// looking every deviceWatchInterval until ctx is done.
+func (c *deviceCache) watch(ctx context.Context, name string, onGone func()) {
+	ticker := time.NewTicker(deviceWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if c.gone(ctx, name) && ctx.Err() == nil {
+			logger.Log.Warn().Str("device", name).Msg("Audio device disappeared, moving to the default")
+			onGone()
+			return
+		}
+	}
+}
+
+// mpvDeviceLine matches an entry of mpv --audio-device=help:
+//
+//	'pulse/alsa_output.pci-0000_00_1f.3.analog-stereo' (Built-in Audio Analog Stereo)
+var mpvDeviceLine = regexp.MustCompile(`^\s+'(.+)' \((.*)\)\s*$`)
+
+// parseMPVDevices reads mpv's device list. "auto" is left out as it is
+// what the empty name selects.
+func parseMPVDevices(out string) []AudioDevice {
+	var devices []AudioDevice
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		m := mpvDeviceLine.FindStringSubmatch(scanner.Text())
+		if m == nil || m[1] == "auto" {
+			continue
+		}
+		devices = append(devices, AudioDevice{Name: m[1], Description: m[2]})
+	}
+	return devices
+}
+
+func listMPVDevices(ctx context.Context) ([]AudioDevice, error) {
+	out, err := exec.CommandContext(ctx, "mpv", "--audio-device=help").Output()
+	devices := parseMPVDevices(string(out))
+	if err != nil && len(devices) == 0 {
+		return nil, fmt.Errorf("listing mpv audio devices: %w", err)
+	}
+	return devices, nil
+}
+
+func listPulseDevices(context.Context) ([]AudioDevice, error) {
+	sinks, err := audio.PulseDevices()
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]AudioDevice, 0, len(sinks))
+	for _, s := range sinks {
+		devices = append(devices, AudioDevice{Name: s.Name, Description: s.Description})
+	}
+	return devices, nil
+}
+
+// AudioDevices lists mpv's outputs.
+func (p *Player) AudioDevices(ctx context.Context) ([]AudioDevice, error) {
+	return p.devices.List(ctx)
+}
+
+// SetAudioDevice picks the output for later streams and moves the running
+// one, which only happens if the device is connected. "" is the default.
+func (p *Player) SetAudioDevice(name string) error {
+	if !p.IsRunning() {
+		p.mu.Lock()
+		p.device = name
+		p.mu.Unlock()
+		return nil
+	}
+	if p.devices.check(name) != name {
+		return fmt.Errorf("%w: %s", ErrDeviceNotFound, name)
+	}
+
+	value := name
+	if value == "" {
+		value = "auto"
+	}
+	if err := p.SetProperty("audio-device", value); err != nil {
+		return fmt.Errorf("switching audio device: %w", err)
+	}
+	p.mu.Lock()
+	p.device = name
+	p.activeDevice = name
+	p.mu.Unlock()
+	return nil
+}
+
+// moveToDefault switches the running mpv to the default output after its
+// device disappeared. The chosen device is kept for later streams.
+func (p *Player) moveToDefault() {
+	if err := p.SetProperty("audio-device", "auto"); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to move to the default audio device")
+		return
+	}
+	p.mu.Lock()
+	p.activeDevice = ""
+	p.mu.Unlock()
+}
+
+// recoverDevice restarts a stream that failed on device, on the default
+// output, if the device is gone. gen is the Play that failed; nothing
+// happens if another one has started since.
+func (p *Player) recoverDevice(gen int, device string) {
+	p.mu.Lock()
+	parent, url := p.parent, p.url
+	p.mu.Unlock()
+	if !p.devices.gone(parent, device) {
+		return
+	}
+
+	p.mu.Lock()
+	current := p.starts == gen && !p.running
+	p.mu.Unlock()
+	if !current || parent.Err() != nil {
+		return
+	}
+	logger.Log.Warn().Str("device", device).Msg("Audio device disappeared, restarting on the default")
+	if err := p.Play(parent, url); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to restart on the default audio device")
+	}
+}
+
+// AudioDevices lists the PulseAudio sinks.
+func (p *NativePlayer) AudioDevices(ctx context.Context) ([]AudioDevice, error) {
+	return p.devices.List(ctx)
+}
+
+// SetAudioDevice picks the output for later streams. A running stream is
+// restarted on it, which only happens if the device is connected.
+func (p *NativePlayer) SetAudioDevice(name string) error {
+	p.mu.Lock()
+	running, url, ctx := p.running, p.url, p.parent
+	p.mu.Unlock()
+
+	if running && p.devices.check(name) != name {
+		return fmt.Errorf("%w: %s", ErrDeviceNotFound, name)
+	}
+	p.mu.Lock()
+	p.device = name
+	p.mu.Unlock()
+	if !running {
+		return nil
+	}
+	return p.Play(ctx, url)
+}
+
+// restartOnDefault plays the stream of the run that done belongs to
+// again, after its device disappeared; Play then falls back to the
+// default output. Nothing happens if playback was stopped or moved on.
+func (p *NativePlayer) restartOnDefault(done chan struct{}) {
+	p.mu.Lock()
+	current := p.done == done
+	parent, url := p.parent, p.url
+	p.mu.Unlock()
+	if !current || parent.Err() != nil {
+		return
+	}
+	if err := p.Play(parent, url); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to restart on the default audio device")
+	}
+}
+
+// recoverDevice restarts a stream whose output failed, if the failure was
+// its device disappearing.
+func (p *NativePlayer) recoverDevice(done chan struct{}, device string) {
+	p.mu.Lock()
+	parent := p.parent
+	p.mu.Unlock()
+	if p.devices.gone(parent, device) {
+		logger.Log.Warn().Str("device", device).Msg("Audio device disappeared, restarting on the default")
+		p.restartOnDefault(done)
+	}
+}
@@ -0,0 +1,160 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/eq"
+	"radio/internal/loudness"
+)
+
+// mpvFilters is the --af chain: the loudness meter first so it hears the
+// stream as sent, then the per-station gain, then the equalizer.
+func (p *Player) mpvFilters() string {
+	filters := []string{"@r128:lavfi=[ebur128=metadata=1]"}
+	if p.gainDB != 0 {
+		filters = append(filters, fmt.Sprintf("lavfi=[volume=%.1fdB]", p.gainDB))
+	}
+	if f := eq.MPVFilter(p.eq); f != "" {
+		filters = append(filters, f)
+	}
+	return strings.Join(filters, ",")
+}
+
+// updateFilters pushes the chain to a running mpv. Replacing the chain
+// restarts mpv's meter, but blocks already read are kept in p.meter.
+func (p *Player) updateFilters() error {
+	p.mu.Lock()
+	running := p.running
+	filters := p.mpvFilters()
+	p.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+	_, err := p.command("af", "set", filters)
+	return err
+}
+
+// SetEqualizer swaps mpv's audio filters. The settings are kept for later
+// playback even if the running player cannot be reached.
+func (p *Player) SetEqualizer(s eq.Settings) error {
+	p.mu.Lock()
+	p.eq = s
+	p.mu.Unlock()
+	return p.updateFilters()
+}
+
+// SetGainOffset sets the per-station loudness correction in dB.
+func (p *Player) SetGainOffset(db float64) error {
+	p.mu.Lock()
+	p.gainDB = db
+	p.mu.Unlock()
+	return p.updateFilters()
+}
+
+// meterLoudness polls the momentary loudness mpv's ebur128 filter
+// reports, one 400 ms block at a time, until ctx is done.
+func (p *Player) meterLoudness(ctx context.Context, meter *loudness.Meter) {
+	ticker := time.NewTicker(loudness.BlockDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var metadata map[string]string
+		if p.GetProperty("af-metadata/r128", &metadata) != nil {
+			continue
+		}
+		if m, err := strconv.ParseFloat(metadata["lavfi.r128.M"], 64); err == nil {
+			meter.AddBlock(m)
+		}
+	}
+}
+
+// loudnessInfo fills the loudness fields of a StreamInfo from meter.
+func loudnessInfo(info *StreamInfo, meter *loudness.Meter, blockStep time.Duration) {
+	if meter == nil {
+		return
+	}
+	if lufs, ok := meter.Integrated(); ok {
+		info.LUFS = lufs
+		info.LoudnessMeasured = time.Duration(meter.Blocks()) * blockStep
+	}
+}
+
+// filterChain runs several audio filters in turn.
+type filterChain []audio.Filter
+
+func (c filterChain) Process(samples []float32) {
+	for _, f := range c {
+		f.Process(samples)
+	}
+}
+
+// updateFilters rebuilds the pipeline's chain: the loudness analyzer
+// first, then the equalizer, then the tap.
+func (p *NativePlayer) updateFilters() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var chain filterChain
+	if p.analyzer != nil {
+		chain = append(chain, p.analyzer)
+	}
+	if p.eq.Active() {
+		f := audio.OutputFormat
+		chain = append(chain, eq.NewProcessor(p.eq, f.SampleRate, f.Channels))
+	}
+	if p.tap != nil {
+		chain = append(chain, p.tap)
+	}
+	if len(chain) == 0 {
+		p.pipeline.SetFilter(nil)
+		return
+	}
+	p.pipeline.SetFilter(chain)
+}
+
+// SetEqualizer replaces the pipeline's filters, which takes effect with
+// the next buffer.
+func (p *NativePlayer) SetEqualizer(s eq.Settings) error {
+	p.mu.Lock()
+	p.eq = s
+	p.mu.Unlock()
+	p.updateFilters()
+	return nil
+}
+
+func (p *NativePlayer) SetTap(tap audio.Filter) bool {
+	p.mu.Lock()
+	p.tap = tap
+	p.mu.Unlock()
+	p.updateFilters()
+	return true
+}
+
+// SetGainOffset sets the per-station loudness correction in dB. It is
+// folded into the output gain with the volume.
+func (p *NativePlayer) SetGainOffset(db float64) error {
+	p.mu.Lock()
+	p.gainDB = db
+	p.mu.Unlock()
+	p.applyGain()
+	return nil
+}
+
+func (p *NativePlayer) applyGain() {
+	p.mu.Lock()
+	g := gain(p.volume) * float32(math.Pow(10, p.gainDB/20))
+	p.mu.Unlock()
+	p.pipeline.SetGain(g)
+}
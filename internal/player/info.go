@@ -0,0 +1,90 @@
+package player
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"radio/internal/hls"
+	"radio/internal/loudness"
+)
+
+// StreamInfo is what mpv reports about the stream it is decoding.
+type StreamInfo struct {
+	Codec      string
+	SampleRate int
+	Channels   int
+	// SampleFormat is mpv's name for the decoded format, e.g. "floatp".
+	SampleFormat string
+	// Bitrate is the measured audio bitrate in bits per second.
+	Bitrate int
+	// FileFormat is the demuxer, e.g. "mp3", "aac", "ogg" or "hls".
+	FileFormat string
+	// CacheSeconds is how much audio is buffered ahead.
+	CacheSeconds float64
+	// BufferFill is mpv's cache-buffering-state, 0-100.
+	BufferFill int
+	// Metadata holds stream tags, including icy-* headers.
+	Metadata map[string]string
+	// Variant is the HLS variant or DASH representation being played,
+	// if any.
+	Variant *hls.Variant
+	// LUFS is the integrated loudness measured since playback started,
+	// zero until something above the gates was heard.
+	LUFS float64
+	// LoudnessMeasured is how much audio went into LUFS.
+	LoudnessMeasured time.Duration
+	// AudioDevice is the output the stream plays on, "" for the default.
+	// It differs from the chosen one when that was missing at start.
+	AudioDevice string
+}
+
+type audioParams struct {
+	Format       string `json:"format"`
+	SampleRate   int    `json:"samplerate"`
+	ChannelCount int    `json:"channel-count"`
+}
+
+// StreamInfo queries the running player. Properties mpv does not know yet,
+// e.g. right after starting, are left zero.
+func (p *Player) StreamInfo() (StreamInfo, error) {
+	var info StreamInfo
+
+	if err := p.GetProperty("audio-codec-name", &info.Codec); err != nil {
+		// Nothing else will work either if the player is unreachable.
+		if errors.Is(err, ErrNoIPC) || !p.IsRunning() {
+			return info, err
+		}
+	}
+
+	var params audioParams
+	if p.GetProperty("audio-params", &params) == nil {
+		info.SampleRate = params.SampleRate
+		info.Channels = params.ChannelCount
+		info.SampleFormat = params.Format
+	}
+
+	var bitrate float64
+	if p.GetProperty("audio-bitrate", &bitrate) == nil {
+		info.Bitrate = int(bitrate)
+	}
+	_ = p.GetProperty("file-format", &info.FileFormat)
+	_ = p.GetProperty("demuxer-cache-duration", &info.CacheSeconds)
+	_ = p.GetProperty("cache-buffering-state", &info.BufferFill)
+
+	p.mu.Lock()
+	info.Variant = p.variant
+	info.AudioDevice = p.activeDevice
+	loudnessInfo(&info, p.meter, loudness.BlockDuration)
+	p.mu.Unlock()
+
+	var metadata map[string]string
+	if p.GetProperty("metadata", &metadata) == nil {
+		info.Metadata = make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			info.Metadata[strings.ToLower(k)] = v
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,100 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoIPC is returned when the running mpv cannot be controlled, e.g. it
+// has not opened its IPC socket yet.
+var ErrNoIPC = errors.New("player control socket unavailable")
+
+const ipcTimeout = 2 * time.Second
+
+var ipcRequestID atomic.Int64
+
+type ipcRequest struct {
+	Command   []any `json:"command"`
+	RequestID int64 `json:"request_id"`
+}
+
+type ipcResponse struct {
+	Data      json.RawMessage `json:"data"`
+	Error     string          `json:"error"`
+	RequestID int64           `json:"request_id"`
+	Event     string          `json:"event"`
+}
+
+// ipcCommand sends one command over mpv's JSON IPC and waits for the reply,
+// skipping the events mpv interleaves with responses.
+func ipcCommand(conn io.ReadWriter, args ...any) (json.RawMessage, error) {
+	req := ipcRequest{Command: args, RequestID: ipcRequestID.Add(1)}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("sending %v: %w", args[0], err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var resp ipcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue
+		}
+		if resp.Event != "" || resp.RequestID != req.RequestID {
+			continue
+		}
+		if resp.Error != "" && resp.Error != "success" {
+			return nil, fmt.Errorf("mpv %v: %s", args[0], resp.Error)
+		}
+		return resp.Data, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading reply to %v: %w", args[0], err)
+	}
+	return nil, fmt.Errorf("reading reply to %v: %w", args[0], io.ErrUnexpectedEOF)
+}
+
+// command runs an IPC command against the running mpv.
+func (p *Player) command(args ...any) (json.RawMessage, error) {
+	p.mu.Lock()
+	path := p.ipcPath
+	running := p.running
+	p.mu.Unlock()
+
+	if !running || path == "" {
+		return nil, errors.New("no running player")
+	}
+
+	conn, err := dialIPC(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoIPC, err)
+	}
+	defer conn.Close()
+	setIPCDeadline(conn, time.Now().Add(ipcTimeout))
+
+	return ipcCommand(conn, args...)
+}
+
+// SetProperty sets an mpv property on the running player.
+func (p *Player) SetProperty(name string, value any) error {
+	_, err := p.command("set_property", name, value)
+	return err
+}
+
+// GetProperty reads an mpv property from the running player into v.
+func (p *Player) GetProperty(name string, v any) error {
+	data, err := p.command("get_property", name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
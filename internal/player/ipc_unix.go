@@ -0,0 +1,30 @@
+//go:build !windows
+
+package player
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func newIPCPath(n int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("radio-mpv-%d-%d.sock", os.Getpid(), n))
+}
+
+func dialIPC(path string) (io.ReadWriteCloser, error) {
+	return net.DialTimeout("unix", path, ipcTimeout)
+}
+
+func setIPCDeadline(conn io.ReadWriteCloser, t time.Time) {
+	if c, ok := conn.(net.Conn); ok {
+		_ = c.SetDeadline(t)
+	}
+}
+
+func removeIPC(path string) {
+	_ = os.Remove(path)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package player
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func newIPCPath(n int) string {
+	return fmt.Sprintf(`\\.\pipe\radio-mpv-%d-%d`, os.Getpid(), n)
+}
+
+// mpv serves IPC on a named pipe, which opens like a file.
+func dialIPC(path string) (io.ReadWriteCloser, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+func setIPCDeadline(conn io.ReadWriteCloser, t time.Time) {
+	if f, ok := conn.(*os.File); ok {
+		_ = f.SetDeadline(t)
+	}
+}
+
+func removeIPC(string) {}
@@ -0,0 +1,233 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/eq"
+	"radio/internal/loudness"
+	"radio/internal/netconf"
+	"radio/pkg/logger"
+)
+
+// NativePlayer plays through the pure-Go audio pipeline. It handles MP3
+// and Ogg Vorbis streams; anything else fails in Play with
+// audio.ErrUnsupportedCodec.
+type NativePlayer struct {
+	pipeline *audio.Pipeline
+	newSink  func(device string) audio.Sink
+	// network is nil for the environment's proxy settings.
+	network *netconf.Network
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	running bool
+	volume  int
+	gainDB  float64
+	eq      eq.Settings
+	// device is the chosen output, activeDevice the one playing.
+	device       string
+	activeDevice string
+	devices      *deviceCache
+	// parent and url restart the stream on another device.
+	parent context.Context
+	url    string
+	// analyzer measures loudness of the current stream.
+	analyzer *loudness.Analyzer
+	tap      audio.Filter
+}
+
+// NewNative returns a native player. A nil client uses a default one and
+// a nil newSink plays through PulseAudio. newSink gets the output device,
+// "" for the default.
+func NewNative(client *http.Client, newSink func(device string) audio.Sink) *NativePlayer {
+	if newSink == nil {
+		newSink = func(device string) audio.Sink { return &audio.PulseSink{Device: device} }
+	}
+	return &NativePlayer{
+		pipeline: audio.NewPipeline(client),
+		newSink:  newSink,
+		volume:   100,
+		devices:  pulseDevices,
+	}
+}
+
+// startedSink reports when the pipeline opens it, which is after the
+// stream connected and a decoder was found.
+type startedSink struct {
+	audio.Sink
+	started chan struct{}
+}
+
+func (s *startedSink) Open(f audio.Format) error {
+	if err := s.Sink.Open(f); err != nil {
+		return err
+	}
+	close(s.started)
+	return nil
+}
+
+// Play starts streamURL, stopping what was playing. It waits until audio
+// is flowing so connection and codec errors are returned here.
+func (p *NativePlayer) Play(ctx context.Context, streamURL string) error {
+	if streamURL == "" {
+		return errors.New("empty stream URL")
+	}
+	_ = p.Stop()
+
+	p.mu.Lock()
+	device := p.device
+	p.mu.Unlock()
+	device = p.devices.check(device)
+
+	playCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	errc := make(chan error, 1)
+	sink := &startedSink{Sink: p.newSink(device), started: make(chan struct{})}
+
+	p.mu.Lock()
+	p.cancel = cancel
+	p.done = done
+	p.running = true
+	p.activeDevice = device
+	p.parent = ctx
+	p.url = streamURL
+	p.analyzer = loudness.NewAnalyzer(audio.OutputFormat.SampleRate, audio.OutputFormat.Channels)
+	p.mu.Unlock()
+	p.updateFilters()
+	p.applyGain()
+
+	go func() {
+		defer close(done)
+		err := p.pipeline.Play(playCtx, streamURL, sink)
+
+		p.mu.Lock()
+		if p.done == done {
+			p.running = false
+		}
+		p.mu.Unlock()
+
+		switch {
+		case playCtx.Err() != nil:
+			logger.Log.Debug().Msg("native player stopped")
+		case errors.Is(err, audio.ErrStreamEnded):
+			logger.Log.Info().Msg("player finished normally")
+		case err != nil:
+			logger.Log.Error().Err(err).Msg("native player failed")
+			if device != "" {
+				go p.recoverDevice(done, device)
+			}
+		}
+		errc <- err
+	}()
+	if device != "" {
+		go p.devices.watch(playCtx, device, func() { p.restartOnDefault(done) })
+	}
+
+	select {
+	case <-sink.started:
+		logger.Log.Info().Str("url", streamURL).Msg("started playing")
+		return nil
+	case err := <-errc:
+		_ = p.Stop()
+		if err == nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("starting player: %w", err)
+	}
+}
+
+func (p *NativePlayer) Stop() error {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	running := p.running
+	p.cancel, p.done = nil, nil
+	p.running = false
+	p.mu.Unlock()
+
+	if cancel == nil {
+		return errors.New("no running player")
+	}
+	cancel()
+	<-done
+	if running {
+		logger.Log.Info().Msg("player stopped")
+	}
+	return nil
+}
+
+func (p *NativePlayer) IsRunning() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+func (p *NativePlayer) Volume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+func (p *NativePlayer) SetVolume(v int) error {
+	v = clampVolume(v)
+
+	p.mu.Lock()
+	p.volume = v
+	p.mu.Unlock()
+
+	p.applyGain()
+	return nil
+}
+
+func (p *NativePlayer) Fade(ctx context.Context, target int, d time.Duration) error {
+	return fade(ctx, p, target, d)
+}
+
+// StreamInfo reports what the pipeline measured. Buffering is done by the
+// sink, so CacheSeconds and BufferFill stay zero.
+func (p *NativePlayer) StreamInfo() (StreamInfo, error) {
+	if !p.IsRunning() {
+		return StreamInfo{}, errors.New("no running player")
+	}
+	st := p.pipeline.Stats()
+
+	info := StreamInfo{
+		Codec:        st.Codec,
+		SampleRate:   st.Source.SampleRate,
+		Channels:     st.Source.Channels,
+		SampleFormat: "s16",
+		Bitrate:      st.Bitrate(),
+		FileFormat:   st.Codec,
+		Metadata:     make(map[string]string, len(st.Headers)+1),
+		Variant:      st.Variant,
+	}
+	p.mu.Lock()
+	info.AudioDevice = p.activeDevice
+	p.mu.Unlock()
+	for k, v := range st.Headers {
+		info.Metadata[k] = v
+	}
+	if st.Title != "" {
+		info.Metadata["icy-title"] = st.Title
+	}
+
+	p.mu.Lock()
+	if p.analyzer != nil {
+		loudnessInfo(&info, p.analyzer.Meter, loudness.BlockStep)
+	}
+	p.mu.Unlock()
+	return info, nil
+}
+
+// gain maps a 0-100 volume to an amplitude factor. Squaring it tracks
+// loudness more closely than a straight line, as mpv's volume does.
+func gain(volume int) float32 {
+	g := float32(volume) / 100
+	return g * g
+}
@@ -0,0 +1,159 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"radio/internal/audio"
+)
+
+func TestNativePlayer(t *testing.T) {
+	data, err := os.ReadFile("../audio/testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flac" {
+			w.Header().Set("Content-Type", "audio/flac")
+			w.Write([]byte("fLaC"))
+			return
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-genre", "Test")
+		w.Write(data)
+		w.(http.Flusher).Flush()
+		// Keep the connection open like a live stream.
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	sink := &audio.NullSink{Realtime: true}
+	p := NewNative(srv.Client(), func(string) audio.Sink { return sink })
+
+	if err := p.Play(context.Background(), srv.URL+"/flac"); !errors.Is(err, audio.ErrUnsupportedCodec) {
+		t.Fatalf("Play(flac) error = %v, want ErrUnsupportedCodec", err)
+	}
+	if p.IsRunning() {
+		t.Fatal("player running after a failed start")
+	}
+
+	if err := p.Play(context.Background(), srv.URL+"/mp3"); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	if !p.IsRunning() {
+		t.Fatal("player not running")
+	}
+	info, err := p.StreamInfo()
+	if err != nil {
+		t.Fatalf("StreamInfo: %v", err)
+	}
+	if info.Codec != audio.CodecMP3 || info.SampleRate == 0 || info.Metadata["icy-genre"] != "Test" {
+		t.Errorf("unexpected stream info %+v", info)
+	}
+
+	tap := &countingTap{}
+	if !p.SetTap(tap) {
+		t.Fatal("native player refused a tap")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for tap.samples.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tap.samples.Load() == 0 {
+		t.Error("tap saw no samples")
+	}
+
+	if err := p.SetVolume(150); err != nil || p.Volume() != 100 {
+		t.Errorf("SetVolume(150) = %v, volume %d", err, p.Volume())
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if p.IsRunning() {
+		t.Error("player running after Stop")
+	}
+	if err := p.Stop(); err == nil {
+		t.Error("expected an error stopping twice")
+	}
+}
+
+// countingTap counts the samples a player hands out.
+type countingTap struct {
+	samples atomic.Int64
+}
+
+func (c *countingTap) Process(samples []float32) {
+	c.samples.Add(int64(len(samples)))
+}
+
+// unpluggedSink fails once some audio went through, like a USB headset
+// pulled out mid-stream.
+type unpluggedSink struct {
+	audio.NullSink
+	writes int
+}
+
+func (s *unpluggedSink) Write(samples []float32) error {
+	if s.writes++; s.writes > 3 {
+		return errors.New("device unplugged")
+	}
+	return s.NullSink.Write(samples)
+}
+
+func TestNativePlayerFallsBackWhenDeviceDisappears(t *testing.T) {
+	data, err := os.ReadFile("../audio/testdata/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(data)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	var plugged atomic.Bool
+	plugged.Store(true)
+	defaultSink := &audio.NullSink{Realtime: true}
+	p := NewNative(srv.Client(), func(device string) audio.Sink {
+		if device == "usb" {
+			plugged.Store(false)
+			return &unpluggedSink{}
+		}
+		return defaultSink
+	})
+	p.devices = &deviceCache{list: func(context.Context) ([]AudioDevice, error) {
+		if plugged.Load() {
+			return []AudioDevice{{Name: "usb"}}, nil
+		}
+		return nil, nil
+	}}
+	if _, err := p.AudioDevices(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_ = p.SetAudioDevice("usb")
+
+	if err := p.Play(context.Background(), srv.URL); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	defer p.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for defaultSink.Frames() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if defaultSink.Frames() == 0 {
+		t.Fatal("playback did not move to the default device")
+	}
+	if info, _ := p.StreamInfo(); info.AudioDevice != "" {
+		t.Errorf("playing on %q, want the default", info.AudioDevice)
+	}
+}
@@ -6,29 +6,66 @@ import (
 	"fmt"
 	"os/exec"
 	"sync"
-
+	"sync/atomic"
+	"time"
+
+	"radio/internal/config"
+	"radio/internal/eq"
+	"radio/internal/hls"
+	"radio/internal/loudness"
+	"radio/internal/netconf"
 	"radio/pkg/logger"
 )
 
+// ipcSeq numbers control sockets, which must differ between players
+// running side by side while crossfading.
+var ipcSeq atomic.Int64
+
 type Player struct {
 	cmd      *exec.Cmd
 	mu       sync.Mutex
 	running  bool
 	stopping bool
 	wg       sync.WaitGroup
+	ipcPath  string
+	volume   int
+	// maxBandwidth caps the HLS variant, in bits per second.
+	maxBandwidth int
+	variant      *hls.Variant
+	resolved     *choiceCache
+	eq           eq.Settings
+	gainDB       float64
+	meter        *loudness.Meter
+	// device is the chosen output, activeDevice the one playing.
+	device       string
+	activeDevice string
+	devices      *deviceCache
+	// parent and url restart the stream on another device; starts
+	// counts Play calls, so a restart can tell it is not stale.
+	parent context.Context
+	url    string
+	starts int
+	stream config.StreamConfig
+	// network is nil for the environment's proxy settings.
+	network *netconf.Network
 }
 
 func New() *Player {
-	return &Player{}
+	return &Player{volume: 100, resolved: newChoiceCache(), devices: mpvDevices}
 }
 
 func (p *Player) Play(ctx context.Context, streamURL string) error {
-	p.mu.Lock()
-
 	if streamURL == "" {
-		p.mu.Unlock()
 		return errors.New("empty stream URL")
 	}
+	ch := p.playChoice(streamURL)
+	playURL := ch.mediaURL
+	p.mu.Lock()
+	device, stream := p.device, p.stream
+	p.mu.Unlock()
+	device = p.devices.check(device)
+
+	p.mu.Lock()
 
 	if p.running {
 		p.stopping = true
@@ -44,10 +81,20 @@ func (p *Player) Play(ctx context.Context, streamURL string) error {
 		p.cmd = nil
 	}
 
-	cmd := exec.CommandContext(ctx, "mpv",
+	ipcPath := newIPCPath(int(ipcSeq.Add(1)))
+	args := []string{
 		"--no-video", "--really-quiet", "--no-terminal", "--force-window=no", "--idle=no",
-		streamURL,
-	)
+		"--input-ipc-server=" + ipcPath,
+		fmt.Sprintf("--volume=%d", p.volume),
+	}
+	if device != "" {
+		args = append(args, "--audio-device="+device)
+	}
+	args = append(args, p.networkArgs(playURL)...)
+	args = append(args, streamArgs(stream)...)
+	args = append(args, bitrateArgs(ch, p.maxBandwidth)...)
+	args = append(args, "--af="+p.mpvFilters())
+	cmd := exec.CommandContext(ctx, "mpv", append(args, playURL)...)
 
 	if err := cmd.Start(); err != nil {
 		p.mu.Unlock()
@@ -55,12 +102,25 @@ func (p *Player) Play(ctx context.Context, streamURL string) error {
 	}
 
 	p.cmd = cmd
+	p.ipcPath = ipcPath
+	p.variant = ch.variant
+	p.activeDevice = device
+	p.parent, p.url = ctx, streamURL
+	p.starts++
+	gen := p.starts
+	p.meter = loudness.NewMeter()
 	p.running = true
 	p.wg.Add(1)
+	meterCtx, stopMeter := context.WithCancel(ctx)
+	go p.meterLoudness(meterCtx, p.meter)
+	if device != "" {
+		go p.devices.watch(meterCtx, device, p.moveToDefault)
+	}
 	p.mu.Unlock()
 
 	go func() {
 		err := cmd.Wait()
+		stopMeter()
 		p.mu.Lock()
 		defer p.mu.Unlock()
 		defer p.wg.Done()
@@ -69,6 +129,9 @@ func (p *Player) Play(ctx context.Context, streamURL string) error {
 			logger.Log.Debug().Msg("player stopped for restart")
 		} else if err != nil {
 			logger.Log.Error().Err(err).Msg("player crashed or exited with error")
+			if device != "" {
+				go p.recoverDevice(gen, device)
+			}
 		} else {
 			logger.Log.Info().Msg("player finished normally")
 		}
@@ -76,6 +139,10 @@ func (p *Player) Play(ctx context.Context, streamURL string) error {
 		p.running = false
 		p.cmd = nil
 		p.stopping = false
+		removeIPC(ipcPath)
+		if p.ipcPath == ipcPath {
+			p.ipcPath = ""
+		}
 	}()
 
 	logger.Log.Info().Str("url", streamURL).Msg("started playing")
@@ -115,3 +182,25 @@ func (p *Player) IsRunning() bool {
 	defer p.mu.Unlock()
 	return p.running
 }
+
+// WaitPlaying blocks until mpv has started playing audio, i.e. it has
+// connected and filled its buffer.
+func (p *Player) WaitPlaying(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if !p.IsRunning() {
+			return errors.New("player exited before playing")
+		}
+		var pos float64
+		if p.GetProperty("playback-time", &pos) == nil && pos > 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package player
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"radio/internal/config"
+	"radio/internal/eq"
+)
+
+func TestRampVolume(t *testing.T) {
+	tests := []struct {
+		from, to       int
+		elapsed, total time.Duration
+		expected       int
+	}{
+		{100, 0, 0, time.Minute, 100},
+		{100, 0, 30 * time.Second, time.Minute, 50},
+		{100, 0, 2 * time.Minute, time.Minute, 0},
+		{0, 80, 15 * time.Second, time.Minute, 20},
+		{0, 150, time.Minute, time.Minute, 100},
+		{50, 0, time.Second, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := rampVolume(tt.from, tt.to, tt.elapsed, tt.total); got != tt.expected {
+			t.Errorf("rampVolume(%d, %d, %s, %s) = %d, expected %d", tt.from, tt.to, tt.elapsed, tt.total, got, tt.expected)
+		}
+	}
+}
+
+type fakeConn struct {
+	in  *strings.Reader
+	out bytes.Buffer
+}
+
+func (c *fakeConn) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *fakeConn) Write(p []byte) (int, error) { return c.out.Write(p) }
+
+func TestIPCCommandSkipsEvents(t *testing.T) {
+	id := ipcRequestID.Load() + 1
+	conn := &fakeConn{in: strings.NewReader(
+		`{"event":"property-change","name":"volume"}` + "\n" +
+			`{"data":null,"error":"success","request_id":` + strconv.FormatInt(id-1, 10) + "}\n" +
+			`{"data":42,"error":"success","request_id":` + strconv.FormatInt(id, 10) + "}\n",
+	)}
+
+	data, err := ipcCommand(conn, "get_property", "volume")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "42" {
+		t.Errorf("expected 42, got %s", data)
+	}
+
+	var req ipcRequest
+	if err := json.Unmarshal(bytes.TrimSpace(conn.out.Bytes()), &req); err != nil {
+		t.Fatalf("invalid request %q: %v", conn.out.String(), err)
+	}
+	if req.RequestID != id || len(req.Command) != 2 || req.Command[0] != "get_property" {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestIPCCommandError(t *testing.T) {
+	id := ipcRequestID.Load() + 1
+	conn := &fakeConn{in: strings.NewReader(`{"error":"property not found","request_id":` + strconv.FormatInt(id, 10) + "}\n")}
+	if _, err := ipcCommand(conn, "get_property", "nope"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMPVFilters(t *testing.T) {
+	p := New()
+	if got := p.mpvFilters(); got != "@r128:lavfi=[ebur128=metadata=1]" {
+		t.Errorf("default filters = %q", got)
+	}
+
+	p.gainDB = -4.25
+	p.eq, _ = eq.FromPreset("bass")
+	got := p.mpvFilters()
+	want := "@r128:lavfi=[ebur128=metadata=1],lavfi=[volume=-4.2dB],lavfi=[equalizer=f=31"
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("filters = %q, want prefix %q", got, want)
+	}
+}
+
+func TestParseMPVDevices(t *testing.T) {
+	out := `List of detected audio devices:
+  'auto' (Autoselect device)
+  'pulse' (Default (pulse))
+  'pulse/alsa_output.usb-Headset-00.analog-stereo' (USB Headset (Analog Stereo))
+  'alsa' (Default (alsa))
+`
+	got := parseMPVDevices(out)
+	want := []AudioDevice{
+		{Name: "pulse", Description: "Default (pulse)"},
+		{Name: "pulse/alsa_output.usb-Headset-00.analog-stereo", Description: "USB Headset (Analog Stereo)"},
+		{Name: "alsa", Description: "Default (alsa)"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d devices, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("device %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDeviceCache(t *testing.T) {
+	var calls atomic.Int32
+	devices := []AudioDevice{{Name: "pulse/headset"}}
+	c := &deviceCache{list: func(context.Context) ([]AudioDevice, error) {
+		calls.Add(1)
+		return devices, nil
+	}}
+	ctx := context.Background()
+
+	if got := c.check("pulse/gone"); got != "pulse/gone" {
+		t.Errorf("device before the first list should be tried, got %q", got)
+	}
+	if _, err := c.List(ctx); err != nil {
+		t.Fatal(err)
+	}
+	n := calls.Load()
+	if got := c.check("pulse/headset"); got != "pulse/headset" {
+		t.Errorf("connected device = %q", got)
+	}
+	if got := c.check("pulse/gone"); got != "" {
+		t.Errorf("missing device should fall back to the default, got %q", got)
+	}
+	if got := c.check(""); got != "" {
+		t.Errorf("default = %q", got)
+	}
+	if calls.Load() != n {
+		t.Error("a fresh list was fetched again when a stream started")
+	}
+
+	devices = nil
+	if !c.gone(ctx, "pulse/headset") {
+		t.Error("unplugged device not reported gone")
+	}
+	failing := &deviceCache{list: func(context.Context) ([]AudioDevice, error) {
+		return nil, errors.New("no server")
+	}}
+	if failing.gone(ctx, "pulse/headset") {
+		t.Error("a failed list should not count as the device being gone")
+	}
+}
+
+func TestStreamArgs(t *testing.T) {
+	if args := streamArgs(config.StreamConfig{}); len(args) != 0 {
+		t.Errorf("defaults should add no options, got %v", args)
+	}
+
+	args := strings.Join(streamArgs(config.StreamConfig{
+		CacheMB:          64,
+		ReadaheadSeconds: 20,
+		TimeoutSeconds:   15,
+		UserAgent:        "Mozilla/5.0",
+		Proxy:            "http://proxy:3128",
+	}), " ")
+	for _, want := range []string{
+		"--cache=yes", "--demuxer-max-bytes=64MiB", "--cache-secs=20", "--demuxer-readahead-secs=20",
+		"--network-timeout=15", "--user-agent=Mozilla/5.0", "--http-proxy=http://proxy:3128",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args %q lack %s", args, want)
+		}
+	}
+}
+
+func TestStreamClientUserAgent(t *testing.T) {
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.UserAgent()
+	}))
+	defer srv.Close()
+
+	client := streamClient(nil, config.StreamConfig{UserAgent: "Mozilla/5.0", TimeoutSeconds: 5})
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("User-Agent", "RadioTerminal/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if ua := <-got; ua != "Mozilla/5.0" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+	if req.Header.Get("User-Agent") != "RadioTerminal/1.0" {
+		t.Error("the caller's request was modified")
+	}
+}
+
+func TestResolveStreamHandsVariantToPlay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/live":
+			w.Write([]byte("#EXTM3U\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=64000,CODECS=\"mp4a.40.2\"\nlow.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=128000,CODECS=\"mp4a.40.2\"\nhigh.m3u8\n"))
+		case "/low.m3u8", "/high.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6,\na.aac\n"))
+		case "/live.mpd":
+			w.Write([]byte(`<MPD type="dynamic"><Period><AdaptationSet contentType="audio">` +
+				`<Representation id="a" bandwidth="48000" codecs="mp4a.40.5"/>` +
+				`<Representation id="b" bandwidth="96000" codecs="mp4a.40.2"/>` +
+				`<Representation id="c" bandwidth="320000" codecs="mp4a.40.2"/>` +
+				`</AdaptationSet></Period></MPD>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := New()
+	p.maxBandwidth = 100000
+	if err := p.ResolveStream(context.Background(), srv.URL+"/live"); err != nil {
+		t.Fatal(err)
+	}
+	ch := p.playChoice(srv.URL + "/live")
+	if ch.mediaURL != srv.URL+"/low.m3u8" || ch.variant == nil || ch.variant.Bandwidth != 64000 {
+		t.Errorf("playChoice = %+v, want the 64 kbps variant", ch)
+	}
+	if args := bitrateArgs(ch, p.maxBandwidth); args != nil {
+		t.Errorf("bitrateArgs = %v for a resolved media playlist, want none", args)
+	}
+	if ch := p.playChoice(srv.URL + "/live"); ch.mediaURL != srv.URL+"/live" || ch.variant != nil {
+		t.Errorf("second playChoice = %+v, want the playlist read afresh", ch)
+	}
+
+	if err := p.ResolveStream(context.Background(), srv.URL+"/live.mpd"); err != nil {
+		t.Fatal(err)
+	}
+	ch = p.playChoice(srv.URL + "/live.mpd")
+	if ch.mediaURL != srv.URL+"/live.mpd" || ch.variant == nil || ch.variant.URI != "b" {
+		t.Errorf("playChoice = %+v, want representation b of the manifest", ch)
+	}
+	if args := bitrateArgs(ch, p.maxBandwidth); len(args) != 1 || args[0] != "--hls-bitrate=96000" {
+		t.Errorf("bitrateArgs = %v, want mpv to select representation b", args)
+	}
+
+	if err := p.ResolveStream(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("expected an error for a missing playlist")
+	}
+	ch = p.playChoice(srv.URL + "/missing")
+	if ch.mediaURL != srv.URL+"/missing" {
+		t.Errorf("playChoice = %+v after a failed resolve, want the original URL", ch)
+	}
+	if args := bitrateArgs(ch, p.maxBandwidth); len(args) != 1 || args[0] != "--hls-bitrate=100000" {
+		t.Errorf("bitrateArgs = %v, want mpv capped at the maximum bandwidth", args)
+	}
+}
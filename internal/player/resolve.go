@@ -0,0 +1,128 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"radio/internal/dash"
+	"radio/internal/hls"
+	"radio/pkg/logger"
+)
+
+const resolveTimeout = 10 * time.Second
+
+// StreamResolver is implemented by backends that read HLS playlists and
+// DASH manifests ahead of Play to pick the rendition. Resolving fetches
+// over the network, so callers run it off the UI goroutine; Play then
+// starts without touching the network first.
+type StreamResolver interface {
+	ResolveStream(ctx context.Context, streamURL string) error
+}
+
+// choice is the rendition picked for a playlist or manifest URL.
+type choice struct {
+	mediaURL string
+	variant  *hls.Variant
+	// dash says mpv gets the manifest and selects variant by bandwidth.
+	dash bool
+}
+
+// choiceCache hands choices from ResolveStream to the next Play of the
+// same URL. It is shared by the players one Crossfader creates, as the
+// stream may start in a different player than the one that resolved it.
+type choiceCache struct {
+	mu      sync.Mutex
+	choices map[string]choice
+}
+
+func newChoiceCache() *choiceCache {
+	return &choiceCache{choices: make(map[string]choice)}
+}
+
+func (c *choiceCache) put(streamURL string, ch choice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.choices[streamURL] = ch
+}
+
+// take returns the choice for streamURL and forgets it, so a later play
+// of the station reads the playlist afresh.
+func (c *choiceCache) take(streamURL string) (choice, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.choices[streamURL]
+	delete(c.choices, streamURL)
+	return ch, ok
+}
+
+// ResolveStream picks the variant of an HLS master playlist or DASH
+// manifest so mpv plays the rendition the info panel reports. If it
+// cannot be read, the error is returned and Play gives mpv the original
+// URL, which it may still manage.
+func (p *Player) ResolveStream(ctx context.Context, streamURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	p.mu.Lock()
+	maxBandwidth, stream, network := p.maxBandwidth, p.stream, p.network
+	p.mu.Unlock()
+	client := streamClient(network, stream)
+
+	ch := choice{mediaURL: streamURL, dash: dash.IsManifestURL(streamURL)}
+	if ch.dash {
+		m, err := dash.Fetch(ctx, client, streamURL)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("url", streamURL).Msg("could not read DASH manifest")
+			return err
+		}
+		v := hls.SelectVariant(representationVariants(m.Audio), maxBandwidth)
+		ch.variant = &v
+	} else {
+		mediaURL, variant, _, err := hls.Resolve(ctx, client, streamURL, maxBandwidth)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("url", streamURL).Msg("could not resolve HLS playlist")
+			return err
+		}
+		ch.mediaURL, ch.variant = mediaURL, variant
+	}
+	if ch.variant != nil {
+		logger.Log.Info().Str("variant", ch.variant.Label()).Msg("selected stream variant")
+	}
+	p.resolved.put(streamURL, ch)
+	return nil
+}
+
+// representationVariants describes DASH representations as variants, so
+// they are picked and shown like HLS ones.
+func representationVariants(reps []dash.Representation) []hls.Variant {
+	variants := make([]hls.Variant, 0, len(reps))
+	for _, r := range reps {
+		variants = append(variants, hls.Variant{URI: r.ID, Bandwidth: r.Bandwidth, Codecs: r.Codecs})
+	}
+	return variants
+}
+
+// playChoice is what mpv is given for streamURL: the choice ResolveStream
+// made, or streamURL itself.
+func (p *Player) playChoice(streamURL string) choice {
+	if ch, ok := p.resolved.take(streamURL); ok {
+		return ch
+	}
+	return choice{mediaURL: streamURL}
+}
+
+// bitrateArgs has mpv select the rendition of a playlist or manifest it
+// reads itself: the resolved DASH representation, or the best one within
+// maxBandwidth when nothing was resolved. mpv applies --hls-bitrate to
+// DASH too.
+func bitrateArgs(ch choice, maxBandwidth int) []string {
+	switch {
+	case ch.dash && ch.variant != nil:
+		return []string{fmt.Sprintf("--hls-bitrate=%d", ch.variant.Bandwidth)}
+	case ch.variant == nil && maxBandwidth > 0:
+		return []string{fmt.Sprintf("--hls-bitrate=%d", maxBandwidth)}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package player
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"radio/internal/config"
+	"radio/internal/netconf"
+	"radio/pkg/logger"
+)
+
+// streamArgs turns c into mpv options.
+func streamArgs(c config.StreamConfig) []string {
+	var args []string
+	if c.CacheMB > 0 || c.ReadaheadSeconds > 0 {
+		args = append(args, "--cache=yes")
+	}
+	if c.CacheMB > 0 {
+		args = append(args, fmt.Sprintf("--demuxer-max-bytes=%dMiB", c.CacheMB))
+	}
+	if c.ReadaheadSeconds > 0 {
+		args = append(args,
+			fmt.Sprintf("--cache-secs=%d", c.ReadaheadSeconds),
+			fmt.Sprintf("--demuxer-readahead-secs=%d", c.ReadaheadSeconds))
+	}
+	if c.TimeoutSeconds > 0 {
+		args = append(args, fmt.Sprintf("--network-timeout=%d", c.TimeoutSeconds))
+	}
+	if c.UserAgent != "" {
+		args = append(args, "--user-agent="+c.UserAgent)
+	}
+	if c.Proxy != "" {
+		args = append(args, "--http-proxy="+c.Proxy)
+	}
+	return args
+}
+
+// defaultStreamTimeout bounds connecting and waiting for the response
+// headers when the stream config sets no timeout. The stream itself may
+// run for as long as it likes.
+const defaultStreamTimeout = 15 * time.Second
+
+// streamClient is the HTTP client for fetching streams and playlists in
+// Go, on top of the shared network config. Cache and readahead are up to
+// the caller.
+func streamClient(network *netconf.Network, c config.StreamConfig) *http.Client {
+	if network == nil {
+		network = netconf.Default()
+	}
+	transport := network.Transport()
+	timeout := defaultStreamTimeout
+	if c.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	netconf.SetDialTimeout(transport, timeout)
+	transport.ResponseHeaderTimeout = timeout
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("proxy", c.Proxy).Msg("Ignoring invalid proxy")
+		} else {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+
+	var rt http.RoundTripper = transport
+	if c.UserAgent != "" {
+		rt = userAgentTransport{base: transport, userAgent: c.UserAgent}
+	}
+	return &http.Client{Transport: rt}
+}
+
+// userAgentTransport sends every request with its own User-Agent.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// SetStreamConfig applies to streams started later.
+func (p *Player) SetStreamConfig(c config.StreamConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stream = c
+	return nil
+}
+
+// SetStreamConfig applies to streams started later. The sink does the
+// buffering, so cache size and readahead are not used.
+func (p *NativePlayer) SetStreamConfig(c config.StreamConfig) error {
+	p.pipeline.SetClient(streamClient(p.network, c))
+	return nil
+}
+
+// networkArgs are the mpv options for the shared network config. They
+// come before streamArgs, so a station's own proxy wins.
+func (p *Player) networkArgs(playURL string) []string {
+	network := p.network
+	if network == nil {
+		network = netconf.Default()
+	}
+	args, err := network.MPVArgs(playURL)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Could not set up the proxy for mpv")
+	}
+	return args
+}
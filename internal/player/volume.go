@@ -0,0 +1,58 @@
+package player
+
+import (
+	"context"
+	"time"
+)
+
+const fadeStep = 200 * time.Millisecond
+
+// Volume returns the volume used for playback, 0-100.
+func (p *Player) Volume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+// SetVolume changes the volume of the running player and of later
+// playback. The new level is kept even if the player cannot be reached.
+func (p *Player) SetVolume(v int) error {
+	v = clampVolume(v)
+
+	p.mu.Lock()
+	p.volume = v
+	running := p.running
+	p.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+	return p.SetProperty("volume", v)
+}
+
+// Fade ramps the volume from its current level to target over d. It returns
+// early, leaving the volume where it got to, when ctx is done.
+func (p *Player) Fade(ctx context.Context, target int, d time.Duration) error {
+	return fade(ctx, p, target, d)
+}
+
+// rampVolume is the volume after elapsed of a linear fade.
+func rampVolume(from, to int, elapsed, total time.Duration) int {
+	if total <= 0 || elapsed >= total {
+		return clampVolume(to)
+	}
+	if elapsed <= 0 {
+		return clampVolume(from)
+	}
+	return clampVolume(from + int(float64(to-from)*float64(elapsed)/float64(total)))
+}
+
+func clampVolume(v int) int {
+	switch {
+	case v < 0:
+		return 0
+	case v > 100:
+		return 100
+	}
+	return v
+}
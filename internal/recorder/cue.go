@@ -0,0 +1,47 @@
+package recorder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type cueTrack struct {
+	file   string
+	artist string
+	title  string
+}
+
+// cueSheet lists the tracks of one recording session, one FILE per track.
+type cueSheet struct {
+	performer string
+	title     string
+	fileType  string
+	tracks    []cueTrack
+}
+
+func cueFileType(ext string) string {
+	if ext == "mp3" {
+		return "MP3"
+	}
+	return "WAVE"
+}
+
+func cueQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+func (c *cueSheet) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PERFORMER %s\n", cueQuote(c.performer))
+	fmt.Fprintf(&b, "TITLE %s\n", cueQuote(c.title))
+	for i, t := range c.tracks {
+		fmt.Fprintf(&b, "FILE %s %s\n", cueQuote(t.file), c.fileType)
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n", i+1)
+		if t.artist != "" {
+			fmt.Fprintf(&b, "    PERFORMER %s\n", cueQuote(t.artist))
+		}
+		fmt.Fprintf(&b, "    TITLE %s\n", cueQuote(t.title))
+		b.WriteString("    INDEX 01 00:00:00\n")
+	}
+	return b.String()
+}
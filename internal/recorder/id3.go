@@ -0,0 +1,37 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// id3v2Tag builds an ID3v2.4 tag with UTF-8 text frames. Empty values are
+// skipped.
+func id3v2Tag(frames map[string]string) []byte {
+	var body bytes.Buffer
+	for _, id := range []string{"TPE1", "TIT2", "TALB", "TRCK", "TDRC", "TENC"} {
+		value, ok := frames[id]
+		if !ok || value == "" {
+			continue
+		}
+		payload := append([]byte{0x03}, value...) // 0x03: UTF-8
+		body.WriteString(id)
+		body.Write(synchsafe(len(payload)))
+		body.Write([]byte{0, 0})
+		body.Write(payload)
+	}
+
+	var tag bytes.Buffer
+	tag.WriteString("ID3")
+	tag.Write([]byte{4, 0, 0})
+	tag.Write(synchsafe(body.Len()))
+	tag.Write(body.Bytes())
+	return tag.Bytes()
+}
+
+func synchsafe(n int) []byte {
+	var out [4]byte
+	binary.BigEndian.PutUint32(out[:], uint32(
+		(n&0x7f)|((n>>7)&0x7f)<<8|((n>>14)&0x7f)<<16|((n>>21)&0x7f)<<24))
+	return out[:]
+}
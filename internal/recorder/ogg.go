@@ -0,0 +1,198 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+const (
+	oggHeaderLen = 27
+	oggFlagBOS   = 0x02
+	// maxHeldPages bounds how long we wait for a comment header.
+	maxHeldPages = 64
+)
+
+var oggCapture = []byte("OggS")
+
+// oggSplitter splits a chained Ogg stream (how Icecast delivers Vorbis and
+// Opus) into one file per logical stream. Each song starts with a BOS page
+// followed by a comment header carrying its tags, so every file is a valid
+// Ogg file with its Vorbis comments intact; the comments also name the file.
+type oggSplitter struct {
+	*trackSplitter
+	buf []byte
+
+	collecting bool
+	held       [][]byte
+	serial     uint32
+	packet     []byte
+	packets    int
+}
+
+func (s *oggSplitter) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for {
+		page, ok := s.nextPage()
+		if !ok {
+			return len(p), nil
+		}
+		if err := s.handlePage(page); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// nextPage cuts one complete page off the front of the buffer, skipping
+// any garbage before the capture pattern.
+func (s *oggSplitter) nextPage() ([]byte, bool) {
+	if i := bytes.Index(s.buf, oggCapture); i != 0 {
+		if i < 0 {
+			if len(s.buf) > 3 {
+				s.buf = s.buf[len(s.buf)-3:]
+			}
+			return nil, false
+		}
+		s.buf = s.buf[i:]
+	}
+	if len(s.buf) < oggHeaderLen {
+		return nil, false
+	}
+	nsegs := int(s.buf[26])
+	if len(s.buf) < oggHeaderLen+nsegs {
+		return nil, false
+	}
+	size := oggHeaderLen + nsegs
+	for _, lace := range s.buf[oggHeaderLen : oggHeaderLen+nsegs] {
+		size += int(lace)
+	}
+	if len(s.buf) < size {
+		return nil, false
+	}
+
+	page := make([]byte, size)
+	copy(page, s.buf[:size])
+	s.buf = s.buf[size:]
+	return page, true
+}
+
+func (s *oggSplitter) handlePage(page []byte) error {
+	serial := binary.LittleEndian.Uint32(page[14:18])
+
+	if page[5]&oggFlagBOS != 0 && !s.collecting {
+		s.collecting = true
+		s.held = nil
+		s.serial = serial
+		s.packet = nil
+		s.packets = 0
+	}
+
+	if !s.collecting {
+		_, err := s.trackSplitter.Write(page)
+		return err
+	}
+
+	s.held = append(s.held, page)
+	if serial == s.serial {
+		nsegs := int(page[26])
+		body := page[oggHeaderLen+nsegs:]
+		for _, lace := range page[oggHeaderLen : oggHeaderLen+nsegs] {
+			s.packet = append(s.packet, body[:lace]...)
+			body = body[lace:]
+			if lace == 255 {
+				continue
+			}
+			s.packets++
+			if s.packets == 2 {
+				return s.finishHeaders(parseOggComments(s.packet))
+			}
+			s.packet = nil
+		}
+	}
+
+	if len(s.held) >= maxHeldPages {
+		return s.finishHeaders(trackInfo{})
+	}
+	return nil
+}
+
+func (s *oggSplitter) finishHeaders(info trackInfo) error {
+	s.collecting = false
+	held := s.held
+	s.held = nil
+	s.packet = nil
+
+	if err := s.startTrack(info); err != nil {
+		return err
+	}
+	for _, page := range held {
+		if _, err := s.trackSplitter.Write(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *oggSplitter) Close() error {
+	if s.collecting && len(s.held) > 0 {
+		if err := s.finishHeaders(trackInfo{}); err != nil {
+			s.trackSplitter.Close()
+			return err
+		}
+	}
+	return s.trackSplitter.Close()
+}
+
+// parseOggComments reads ARTIST and TITLE from a Vorbis or Opus comment
+// header packet.
+func parseOggComments(packet []byte) trackInfo {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		packet = packet[7:]
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		packet = packet[8:]
+	default:
+		return trackInfo{}
+	}
+
+	readLen := func() (int, bool) {
+		if len(packet) < 4 {
+			return 0, false
+		}
+		n := int(binary.LittleEndian.Uint32(packet))
+		packet = packet[4:]
+		return n, n <= len(packet)
+	}
+
+	vendorLen, ok := readLen()
+	if !ok {
+		return trackInfo{}
+	}
+	packet = packet[vendorLen:]
+
+	if len(packet) < 4 {
+		return trackInfo{}
+	}
+	count := int(binary.LittleEndian.Uint32(packet))
+	packet = packet[4:]
+
+	var info trackInfo
+	for i := 0; i < count; i++ {
+		n, ok := readLen()
+		if !ok {
+			break
+		}
+		key, value, found := strings.Cut(string(packet[:n]), "=")
+		packet = packet[n:]
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "ARTIST":
+			info.artist = value
+		case "TITLE":
+			info.title = value
+		}
+	}
+	return info
+}
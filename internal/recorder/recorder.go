@@ -0,0 +1,298 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/client"
+	"radio/internal/netconf"
+	"radio/pkg/logger"
+)
+
+var ErrNotRecording = errors.New("not recording")
+
+// Recorder saves a station's stream to disk as it is received, without
+// re-encoding. It runs its own connection, independent of the player.
+type Recorder struct {
+	dir        string
+	httpClient *http.Client
+
+	// SplitTracks records into a per-session directory with one tagged file
+	// per song and a cue sheet, instead of a single file. Songs are detected
+	// from ICY StreamTitle changes, or from chained streams for Ogg.
+	SplitTracks bool
+
+	mu     sync.Mutex
+	active *session
+}
+
+type session struct {
+	station client.Station
+	path    string
+	started time.Time
+	cancel  context.CancelFunc
+	done    chan struct{}
+	tracks  func() (string, int)
+
+	mu    sync.Mutex
+	bytes int64
+	err   error
+}
+
+type Status struct {
+	Recording bool
+	Station   client.Station
+	Path      string
+	Started   time.Time
+	Bytes     int64
+	// Err is set when the recording stopped on its own, e.g. the stream
+	// ended or the disk filled up.
+	Err error
+	// Track and Tracks describe the current song when splitting.
+	Track  string
+	Tracks int
+}
+
+func New(dir string, httpClient *http.Client) *Recorder {
+	if httpClient == nil {
+		httpClient = netconf.Default().Client(0)
+	}
+	return &Recorder{dir: dir, httpClient: httpClient}
+}
+
+// Start begins recording station, stopping any recording in progress. It
+// returns once the stream has answered, with the path of the recording file,
+// or of the session directory when splitting tracks.
+func (r *Recorder) Start(ctx context.Context, station client.Station) (string, error) {
+	if station.URL == "" {
+		return "", errors.New("empty stream URL")
+	}
+	_ = r.Stop()
+
+	if err := os.MkdirAll(r.dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("creating recordings directory: %w", err)
+	}
+
+	// The stream is opened the way the native player opens it, so station
+	// lists and HLS playlists are followed to the audio. ICY metadata is
+	// stripped and reaches the splitter in step with the audio.
+	ctx, cancel := context.WithCancel(ctx)
+	var onMeta func(string)
+	stream, err := audio.OpenStream(ctx, r.httpClient, station.URL, 0, func(title string) {
+		if onMeta != nil {
+			onMeta(title)
+		}
+	})
+	if err != nil {
+		cancel()
+		return "", err
+	}
+
+	started := time.Now()
+	ext := Extension(station.Codec, stream.ContentType)
+	if ext == "bin" {
+		ext = Extension(stream.Codec, "")
+	}
+	_, hasMeta := stream.Headers["icy-metaint"]
+
+	s := &session{
+		station: station,
+		started: started,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	var sink io.WriteCloser
+	if r.SplitTracks {
+		s.path = filepath.Join(r.dir, strings.TrimSuffix(FileName(station.Name, started, ext), "."+ext))
+		splitter, err := newTrackSplitter(s.path, station.Name, ext, started)
+		if err != nil {
+			stream.Close()
+			cancel()
+			return "", err
+		}
+		s.tracks = splitter.track
+
+		switch {
+		case ext == "ogg" || ext == "opus":
+			sink = &oggSplitter{trackSplitter: splitter}
+		case hasMeta:
+			meta := &icySplitter{trackSplitter: splitter}
+			onMeta = meta.onMeta
+			sink = meta
+		default:
+			logger.Log.Warn().Str("url", station.URL).Msg("stream sends no metadata, recording a single track")
+			sink = splitter
+		}
+	} else {
+		s.path = filepath.Join(r.dir, FileName(station.Name, started, ext))
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+		if err != nil {
+			stream.Close()
+			cancel()
+			return "", fmt.Errorf("creating recording file: %w", err)
+		}
+		sink = f
+	}
+
+	r.mu.Lock()
+	r.active = s
+	r.mu.Unlock()
+
+	go s.run(ctx, stream, stream, sink)
+
+	logger.Log.Info().Str("url", station.URL).Str("file", s.path).Msg("recording started")
+	return s.path, nil
+}
+
+func (s *session) run(ctx context.Context, conn io.Closer, body io.Reader, sink io.WriteCloser) {
+	defer close(s.done)
+	defer conn.Close()
+
+	_, err := io.Copy(sink, &countingReader{r: body, s: s})
+	if cerr := sink.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case ctx.Err() != nil:
+		// Stopped by us.
+		err = nil
+	case err == nil:
+		// The server closed the stream.
+		err = io.EOF
+	}
+
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("file", s.path).Msg("recording ended")
+	} else {
+		logger.Log.Info().Str("file", s.path).Msg("recording stopped")
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	s *session
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.s.mu.Lock()
+	c.s.bytes += int64(n)
+	c.s.mu.Unlock()
+	return n, err
+}
+
+// Stop ends the current recording and waits for the file to be closed.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	s := r.active
+	r.active = nil
+	r.mu.Unlock()
+
+	if s == nil {
+		return ErrNotRecording
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+func (r *Recorder) Status() Status {
+	r.mu.Lock()
+	s := r.active
+	r.mu.Unlock()
+
+	if s == nil {
+		return Status{}
+	}
+
+	var track string
+	var tracks int
+	if s.tracks != nil {
+		track, tracks = s.tracks()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Status{
+		Recording: s.err == nil,
+		Station:   s.station,
+		Path:      s.path,
+		Started:   s.started,
+		Bytes:     s.bytes,
+		Err:       s.err,
+		Track:     track,
+		Tracks:    tracks,
+	}
+}
+
+// Extension picks the file extension for a stream from the codec reported
+// by radio-browser, falling back to the response Content-Type.
+func Extension(codec, contentType string) string {
+	switch strings.ToUpper(strings.TrimSpace(codec)) {
+	case "MP3":
+		return "mp3"
+	case "AAC", "AAC+", "AACP", "HE-AAC":
+		return "aac"
+	case "OGG", "VORBIS":
+		return "ogg"
+	case "OPUS":
+		return "opus"
+	case "FLAC":
+		return "flac"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	case "audio/aac", "audio/aacp", "audio/x-aac":
+		return "aac"
+	case "audio/ogg", "application/ogg":
+		return "ogg"
+	case "audio/opus":
+		return "opus"
+	case "audio/flac", "audio/x-flac":
+		return "flac"
+	}
+	return "bin"
+}
+
+// FileName builds "<station> - <timestamp>.<ext>" with characters that are
+// unsafe in file names replaced.
+func FileName(station string, started time.Time, ext string) string {
+	name := SanitizeName(station)
+	if name == "" {
+		name = "recording"
+	}
+	return fmt.Sprintf("%s - %s.%s", name, started.Format("2006-01-02_15-04-05"), ext)
+}
+
+func SanitizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Trim(strings.TrimSpace(s), ".")
+}
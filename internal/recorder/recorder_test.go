@@ -0,0 +1,203 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+)
+
+// streamServer writes chunk every few milliseconds until the client leaves.
+func streamServer(t *testing.T, contentType string, chunk []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		flusher := w.(http.Flusher)
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func waitForBytes(t *testing.T, r *Recorder, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Status().Bytes < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d bytes, got %d", n, r.Status().Bytes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRecordStream(t *testing.T) {
+	chunk := []byte("ID3-fake-mp3-frame-")
+	server := streamServer(t, "audio/mpeg", chunk)
+	dir := t.TempDir()
+
+	r := New(dir, server.Client())
+	path, err := r.Start(context.Background(), client.Station{Name: "Rock/FM: Live", URL: server.URL})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if filepath.Dir(path) != dir {
+		t.Errorf("expected file in %s, got %s", dir, path)
+	}
+	if !strings.HasPrefix(filepath.Base(path), "Rock_FM_ Live - ") || filepath.Ext(path) != ".mp3" {
+		t.Errorf("unexpected file name: %s", filepath.Base(path))
+	}
+
+	waitForBytes(t, r, int64(len(chunk)*3))
+	st := r.Status()
+	if !st.Recording || st.Path != path {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if r.Status().Recording {
+		t.Error("expected recording to be stopped")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	if len(data) < len(chunk)*3 || !bytes.HasPrefix(data, chunk) {
+		t.Errorf("unexpected recording contents (%d bytes)", len(data))
+	}
+	if int64(len(data)) < st.Bytes {
+		t.Errorf("file has %d bytes, status reported %d", len(data), st.Bytes)
+	}
+}
+
+func TestRecordStreamEnded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("short"))
+	}))
+	defer server.Close()
+
+	r := New(t.TempDir(), server.Client())
+	if _, err := r.Start(context.Background(), client.Station{Name: "Short", URL: server.URL, Codec: "OGG"}); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Status().Err == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected recording to end with the stream")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.Status().Recording {
+		t.Error("expected Recording to be false after stream ended")
+	}
+	if filepath.Ext(r.Status().Path) != ".ogg" {
+		t.Errorf("expected .ogg from codec, got %s", r.Status().Path)
+	}
+}
+
+func TestRecordFollowsPlaylists(t *testing.T) {
+	// An MPEG frame header, so the codec is told from the data.
+	audio := append([]byte{0xFF, 0xFB, 0x90, 0x00}, "fake-mp3-frame"...)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/radio.pls":
+			w.Header().Set("Content-Type", "audio/x-scpls")
+			w.Write([]byte("[playlist]\nFile1=/live.m3u8\nTitle1=Live\n"))
+		case "/live.m3u8":
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\na.mp3\n#EXTINF:1,\nb.mp3\n#EXT-X-ENDLIST\n"))
+		case "/a.mp3", "/b.mp3":
+			w.Write(audio)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	r := New(t.TempDir(), server.Client())
+	path, err := r.Start(context.Background(), client.Station{Name: "Listed", URL: server.URL + "/radio.pls"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForEnd(t, r)
+	r.Stop()
+
+	if filepath.Ext(path) != ".mp3" {
+		t.Errorf("expected .mp3 from the stream, got %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	if want := append(append([]byte{}, audio...), audio...); !bytes.Equal(data, want) {
+		t.Errorf("expected both segments recorded, got %q", data)
+	}
+}
+
+func TestStartBadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	r := New(dir, server.Client())
+	if _, err := r.Start(context.Background(), client.Station{Name: "Gone", URL: server.URL}); err == nil {
+		t.Fatal("expected error for 404 stream")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected no file to be created, got %d", len(entries))
+	}
+}
+
+func TestStopWhenIdle(t *testing.T) {
+	r := New(t.TempDir(), nil)
+	if err := r.Stop(); !errors.Is(err, ErrNotRecording) {
+		t.Errorf("expected ErrNotRecording, got %v", err)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		codec, contentType, want string
+	}{
+		{"MP3", "", "mp3"},
+		{"AAC+", "", "aac"},
+		{"aac", "", "aac"},
+		{"OGG", "", "ogg"},
+		{"OPUS", "", "opus"},
+		{"FLAC", "", "flac"},
+		{"", "audio/mpeg", "mp3"},
+		{"", "audio/aacp; charset=binary", "aac"},
+		{"", "application/ogg", "ogg"},
+		{"UNKNOWN", "text/html", "bin"},
+	}
+	for _, tt := range tests {
+		if got := Extension(tt.codec, tt.contentType); got != tt.want {
+			t.Errorf("Extension(%q, %q) = %q, want %q", tt.codec, tt.contentType, got, tt.want)
+		}
+	}
+}
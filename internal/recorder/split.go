@@ -0,0 +1,217 @@
+package recorder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"radio/internal/icy"
+	"radio/pkg/logger"
+)
+
+type trackInfo struct {
+	artist string
+	title  string
+}
+
+func (t trackInfo) String() string {
+	if t.artist == "" {
+		return t.title
+	}
+	return t.artist + " - " + t.title
+}
+
+// trackSplitter writes one file per track into a session directory and
+// keeps the session's cue sheet up to date after every track change.
+type trackSplitter struct {
+	dir     string
+	ext     string
+	station string
+	started time.Time
+	tagID3  bool
+
+	cue     cueSheet
+	cuePath string
+
+	mu      sync.Mutex
+	cur     *os.File
+	current trackInfo
+	used    map[string]bool
+}
+
+func newTrackSplitter(dir, station, ext string, started time.Time) (*trackSplitter, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("creating session directory: %w", err)
+	}
+	session := filepath.Base(dir)
+	return &trackSplitter{
+		dir:     dir,
+		ext:     ext,
+		station: station,
+		started: started,
+		tagID3:  ext == "mp3" || ext == "aac",
+		cue: cueSheet{
+			performer: station,
+			title:     session,
+			fileType:  cueFileType(ext),
+		},
+		cuePath: filepath.Join(dir, session+".cue"),
+		used:    make(map[string]bool),
+	}, nil
+}
+
+// startTrack closes the current file and opens a new one for info.
+func (t *trackSplitter) startTrack(info trackInfo) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cur != nil {
+		if err := t.cur.Close(); err != nil {
+			return err
+		}
+		t.cur = nil
+	}
+
+	n := len(t.cue.tracks) + 1
+	if info.title == "" {
+		info.title = fmt.Sprintf("%s - Track %02d", t.station, n)
+	}
+
+	name := SanitizeName(info.String())
+	if name == "" {
+		name = fmt.Sprintf("Track %02d", n)
+	}
+	file := name + "." + t.ext
+	for i := 2; t.used[file]; i++ {
+		file = fmt.Sprintf("%s (%d).%s", name, i, t.ext)
+	}
+	t.used[file] = true
+
+	f, err := os.OpenFile(filepath.Join(t.dir, file), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("creating track file: %w", err)
+	}
+	if t.tagID3 {
+		tag := id3v2Tag(map[string]string{
+			"TPE1": info.artist,
+			"TIT2": info.title,
+			"TALB": t.station,
+			"TRCK": strconv.Itoa(n),
+			"TDRC": t.started.Format("2006-01-02"),
+			"TENC": "Terminal Radio",
+		})
+		if _, err := f.Write(tag); err != nil {
+			f.Close()
+			return fmt.Errorf("writing tags: %w", err)
+		}
+	}
+
+	t.cur = f
+	t.current = info
+	t.cue.tracks = append(t.cue.tracks, cueTrack{file: file, artist: info.artist, title: info.title})
+	if err := os.WriteFile(t.cuePath, []byte(t.cue.String()), 0644); err != nil {
+		logger.Log.Warn().Err(err).Msgf("Failed to update cue sheet %s", t.cuePath)
+	}
+
+	logger.Log.Info().Str("file", file).Msg("recording new track")
+	return nil
+}
+
+func (t *trackSplitter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	f := t.cur
+	t.mu.Unlock()
+
+	if f == nil {
+		if err := t.startTrack(trackInfo{}); err != nil {
+			return 0, err
+		}
+		t.mu.Lock()
+		f = t.cur
+		t.mu.Unlock()
+	}
+	return f.Write(p)
+}
+
+func (t *trackSplitter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cur == nil {
+		return nil
+	}
+	err := t.cur.Close()
+	t.cur = nil
+	return err
+}
+
+func (t *trackSplitter) track() (string, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current.String(), len(t.cue.tracks)
+}
+
+// icySplitter feeds a trackSplitter from an ICY stream. Audio received
+// before the first metadata block is held back so that it lands in the
+// file named after the first title.
+type icySplitter struct {
+	*trackSplitter
+	pending    []byte
+	seenTitle  bool
+	lastTitle  string
+	titleError error
+}
+
+const maxPending = 1 << 20
+
+func (s *icySplitter) onMeta(meta string) {
+	title, ok := icy.ParseStreamTitle(meta)
+	if !ok || (s.seenTitle && title == s.lastTitle) {
+		return
+	}
+	s.seenTitle = true
+	s.lastTitle = title
+
+	artist, name := splitArtistTitle(title)
+	if err := s.startTrack(trackInfo{artist: artist, title: name}); err != nil {
+		s.titleError = err
+		return
+	}
+	if len(s.pending) > 0 {
+		if _, err := s.trackSplitter.Write(s.pending); err != nil {
+			s.titleError = err
+		}
+		s.pending = nil
+	}
+}
+
+func (s *icySplitter) Write(p []byte) (int, error) {
+	if s.titleError != nil {
+		return 0, s.titleError
+	}
+	if !s.seenTitle && len(s.pending)+len(p) <= maxPending {
+		s.pending = append(s.pending, p...)
+		return len(p), nil
+	}
+	if len(s.pending) > 0 {
+		if _, err := s.trackSplitter.Write(s.pending); err != nil {
+			return 0, err
+		}
+		s.pending = nil
+	}
+	return s.trackSplitter.Write(p)
+}
+
+func (s *icySplitter) Close() error {
+	if len(s.pending) > 0 {
+		if _, err := s.trackSplitter.Write(s.pending); err != nil {
+			s.trackSplitter.Close()
+			return err
+		}
+		s.pending = nil
+	}
+	return s.trackSplitter.Close()
+}
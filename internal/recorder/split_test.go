@@ -0,0 +1,221 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+)
+
+// icyServer serves a synthetic ICY stream: each song is a run of audio
+// bytes, and every metaint bytes a metadata block announces the title of the
+// audio that follows it, as real servers do.
+func icyServer(t *testing.T, metaint int, songs []string, bytesPerSong int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Icy-MetaData") != "1" {
+			t.Errorf("expected Icy-MetaData request header")
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-metaint", fmt.Sprint(metaint))
+
+		var out bytes.Buffer
+		chunk := 0
+		for i, song := range songs {
+			for sent := 0; sent < bytesPerSong; sent += metaint {
+				if chunk > 0 {
+					meta := fmt.Sprintf("StreamTitle='%s';", song)
+					blocks := (len(meta) + 15) / 16
+					out.WriteByte(byte(blocks))
+					out.WriteString(meta)
+					out.Write(make([]byte, blocks*16-len(meta)))
+				}
+				out.Write(bytes.Repeat([]byte{byte('a' + i)}, metaint))
+				chunk++
+			}
+		}
+		w.Write(out.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func waitForEnd(t *testing.T, r *Recorder) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := r.Status()
+		if st.Err != nil {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for stream to end")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSplitICYStream(t *testing.T) {
+	server := icyServer(t, 64, []string{"Artist One - First Song", "Artist Two - Second: Song"}, 256)
+
+	r := New(t.TempDir(), server.Client())
+	r.SplitTracks = true
+	dir, err := r.Start(context.Background(), client.Station{Name: "Test FM", URL: server.URL, Codec: "MP3"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	st := waitForEnd(t, r)
+	r.Stop()
+
+	if st.Tracks != 2 || st.Track != "Artist Two - Second: Song" {
+		t.Errorf("unexpected track status: %d %q", st.Tracks, st.Track)
+	}
+
+	first, err := os.ReadFile(filepath.Join(dir, "Artist One - First Song.mp3"))
+	if err != nil {
+		t.Fatalf("expected first track file: %v", err)
+	}
+	second, err := os.ReadFile(filepath.Join(dir, "Artist Two - Second_ Song.mp3"))
+	if err != nil {
+		t.Fatalf("expected second track file: %v", err)
+	}
+
+	for _, tc := range []struct {
+		data   []byte
+		fill   byte
+		artist string
+		title  string
+	}{
+		{first, 'a', "Artist One", "First Song"},
+		{second, 'b', "Artist Two", "Second: Song"},
+	} {
+		if !bytes.HasPrefix(tc.data, []byte("ID3\x04")) {
+			t.Fatal("expected ID3v2.4 tag at start of track")
+		}
+		tagSize := int(tc.data[6])<<21 | int(tc.data[7])<<14 | int(tc.data[8])<<7 | int(tc.data[9])
+		tag, audio := tc.data[10:10+tagSize], tc.data[10+tagSize:]
+		if !bytes.Contains(tag, []byte(tc.artist)) || !bytes.Contains(tag, []byte(tc.title)) || !bytes.Contains(tag, []byte("Test FM")) {
+			t.Errorf("tag missing artist/title/album: %q", tag)
+		}
+		if len(audio) != 256 || bytes.Count(audio, []byte{tc.fill}) != 256 {
+			t.Errorf("expected 256 bytes of %q audio with metadata stripped, got %q", tc.fill, audio)
+		}
+	}
+
+	cue, err := os.ReadFile(filepath.Join(dir, filepath.Base(dir)+".cue"))
+	if err != nil {
+		t.Fatalf("expected cue sheet: %v", err)
+	}
+	for _, want := range []string{
+		`PERFORMER "Test FM"`,
+		`FILE "Artist One - First Song.mp3" MP3`,
+		`TRACK 01 AUDIO`,
+		`FILE "Artist Two - Second_ Song.mp3" MP3`,
+		`TRACK 02 AUDIO`,
+		`TITLE "Second: Song"`,
+		`PERFORMER "Artist Two"`,
+	} {
+		if !strings.Contains(string(cue), want) {
+			t.Errorf("cue sheet missing %q:\n%s", want, cue)
+		}
+	}
+}
+
+// oggPage builds one Ogg page holding packets, each fitting in the page.
+func oggPage(flags byte, serial uint32, packets ...[]byte) []byte {
+	var lacing, body []byte
+	for _, p := range packets {
+		n := len(p)
+		for n >= 255 {
+			lacing = append(lacing, 255)
+			n -= 255
+		}
+		lacing = append(lacing, byte(n))
+		body = append(body, p...)
+	}
+	header := make([]byte, oggHeaderLen)
+	copy(header, "OggS")
+	header[5] = flags
+	binary.LittleEndian.PutUint32(header[14:], serial)
+	header[26] = byte(len(lacing))
+	return append(append(header, lacing...), body...)
+}
+
+func vorbisComments(comments ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x03vorbis")
+	binary.Write(&b, binary.LittleEndian, uint32(4))
+	b.WriteString("test")
+	binary.Write(&b, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		binary.Write(&b, binary.LittleEndian, uint32(len(c)))
+		b.WriteString(c)
+	}
+	b.WriteByte(1)
+	return b.Bytes()
+}
+
+func TestSplitOggChains(t *testing.T) {
+	song := func(serial uint32, artist, title string, audio byte) []byte {
+		var b bytes.Buffer
+		b.Write(oggPage(oggFlagBOS, serial, []byte("\x01vorbis-ident")))
+		b.Write(oggPage(0, serial, vorbisComments("ARTIST="+artist, "TITLE="+title), []byte("\x05vorbis-setup")))
+		b.Write(oggPage(0, serial, bytes.Repeat([]byte{audio}, 300)))
+		b.Write(oggPage(0x04, serial, []byte{audio}))
+		return b.Bytes()
+	}
+	stream := append(song(1, "Band", "Opening", 'x'), song(2, "Other Band", "Closing", 'y')...)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/ogg")
+		// Dribble the bytes so pages arrive split across reads.
+		for i := 0; i < len(stream); i += 37 {
+			end := min(i+37, len(stream))
+			w.Write(stream[i:end])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	r := New(t.TempDir(), server.Client())
+	r.SplitTracks = true
+	dir, err := r.Start(context.Background(), client.Station{Name: "Ogg Radio", URL: server.URL, Codec: "OGG"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForEnd(t, r)
+	r.Stop()
+
+	first, err := os.ReadFile(filepath.Join(dir, "Band - Opening.ogg"))
+	if err != nil {
+		t.Fatalf("expected first track: %v", err)
+	}
+	second, err := os.ReadFile(filepath.Join(dir, "Other Band - Closing.ogg"))
+	if err != nil {
+		t.Fatalf("expected second track: %v", err)
+	}
+	if !bytes.Equal(first, song(1, "Band", "Opening", 'x')) {
+		t.Error("first track is not the first chain byte for byte")
+	}
+	if !bytes.Equal(second, song(2, "Other Band", "Closing", 'y')) {
+		t.Error("second track is not the second chain byte for byte")
+	}
+}
+
+func TestSplitArtistTitle(t *testing.T) {
+	if a, ti := splitArtistTitle("A-ha - Take On Me"); a != "A-ha" || ti != "Take On Me" {
+		t.Errorf("unexpected split: %q / %q", a, ti)
+	}
+	if a, ti := splitArtistTitle("Station jingle"); a != "" || ti != "Station jingle" {
+		t.Errorf("unexpected split: %q / %q", a, ti)
+	}
+}
@@ -0,0 +1,11 @@
+package recorder
+
+import "strings"
+
+// splitArtistTitle splits the conventional "Artist - Title" form.
+func splitArtistTitle(s string) (artist, title string) {
+	if i := strings.Index(s, " - "); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+3:])
+	}
+	return "", strings.TrimSpace(s)
+}
@@ -0,0 +1,197 @@
+// Package scan picks the next station for auto-switching.
+package scan
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"radio/internal/client"
+)
+
+type Mode int
+
+const (
+	Sequential Mode = iota
+	Shuffle
+	Weighted
+)
+
+var modeNames = []string{"sequential", "shuffle", "weighted"}
+
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("mode(%d)", int(m))
+}
+
+// Next cycles through the modes.
+func (m Mode) Next() Mode {
+	return (m + 1) % Mode(len(modeNames))
+}
+
+func ParseMode(s string) (Mode, error) {
+	for i, name := range modeNames {
+		if strings.EqualFold(s, name) {
+			return Mode(i), nil
+		}
+	}
+	return Sequential, fmt.Errorf("unknown scan mode %q", s)
+}
+
+// Filter restricts scanning to stations with a tag and/or from a country.
+// Empty fields match everything.
+type Filter struct {
+	Tag     string
+	Country string
+}
+
+func (f Filter) Match(st client.Station) bool {
+	if f.Country != "" && !strings.EqualFold(st.Country, f.Country) && !strings.EqualFold(st.CountryCode, f.Country) {
+		return false
+	}
+	if f.Tag == "" {
+		return true
+	}
+	for _, tag := range strings.Split(st.Tags, ",") {
+		if strings.EqualFold(strings.TrimSpace(tag), f.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) String() string {
+	var parts []string
+	if f.Tag != "" {
+		parts = append(parts, "tag:"+f.Tag)
+	}
+	if f.Country != "" {
+		parts = append(parts, "country:"+f.Country)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Scanner chooses stations one after another. Shuffle plays every station
+// once before repeating; Weighted favours stations with more votes.
+type Scanner struct {
+	Mode   Mode
+	Filter Filter
+	// Dwell is how long each station plays before switching.
+	Dwell time.Duration
+
+	rng *rand.Rand
+	// bag holds the URLs not yet played in the current shuffle round.
+	bag []string
+}
+
+func New(mode Mode, dwell time.Duration, rng *rand.Rand) *Scanner {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return &Scanner{Mode: mode, Dwell: dwell, rng: rng}
+}
+
+// SetMode switches mode and starts a fresh shuffle round.
+func (s *Scanner) SetMode(m Mode) {
+	s.Mode = m
+	s.bag = nil
+}
+
+// SetFilter changes the filter and starts a fresh shuffle round.
+func (s *Scanner) SetFilter(f Filter) {
+	s.Filter = f
+	s.bag = nil
+}
+
+// Next returns the station to play after current (a URL, may be empty).
+// It avoids replaying current unless it is the only candidate.
+func (s *Scanner) Next(stations []client.Station, current string) (client.Station, bool) {
+	var candidates []client.Station
+	for _, st := range stations {
+		if s.Filter.Match(st) {
+			candidates = append(candidates, st)
+		}
+	}
+	if len(candidates) == 0 {
+		return client.Station{}, false
+	}
+
+	switch s.Mode {
+	case Shuffle:
+		return s.nextShuffle(candidates, current), true
+	case Weighted:
+		return s.nextWeighted(candidates, current), true
+	default:
+		return nextSequential(candidates, current), true
+	}
+}
+
+func nextSequential(candidates []client.Station, current string) client.Station {
+	for i, st := range candidates {
+		if st.URL == current {
+			return candidates[(i+1)%len(candidates)]
+		}
+	}
+	return candidates[0]
+}
+
+func (s *Scanner) nextShuffle(candidates []client.Station, current string) client.Station {
+	byURL := make(map[string]client.Station, len(candidates))
+	for _, st := range candidates {
+		byURL[st.URL] = st
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		// Drop stations that have left the list since the round began.
+		bag := s.bag[:0]
+		for _, u := range s.bag {
+			if _, ok := byURL[u]; ok && (u != current || len(candidates) == 1) {
+				bag = append(bag, u)
+			}
+		}
+		s.bag = bag
+
+		if len(s.bag) > 0 {
+			i := s.rng.Intn(len(s.bag))
+			u := s.bag[i]
+			s.bag = append(s.bag[:i], s.bag[i+1:]...)
+			return byURL[u]
+		}
+
+		for _, st := range candidates {
+			s.bag = append(s.bag, st.URL)
+		}
+	}
+	return candidates[0]
+}
+
+func (s *Scanner) nextWeighted(candidates []client.Station, current string) client.Station {
+	total := 0
+	for _, st := range candidates {
+		if st.URL != current || len(candidates) == 1 {
+			total += weight(st)
+		}
+	}
+
+	n := s.rng.Intn(total)
+	for _, st := range candidates {
+		if st.URL == current && len(candidates) > 1 {
+			continue
+		}
+		n -= weight(st)
+		if n < 0 {
+			return st
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func weight(st client.Station) int {
+	if st.Votes < 0 {
+		return 1
+	}
+	return st.Votes + 1
+}
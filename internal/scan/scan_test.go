@@ -0,0 +1,130 @@
+package scan
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+)
+
+func stations(urls ...string) []client.Station {
+	var out []client.Station
+	for _, u := range urls {
+		out = append(out, client.Station{URL: u, Name: u})
+	}
+	return out
+}
+
+func TestSequentialWraps(t *testing.T) {
+	s := New(Sequential, time.Minute, rand.New(rand.NewSource(1)))
+	list := stations("a", "b", "c")
+
+	var got []string
+	current := ""
+	for i := 0; i < 4; i++ {
+		st, ok := s.Next(list, current)
+		if !ok {
+			t.Fatal("expected a station")
+		}
+		current = st.URL
+		got = append(got, current)
+	}
+	if want := []string{"a", "b", "c", "a"}; !equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestShuffleWithoutRepeat(t *testing.T) {
+	s := New(Shuffle, time.Minute, rand.New(rand.NewSource(42)))
+	list := stations("a", "b", "c", "d", "e")
+
+	current := ""
+	seen := make(map[string]int)
+	for i := 0; i < len(list); i++ {
+		st, _ := s.Next(list, current)
+		if st.URL == current {
+			t.Fatalf("replayed %s back to back", current)
+		}
+		current = st.URL
+		seen[current]++
+	}
+	for _, st := range list {
+		if seen[st.URL] != 1 {
+			t.Errorf("expected %s once per round, got %d", st.URL, seen[st.URL])
+		}
+	}
+
+	// The next round never starts with the station that just played.
+	for i := 0; i < 20; i++ {
+		st, _ := s.Next(list, current)
+		if st.URL == current {
+			t.Fatalf("replayed %s back to back", current)
+		}
+		current = st.URL
+	}
+}
+
+func TestWeightedFavoursVotes(t *testing.T) {
+	s := New(Weighted, time.Minute, rand.New(rand.NewSource(7)))
+	list := []client.Station{{URL: "popular", Votes: 99}, {URL: "obscure", Votes: 0}, {URL: "current", Votes: 1000}}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		st, _ := s.Next(list, "current")
+		counts[st.URL]++
+	}
+	if counts["current"] != 0 {
+		t.Errorf("expected the current station to be skipped, got %d", counts["current"])
+	}
+	if counts["popular"] < 900 {
+		t.Errorf("expected the popular station to dominate, got %v", counts)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	list := []client.Station{
+		{URL: "a", Tags: "rock,Jazz", Country: "Germany", CountryCode: "DE"},
+		{URL: "b", Tags: "jazzy", Country: "France", CountryCode: "FR"},
+		{URL: "c", Tags: "jazz", Country: "France", CountryCode: "FR"},
+	}
+
+	s := New(Sequential, time.Minute, nil)
+	s.SetFilter(Filter{Tag: "jazz", Country: "fr"})
+	st, ok := s.Next(list, "")
+	if !ok || st.URL != "c" {
+		t.Errorf("expected c, got %v (%v)", st.URL, ok)
+	}
+	if st, _ := s.Next(list, "c"); st.URL != "c" {
+		t.Errorf("expected the only match to repeat, got %s", st.URL)
+	}
+
+	s.SetFilter(Filter{Country: "Spain"})
+	if _, ok := s.Next(list, ""); ok {
+		t.Error("expected no station for an unmatched filter")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, m := range []Mode{Sequential, Shuffle, Weighted} {
+		parsed, err := ParseMode(m.String())
+		if err != nil || parsed != m {
+			t.Errorf("ParseMode(%q) = %v, %v", m.String(), parsed, err)
+		}
+	}
+	if _, err := ParseMode("random"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"radio/internal/client"
+	"radio/internal/player"
+	"radio/internal/recorder"
+	"radio/pkg/logger"
+)
+
+// Executor carries out scheduler events directly on a player and a
+// recorder. It is what headless mode uses in place of the TUI.
+type Executor struct {
+	player   player.Backend
+	recorder *recorder.Recorder
+}
+
+func NewExecutor(pl player.Backend, rec *recorder.Recorder) *Executor {
+	return &Executor{player: pl, recorder: rec}
+}
+
+func (s Schedule) Station() client.Station {
+	return client.Station{URL: s.StationURL, Name: s.StationName, Codec: s.Codec}
+}
+
+func (e *Executor) Handle(ctx context.Context, ev Event) error {
+	sch := ev.Schedule
+	var err error
+
+	switch {
+	case ev.Kind == EventStart && sch.Action == ActionPlay:
+		err = e.player.Play(ctx, sch.StationURL)
+	case ev.Kind == EventStop && sch.Action == ActionPlay:
+		err = e.player.Stop()
+	case ev.Kind == EventStart && sch.Action == ActionRecord:
+		_, err = e.recorder.Start(ctx, sch.Station())
+	case ev.Kind == EventStop && sch.Action == ActionRecord:
+		err = e.recorder.Stop()
+	default:
+		err = fmt.Errorf("unknown action %q", sch.Action)
+	}
+
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Scheduled %s of %s failed", sch.Action, sch.StationName)
+	}
+	return err
+}
+
+// RunHeadless executes schedules until ctx is done.
+func RunHeadless(ctx context.Context, s *Scheduler, ex *Executor) {
+	for ev := range s.Run(ctx) {
+		_ = ex.Handle(ctx, ev)
+	}
+}
@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionPlay   Action = "play"
+	ActionRecord Action = "record"
+)
+
+// startOnlyWindow is how long a schedule without an end time stays
+// eligible to fire, so a brief stall does not make it miss its start.
+const startOnlyWindow = time.Minute
+
+// Schedule plays or records a station either once (At) or on a weekly
+// pattern (Weekdays at StartMinute). A zero Duration only starts the action.
+type Schedule struct {
+	ID          string         `json:"id"`
+	StationURL  string         `json:"station_url"`
+	StationName string         `json:"station_name"`
+	Codec       string         `json:"codec,omitempty"`
+	Action      Action         `json:"action"`
+	At          time.Time      `json:"at,omitempty"`
+	Weekdays    []time.Weekday `json:"weekdays,omitempty"`
+	StartMinute int            `json:"start_minute"`
+	Duration    time.Duration  `json:"duration"`
+	Enabled     bool           `json:"enabled"`
+	// Spec is the text the schedule was created from, kept for display.
+	Spec string `json:"spec"`
+}
+
+func (s Schedule) OneShot() bool {
+	return !s.At.IsZero()
+}
+
+func (s Schedule) matchesDay(day time.Weekday) bool {
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range s.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Schedule) windowLength() time.Duration {
+	if s.Duration > 0 {
+		return s.Duration
+	}
+	return startOnlyWindow
+}
+
+// Window returns the occurrence that contains t, if any.
+func (s Schedule) Window(t time.Time) (start, end time.Time, ok bool) {
+	if s.OneShot() {
+		start, end = s.At, s.At.Add(s.windowLength())
+		return start, end, !t.Before(start) && t.Before(end)
+	}
+
+	// Look back far enough to catch an occurrence that started yesterday
+	// and runs past midnight.
+	for d := -int(s.windowLength()/(24*time.Hour)) - 1; d <= 0; d++ {
+		day := t.AddDate(0, 0, d)
+		if !s.matchesDay(day.Weekday()) {
+			continue
+		}
+		start = s.startOn(day)
+		end = start.Add(s.windowLength())
+		if !t.Before(start) && t.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// Next returns the first occurrence starting after t.
+func (s Schedule) Next(t time.Time) (time.Time, bool) {
+	if s.OneShot() {
+		return s.At, s.At.After(t)
+	}
+	for d := 0; d <= 7; d++ {
+		day := t.AddDate(0, 0, d)
+		if !s.matchesDay(day.Weekday()) {
+			continue
+		}
+		if start := s.startOn(day); start.After(t) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s Schedule) startOn(day time.Time) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, s.StartMinute/60, s.StartMinute%60, 0, 0, day.Location())
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseSpec parses when a schedule runs, e.g.
+//
+//	mon 20:00-22:00
+//	mondays 20:00–22:00
+//	mon,wed,fri 07:30
+//	daily 06:00-07:00, weekdays ..., weekends ...
+//	2025-12-31 23:00-00:30, today 18:00, tomorrow 07:00
+//
+// The returned schedule has only its timing fields set.
+func ParseSpec(spec string, now time.Time) (Schedule, error) {
+	normalized := strings.NewReplacer("–", "-", "—", "-").Replace(strings.ToLower(strings.TrimSpace(spec)))
+	fields := strings.Fields(normalized)
+	if len(fields) != 2 {
+		return Schedule{}, fmt.Errorf("expected \"<days> <HH:MM>[-HH:MM]\", got %q", spec)
+	}
+
+	startMin, duration, err := parseTimeRange(fields[1])
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	s := Schedule{StartMinute: startMin, Duration: duration, Spec: strings.TrimSpace(spec)}
+
+	days := fields[0]
+	switch days {
+	case "daily", "everyday":
+		return s, nil
+	case "weekdays":
+		s.Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		return s, nil
+	case "weekends":
+		s.Weekdays = []time.Weekday{time.Saturday, time.Sunday}
+		return s, nil
+	case "today", "tomorrow":
+		day := now
+		if days == "tomorrow" {
+			day = now.AddDate(0, 0, 1)
+		}
+		s.At = s.startOn(day)
+		s.StartMinute = 0
+		return s, nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", days, now.Location()); err == nil {
+		s.At = s.startOn(date)
+		s.StartMinute = 0
+		return s, nil
+	}
+
+	seen := make(map[time.Weekday]bool)
+	for _, name := range strings.Split(days, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) < 3 {
+			return Schedule{}, fmt.Errorf("unknown day %q", name)
+		}
+		day, ok := weekdayNames[name[:3]]
+		if !ok {
+			return Schedule{}, fmt.Errorf("unknown day %q", name)
+		}
+		if !seen[day] {
+			seen[day] = true
+			s.Weekdays = append(s.Weekdays, day)
+		}
+	}
+	sort.Slice(s.Weekdays, func(i, j int) bool { return s.Weekdays[i] < s.Weekdays[j] })
+	return s, nil
+}
+
+func parseTimeRange(s string) (startMin int, duration time.Duration, err error) {
+	from, to, hasEnd := strings.Cut(s, "-")
+	startMin, err = parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !hasEnd {
+		return startMin, 0, nil
+	}
+	endMin, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	minutes := endMin - startMin
+	if minutes <= 0 {
+		minutes += 24 * 60
+	}
+	return startMin, time.Duration(minutes) * time.Minute, nil
+}
+
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+var errNoStation = errors.New("schedule needs a station")
+
+func (s Schedule) validate() error {
+	if s.StationURL == "" {
+		return errNoStation
+	}
+	switch s.Action {
+	case ActionPlay:
+	case ActionRecord:
+		if s.Duration <= 0 {
+			return errors.New("recording schedules need an end time")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// 2025-03-10 is a Monday.
+var monday = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func TestParseSpecWeekly(t *testing.T) {
+	s, err := ParseSpec("Mondays 20:00–22:00", monday)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.OneShot() {
+		t.Fatal("expected a recurring schedule")
+	}
+	if len(s.Weekdays) != 1 || s.Weekdays[0] != time.Monday {
+		t.Errorf("unexpected weekdays: %v", s.Weekdays)
+	}
+	if s.StartMinute != 20*60 || s.Duration != 2*time.Hour {
+		t.Errorf("unexpected window: start %d, duration %s", s.StartMinute, s.Duration)
+	}
+}
+
+func TestParseSpecVariants(t *testing.T) {
+	tests := []struct {
+		spec     string
+		weekdays int
+		at       time.Time
+		duration time.Duration
+	}{
+		{"daily 06:00-07:00", 0, time.Time{}, time.Hour},
+		{"weekdays 07:30", 5, time.Time{}, 0},
+		{"weekends 10:00-10:30", 2, time.Time{}, 30 * time.Minute},
+		{"mon,wed,fri 23:00-01:00", 3, time.Time{}, 2 * time.Hour},
+		{"tomorrow 07:00", 0, time.Date(2025, 3, 11, 7, 0, 0, 0, time.UTC), 0},
+		{"2025-12-31 23:00-00:30", 0, time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC), 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		s, err := ParseSpec(tt.spec, monday)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if len(s.Weekdays) != tt.weekdays {
+			t.Errorf("%q: expected %d weekdays, got %v", tt.spec, tt.weekdays, s.Weekdays)
+		}
+		if !s.At.Equal(tt.at) {
+			t.Errorf("%q: expected at %s, got %s", tt.spec, tt.at, s.At)
+		}
+		if s.Duration != tt.duration {
+			t.Errorf("%q: expected duration %s, got %s", tt.spec, tt.duration, s.Duration)
+		}
+	}
+}
+
+func TestParseSpecInvalid(t *testing.T) {
+	for _, spec := range []string{"", "monday", "funday 10:00", "mon 25:00", "mon 10:00-xx", "mon 10:00 extra"} {
+		if _, err := ParseSpec(spec, monday); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestWindowAcrossMidnight(t *testing.T) {
+	s, err := ParseSpec("mon 23:00-01:00", monday)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tuesdayEarly := time.Date(2025, 3, 11, 0, 30, 0, 0, time.UTC)
+	start, end, ok := s.Window(tuesdayEarly)
+	if !ok {
+		t.Fatal("expected Monday's window to include 00:30 on Tuesday")
+	}
+	if !start.Equal(time.Date(2025, 3, 10, 23, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 3, 11, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected window %s - %s", start, end)
+	}
+
+	if _, _, ok := s.Window(time.Date(2025, 3, 11, 23, 30, 0, 0, time.UTC)); ok {
+		t.Error("did not expect a window on Tuesday night")
+	}
+}
+
+func TestNext(t *testing.T) {
+	s, err := ParseSpec("mon 10:00-11:00", monday)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next, ok := s.Next(monday)
+	if !ok || !next.Equal(time.Date(2025, 3, 17, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected next Monday 10:00, got %s (%v)", next, ok)
+	}
+
+	once, err := ParseSpec("today 09:00", monday)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := once.Next(monday); ok {
+		t.Error("a past one-shot has no next occurrence")
+	}
+}
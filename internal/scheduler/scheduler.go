@@ -0,0 +1,272 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"radio/internal/storage"
+	"radio/pkg/logger"
+)
+
+type EventKind int
+
+const (
+	EventStart EventKind = iota
+	EventStop
+)
+
+func (k EventKind) String() string {
+	if k == EventStop {
+		return "stop"
+	}
+	return "start"
+}
+
+// Event asks the app to start or stop a scheduled action.
+type Event struct {
+	Kind     EventKind
+	Schedule Schedule
+	// Until is when a started action is due to stop; zero for start-only.
+	Until time.Time
+}
+
+type running struct {
+	schedule   Schedule
+	start, end time.Time
+}
+
+// Scheduler keeps the schedules, persists them next to the favorites and
+// emits start/stop events as their windows open and close. Executing the
+// events is left to the caller, so the TUI and headless mode can each keep
+// their own state straight.
+type Scheduler struct {
+	path string
+	now  func() time.Time
+
+	mu        sync.Mutex
+	schedules []Schedule
+	running   map[string]running
+}
+
+type fileFormat struct {
+	Schedules []Schedule `json:"schedules"`
+}
+
+func New(path string) (*Scheduler, error) {
+	s := &Scheduler{
+		path:    path,
+		now:     time.Now,
+		running: make(map[string]running),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		logger.Log.Info().Msgf("Schedules file %s does not exist, starting empty", path)
+	case err != nil:
+		return nil, fmt.Errorf("reading schedules: %w", err)
+	case len(data) > 0:
+		var f fileFormat
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("decoding schedules %s: %w", path, err)
+		}
+		s.schedules = f.Schedules
+	}
+
+	return s, nil
+}
+
+func (s *Scheduler) save() error {
+	data, err := json.MarshalIndent(fileFormat{Schedules: s.schedules}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFileAtomic(s.path, data, 0644); err != nil {
+		logger.Log.Error().Err(err).Msgf("Error writing schedules file %s", s.path)
+		return err
+	}
+	return nil
+}
+
+func newID() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Add validates and stores a schedule, assigning it an ID.
+func (s *Scheduler) Add(sch Schedule) (Schedule, error) {
+	if err := sch.validate(); err != nil {
+		return Schedule{}, err
+	}
+	sch.ID = newID()
+	sch.Enabled = true
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules = append(s.schedules, sch)
+	return sch, s.save()
+}
+
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sch := range s.schedules {
+		if sch.ID == id {
+			s.schedules = append(s.schedules[:i], s.schedules[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("schedule %s not found", id)
+}
+
+func (s *Scheduler) SetEnabled(id string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.schedules {
+		if s.schedules[i].ID == id {
+			s.schedules[i].Enabled = enabled
+			return s.save()
+		}
+	}
+	return fmt.Errorf("schedule %s not found", id)
+}
+
+// List returns the schedules ordered by their next start.
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	out := make([]Schedule, len(s.schedules))
+	copy(out, s.schedules)
+	s.mu.Unlock()
+
+	now := s.now()
+	sort.SliceStable(out, func(i, j int) bool {
+		ni, oki := out[i].Next(now)
+		nj, okj := out[j].Next(now)
+		if oki != okj {
+			return oki
+		}
+		return ni.Before(nj)
+	})
+	return out
+}
+
+// IsRunning reports whether the schedule's action is currently active.
+func (s *Scheduler) IsRunning(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.running[id]
+	return ok
+}
+
+// check compares the schedules with now and returns the events due.
+func (s *Scheduler) check(now time.Time) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []Event
+	changed := false
+
+	for i := range s.schedules {
+		sch := s.schedules[i]
+		run, isRunning := s.running[sch.ID]
+
+		if isRunning && !now.Before(run.end) {
+			delete(s.running, sch.ID)
+			isRunning = false
+			if sch.Duration > 0 {
+				events = append(events, Event{Kind: EventStop, Schedule: sch})
+			}
+			if sch.OneShot() {
+				s.schedules[i].Enabled = false
+				changed = true
+			}
+		}
+
+		if !sch.Enabled {
+			if isRunning && sch.Duration > 0 {
+				delete(s.running, sch.ID)
+				events = append(events, Event{Kind: EventStop, Schedule: sch})
+			}
+			continue
+		}
+
+		start, end, active := sch.Window(now)
+		if !active || (isRunning && run.start.Equal(start)) {
+			continue
+		}
+
+		s.running[sch.ID] = running{schedule: sch, start: start, end: end}
+		ev := Event{Kind: EventStart, Schedule: sch}
+		if sch.Duration > 0 {
+			ev.Until = end
+		}
+		events = append(events, ev)
+	}
+
+	// Stop actions whose schedule was removed while running.
+	for id, run := range s.running {
+		if s.indexLocked(id) < 0 {
+			delete(s.running, id)
+			if run.schedule.Duration > 0 {
+				events = append(events, Event{Kind: EventStop, Schedule: run.schedule})
+			}
+		}
+	}
+
+	if changed {
+		_ = s.save()
+	}
+	return events
+}
+
+func (s *Scheduler) indexLocked(id string) int {
+	for i, sch := range s.schedules {
+		if sch.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Run checks the schedules every second and sends due events until ctx is
+// done.
+func (s *Scheduler) Run(ctx context.Context) <-chan Event {
+	events := make(chan Event, 16)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			for _, ev := range s.check(s.now()) {
+				logger.Log.Info().Msgf("Schedule %s: %s %s %s", ev.Schedule.ID, ev.Kind, ev.Schedule.Action, ev.Schedule.StationName)
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
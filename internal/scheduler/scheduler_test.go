@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(t *testing.T) (*Scheduler, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("failed to create scheduler: %v", err)
+	}
+	return s, path
+}
+
+func mustParse(t *testing.T, spec string) Schedule {
+	t.Helper()
+	sch, err := ParseSpec(spec, monday)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", spec, err)
+	}
+	sch.StationURL = "http://example.com/stream"
+	sch.StationName = "Example"
+	return sch
+}
+
+func TestAddValidates(t *testing.T) {
+	s, _ := newTestScheduler(t)
+
+	sch := mustParse(t, "mon 20:00")
+	sch.Action = ActionRecord
+	if _, err := s.Add(sch); err == nil {
+		t.Error("expected an error for a recording without an end time")
+	}
+
+	sch.StationURL = ""
+	sch.Action = ActionPlay
+	if _, err := s.Add(sch); err == nil {
+		t.Error("expected an error for a schedule without a station")
+	}
+}
+
+func TestCheckStartsAndStops(t *testing.T) {
+	s, _ := newTestScheduler(t)
+
+	sch := mustParse(t, "mon 20:00-22:00")
+	sch.Action = ActionRecord
+	added, err := s.Add(sch)
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	if events := s.check(time.Date(2025, 3, 10, 19, 59, 0, 0, time.UTC)); len(events) != 0 {
+		t.Fatalf("expected no events before the window, got %v", events)
+	}
+
+	events := s.check(time.Date(2025, 3, 10, 20, 0, 1, 0, time.UTC))
+	if len(events) != 1 || events[0].Kind != EventStart || events[0].Schedule.ID != added.ID {
+		t.Fatalf("expected one start event, got %v", events)
+	}
+	if !events[0].Until.Equal(time.Date(2025, 3, 10, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected until: %s", events[0].Until)
+	}
+	if !s.IsRunning(added.ID) {
+		t.Error("expected the schedule to be running")
+	}
+
+	if events := s.check(time.Date(2025, 3, 10, 21, 0, 0, 0, time.UTC)); len(events) != 0 {
+		t.Fatalf("expected no repeated start, got %v", events)
+	}
+
+	events = s.check(time.Date(2025, 3, 10, 22, 0, 0, 0, time.UTC))
+	if len(events) != 1 || events[0].Kind != EventStop {
+		t.Fatalf("expected one stop event, got %v", events)
+	}
+	if s.IsRunning(added.ID) {
+		t.Error("expected the schedule to have stopped")
+	}
+}
+
+func TestOneShotDisablesAfterRunning(t *testing.T) {
+	s, path := newTestScheduler(t)
+
+	sch := mustParse(t, "tomorrow 07:00")
+	sch.Action = ActionPlay
+	added, err := s.Add(sch)
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	at := time.Date(2025, 3, 11, 7, 0, 0, 0, time.UTC)
+	if events := s.check(at); len(events) != 1 || events[0].Kind != EventStart {
+		t.Fatalf("expected a start event, got %v", events)
+	}
+	// Play-only schedules never emit a stop.
+	if events := s.check(at.Add(2 * time.Minute)); len(events) != 0 {
+		t.Fatalf("expected no events, got %v", events)
+	}
+
+	reloaded, err := New(path)
+	if err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 1 || list[0].ID != added.ID || list[0].Enabled {
+		t.Fatalf("expected the one-shot to be persisted as disabled, got %+v", list)
+	}
+}
+
+func TestRemoveRunningStops(t *testing.T) {
+	s, _ := newTestScheduler(t)
+
+	sch := mustParse(t, "daily 10:00-11:00")
+	sch.Action = ActionRecord
+	added, err := s.Add(sch)
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	s.check(time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC))
+	if err := s.Remove(added.ID); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	events := s.check(time.Date(2025, 3, 10, 10, 31, 0, 0, time.UTC))
+	if len(events) != 1 || events[0].Kind != EventStop {
+		t.Fatalf("expected a stop event, got %v", events)
+	}
+}
+
+func TestDisableRunningStops(t *testing.T) {
+	s, _ := newTestScheduler(t)
+
+	sch := mustParse(t, "daily 10:00-11:00")
+	sch.Action = ActionPlay
+	added, err := s.Add(sch)
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	s.check(time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC))
+	if err := s.SetEnabled(added.ID, false); err != nil {
+		t.Fatalf("failed to disable: %v", err)
+	}
+	events := s.check(time.Date(2025, 3, 10, 10, 31, 0, 0, time.UTC))
+	if len(events) != 1 || events[0].Kind != EventStop {
+		t.Fatalf("expected a stop event, got %v", events)
+	}
+}
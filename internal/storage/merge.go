@@ -0,0 +1,146 @@
+package storage
+
+import "sort"
+
+// Conflict describes a station changed differently on both sides since the
+// last common state. A nil side means the station was deleted there.
+type Conflict struct {
+	URL    string
+	Local  *FavoriteStation
+	Remote *FavoriteStation
+}
+
+// ChangeEvent reports how the favorites changed after an external edit.
+type ChangeEvent struct {
+	Added     []string
+	Removed   []string
+	Updated   []string
+	Conflicts []Conflict
+}
+
+func (e ChangeEvent) Empty() bool {
+	return len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Updated) == 0 && len(e.Conflicts) == 0
+}
+
+// Resolver picks the outcome of a conflict; present is false when the
+// station should end up deleted.
+type Resolver func(c Conflict) (station FavoriteStation, present bool)
+
+// PreferRemote resolves every conflict in favour of the remote side.
+func PreferRemote(c Conflict) (FavoriteStation, bool) {
+	if c.Remote == nil {
+		return FavoriteStation{}, false
+	}
+	return *c.Remote, true
+}
+
+// PreferLocal resolves every conflict in favour of the local side.
+func PreferLocal(c Conflict) (FavoriteStation, bool) {
+	if c.Local == nil {
+		return FavoriteStation{}, false
+	}
+	return *c.Local, true
+}
+
+func mergeFavorites(base, local, remote map[string]FavoriteStation) (map[string]FavoriteStation, []Conflict) {
+	return MergeFavorites(base, local, remote, PreferRemote)
+}
+
+// MergeFavorites performs a per-station three-way merge of local and remote
+// against their common base. Stations changed on only one side take that
+// side's version; stations changed differently on both sides are conflicts,
+// settled by resolve and returned so the caller can report them.
+func MergeFavorites(base, local, remote map[string]FavoriteStation, resolve Resolver) (map[string]FavoriteStation, []Conflict) {
+	merged := make(map[string]FavoriteStation)
+	var conflicts []Conflict
+
+	for _, url := range unionKeys(base, local, remote) {
+		b, inBase := base[url]
+		l, inLocal := local[url]
+		r, inRemote := remote[url]
+
+		localChanged := !sameFavorite(b, inBase, l, inLocal)
+		remoteChanged := !sameFavorite(b, inBase, r, inRemote)
+
+		var keep FavoriteStation
+		var present bool
+		switch {
+		case !localChanged:
+			keep, present = r, inRemote
+		case !remoteChanged, sameFavorite(l, inLocal, r, inRemote):
+			keep, present = l, inLocal
+		default:
+			c := Conflict{URL: url}
+			if inLocal {
+				c.Local = &l
+			}
+			if inRemote {
+				c.Remote = &r
+			}
+			conflicts = append(conflicts, c)
+			keep, present = resolve(c)
+		}
+
+		if present {
+			merged[url] = keep
+		}
+	}
+
+	return merged, conflicts
+}
+
+// DiffFavorites lists what changed going from old to new.
+func DiffFavorites(old, new map[string]FavoriteStation) ChangeEvent {
+	return diffFavorites(old, new)
+}
+
+func diffFavorites(old, new map[string]FavoriteStation) ChangeEvent {
+	var ev ChangeEvent
+	for _, url := range unionKeys(old, new) {
+		o, inOld := old[url]
+		n, inNew := new[url]
+		switch {
+		case !inOld && inNew:
+			ev.Added = append(ev.Added, url)
+		case inOld && !inNew:
+			ev.Removed = append(ev.Removed, url)
+		case !sameFavorite(o, inOld, n, inNew):
+			ev.Updated = append(ev.Updated, url)
+		}
+	}
+	return ev
+}
+
+func sameFavorite(a FavoriteStation, aOK bool, b FavoriteStation, bOK bool) bool {
+	if aOK != bOK {
+		return false
+	}
+	if !aOK {
+		return true
+	}
+	return a.URL == b.URL && a.Name == b.Name && a.Bitrate == b.Bitrate &&
+		a.Country == b.Country && a.Tags == b.Tags && a.AddedAt.Equal(b.AddedAt) && a.UpdatedAt.Equal(b.UpdatedAt)
+}
+
+func unionKeys(maps ...map[string]FavoriteStation) []string {
+	seen := make(map[string]struct{})
+	for _, m := range maps {
+		for k := range m {
+			seen[k] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func cloneFavorites(m map[string]FavoriteStation) map[string]FavoriteStation {
+	out := make(map[string]FavoriteStation, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func fav(url, name string) FavoriteStation {
+	return FavoriteStation{URL: url, Name: name}
+}
+
+func TestMergeFavorites(t *testing.T) {
+	a, a2, a3 := fav("a", "A"), fav("a", "A local"), fav("a", "A remote")
+	b, c := fav("b", "B"), fav("c", "C")
+
+	tests := []struct {
+		name          string
+		base          map[string]FavoriteStation
+		local         map[string]FavoriteStation
+		remote        map[string]FavoriteStation
+		want          map[string]FavoriteStation
+		wantConflicts []string
+	}{
+		{
+			name:   "remote only change",
+			base:   map[string]FavoriteStation{"a": a},
+			local:  map[string]FavoriteStation{"a": a},
+			remote: map[string]FavoriteStation{"a": a, "b": b},
+			want:   map[string]FavoriteStation{"a": a, "b": b},
+		},
+		{
+			name:   "local only change",
+			base:   map[string]FavoriteStation{"a": a},
+			local:  map[string]FavoriteStation{"a": a, "c": c},
+			remote: map[string]FavoriteStation{"a": a},
+			want:   map[string]FavoriteStation{"a": a, "c": c},
+		},
+		{
+			name:   "both sides add different stations",
+			base:   map[string]FavoriteStation{},
+			local:  map[string]FavoriteStation{"b": b},
+			remote: map[string]FavoriteStation{"c": c},
+			want:   map[string]FavoriteStation{"b": b, "c": c},
+		},
+		{
+			name:   "remote delete",
+			base:   map[string]FavoriteStation{"a": a, "b": b},
+			local:  map[string]FavoriteStation{"a": a, "b": b},
+			remote: map[string]FavoriteStation{"b": b},
+			want:   map[string]FavoriteStation{"b": b},
+		},
+		{
+			name:   "same change on both sides",
+			base:   map[string]FavoriteStation{"a": a},
+			local:  map[string]FavoriteStation{"a": a2},
+			remote: map[string]FavoriteStation{"a": a2},
+			want:   map[string]FavoriteStation{"a": a2},
+		},
+		{
+			name:          "conflicting edits keep remote",
+			base:          map[string]FavoriteStation{"a": a},
+			local:         map[string]FavoriteStation{"a": a2},
+			remote:        map[string]FavoriteStation{"a": a3},
+			want:          map[string]FavoriteStation{"a": a3},
+			wantConflicts: []string{"a"},
+		},
+		{
+			name:          "local edit against remote delete",
+			base:          map[string]FavoriteStation{"a": a},
+			local:         map[string]FavoriteStation{"a": a2},
+			remote:        map[string]FavoriteStation{},
+			want:          map[string]FavoriteStation{},
+			wantConflicts: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := mergeFavorites(tt.base, tt.local, tt.remote)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged = %v, want %v", got, tt.want)
+			}
+			var urls []string
+			for _, c := range conflicts {
+				urls = append(urls, c.URL)
+			}
+			if !reflect.DeepEqual(urls, tt.wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", urls, tt.wantConflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"radio/pkg/logger"
+)
+
+// CurrentVersion is the schema version written by this build.
+//
+// History:
+//
+//	1: {"favorites": {url: {url, name, bitrate, country, tags}}}, no version field
+//	2: adds "version" and a per-station "added_at" timestamp
+//	3: adds "history", "settings" and "cache" sections
+//	4: adds a per-station "updated_at" timestamp, initially equal to added_at
+const CurrentVersion = 4
+
+var ErrUnsupportedVersion = errors.New("storage file was written by a newer version")
+
+// document is the raw top-level JSON object of the storage file. Migrations
+// work on it rather than on typed structs so that old shapes can be read even
+// after FavoriteStation changes.
+type document map[string]json.RawMessage
+
+type migration struct {
+	from  int
+	desc  string
+	apply func(doc document, now time.Time) error
+}
+
+var migrations = []migration{
+	{from: 1, desc: "add version and added_at", apply: migrateV1ToV2},
+	{from: 2, desc: "add history, settings and cache", apply: migrateV2ToV3},
+	{from: 3, desc: "add updated_at", apply: migrateV3ToV4},
+}
+
+func documentVersion(doc document) (int, error) {
+	raw, ok := doc["version"]
+	if !ok {
+		return 1, nil
+	}
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, fmt.Errorf("decoding version: %w", err)
+	}
+	return v, nil
+}
+
+// migrate upgrades doc in place, one step at a time, until it reaches target.
+// It returns the version the document had before migrating.
+func migrate(doc document, steps []migration, target int, now time.Time) (int, error) {
+	from, err := documentVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+	if from > target {
+		return from, fmt.Errorf("%w: file version %d, supported %d", ErrUnsupportedVersion, from, target)
+	}
+
+	version := from
+	for version < target {
+		step, ok := findMigration(steps, version)
+		if !ok {
+			return from, fmt.Errorf("no migration from version %d", version)
+		}
+		if err := step.apply(doc, now); err != nil {
+			return from, fmt.Errorf("migrating from version %d (%s): %w", version, step.desc, err)
+		}
+		version++
+		doc["version"] = json.RawMessage(fmt.Sprint(version))
+		logger.Log.Info().Msgf("Storage migrated to version %d: %s", version, step.desc)
+	}
+	return from, nil
+}
+
+func findMigration(steps []migration, from int) (migration, bool) {
+	for _, m := range steps {
+		if m.from == from {
+			return m, true
+		}
+	}
+	return migration{}, false
+}
+
+// eachFavorite calls fn with the raw fields of every stored favorite and
+// writes the modified favorites back into doc.
+func eachFavorite(doc document, fn func(fav map[string]json.RawMessage)) error {
+	raw, ok := doc["favorites"]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+
+	var favorites map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &favorites); err != nil {
+		return err
+	}
+
+	for url, fav := range favorites {
+		if fav == nil {
+			fav = make(map[string]json.RawMessage)
+			favorites[url] = fav
+		}
+		fn(fav)
+	}
+
+	out, err := json.Marshal(favorites)
+	if err != nil {
+		return err
+	}
+	doc["favorites"] = out
+	return nil
+}
+
+func migrateV1ToV2(doc document, now time.Time) error {
+	stamp, _ := json.Marshal(now.UTC())
+	return eachFavorite(doc, func(fav map[string]json.RawMessage) {
+		if _, ok := fav["added_at"]; !ok {
+			fav["added_at"] = stamp
+		}
+	})
+}
+
+func migrateV2ToV3(doc document, now time.Time) error {
+	defaults := map[string]string{
+		"history":  "[]",
+		"settings": "{}",
+		"cache":    "{}",
+	}
+	for key, empty := range defaults {
+		if raw, ok := doc[key]; !ok || string(raw) == "null" {
+			doc[key] = json.RawMessage(empty)
+		}
+	}
+	return nil
+}
+
+func migrateV3ToV4(doc document, now time.Time) error {
+	stamp, _ := json.Marshal(now.UTC())
+	return eachFavorite(doc, func(fav map[string]json.RawMessage) {
+		if _, ok := fav["updated_at"]; ok {
+			return
+		}
+		if added, ok := fav["added_at"]; ok {
+			fav["updated_at"] = added
+		} else {
+			fav["updated_at"] = stamp
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+const v1Fixture = `{
+  "favorites": {
+    "http://example.com/stream": {
+      "url": "http://example.com/stream",
+      "name": "Test Station",
+      "bitrate": 128,
+      "country": "Testland",
+      "tags": "pop,rock"
+    }
+  }
+}`
+
+const v2Fixture = `{
+  "version": 2,
+  "favorites": {
+    "http://example.com/stream": {
+      "url": "http://example.com/stream",
+      "name": "Test Station",
+      "bitrate": 128,
+      "country": "Testland",
+      "tags": "pop,rock",
+      "added_at": "2025-01-02T03:04:05Z"
+    }
+  }
+}`
+
+func TestLoadVersion1(t *testing.T) {
+	path := tempFilePath(t)
+	if err := os.WriteFile(path, []byte(v1Fixture), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to load v1 file: %v", err)
+	}
+
+	favs := s.Favorites
+	fav, ok := favs["http://example.com/stream"]
+	if !ok {
+		t.Fatal("expected v1 favorite to be loaded")
+	}
+	if fav.Name != "Test Station" || fav.Bitrate != 128 || fav.Tags != "pop,rock" {
+		t.Errorf("unexpected favorite after migration: %+v", fav)
+	}
+	if fav.AddedAt.IsZero() {
+		t.Error("expected added_at to be filled in by migration")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read upgraded file: %v", err)
+	}
+	var doc struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("failed to decode upgraded file: %v", err)
+	}
+	if doc.Version != CurrentVersion {
+		t.Errorf("expected file to be rewritten at version %d, got %d", CurrentVersion, doc.Version)
+	}
+
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("expected original file to be kept as backup: %v", err)
+	}
+	if string(backup) != v1Fixture {
+		t.Error("expected backup to hold the original v1 file")
+	}
+}
+
+func TestLoadVersion2(t *testing.T) {
+	path := tempFilePath(t)
+	if err := os.WriteFile(path, []byte(v2Fixture), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to load v2 file: %v", err)
+	}
+
+	fav := s.Favorites["http://example.com/stream"]
+	want := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !fav.AddedAt.Equal(want) {
+		t.Errorf("expected added_at %v to be preserved, got %v", want, fav.AddedAt)
+	}
+}
+
+func TestLoadNewerVersionFails(t *testing.T) {
+	path := tempFilePath(t)
+	if err := os.WriteFile(path, []byte(`{"version": 999, "favorites": {}}`), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	_, err := NewStorage(path)
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != `{"version": 999, "favorites": {}}` {
+		t.Error("newer file must not be modified")
+	}
+}
+
+func TestMigrateRunsStepsInOrder(t *testing.T) {
+	var order []int
+	step := func(from int) migration {
+		return migration{from: from, desc: "test", apply: func(doc document, now time.Time) error {
+			order = append(order, from)
+			return nil
+		}}
+	}
+	steps := []migration{step(3), step(1), step(2)}
+
+	doc := document{}
+	from, err := migrate(doc, steps, 4, time.Now())
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if from != 1 {
+		t.Errorf("expected source version 1, got %d", from)
+	}
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("unexpected migration order: %v", order)
+	}
+	if v, _ := documentVersion(doc); v != 4 {
+		t.Errorf("expected version 4, got %d", v)
+	}
+}
+
+func TestMigrateMissingStep(t *testing.T) {
+	doc := document{"version": json.RawMessage("1")}
+	if _, err := migrate(doc, nil, 2, time.Now()); err == nil {
+		t.Fatal("expected error for missing migration step")
+	}
+}
+
+const v3Fixture = `{
+  "version": 3,
+  "favorites": {
+    "http://example.com/stream": {
+      "url": "http://example.com/stream",
+      "name": "Test Station",
+      "bitrate": 128,
+      "country": "Testland",
+      "tags": "pop,rock",
+      "added_at": "2025-01-02T03:04:05Z"
+    }
+  },
+  "history": [],
+  "settings": {"volume": "70"},
+  "cache": {}
+}`
+
+func TestLoadVersion3(t *testing.T) {
+	path := tempFilePath(t)
+	if err := os.WriteFile(path, []byte(v3Fixture), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to load v3 file: %v", err)
+	}
+
+	fav := s.Favorites["http://example.com/stream"]
+	if !fav.UpdatedAt.Equal(fav.AddedAt) {
+		t.Errorf("expected updated_at to default to added_at, got %v", fav.UpdatedAt)
+	}
+	if v, _ := s.GetSetting("volume"); v != "70" {
+		t.Errorf("expected settings to survive migration, got %q", v)
+	}
+}
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"radio/internal/client"
+	"radio/pkg/logger"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is the SQLite implementation of Store. The schema version is
+// kept in PRAGMA user_version and upgraded by sqliteMigrations on open.
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+var sqliteMigrations = []string{
+	// 1: initial schema
+	`CREATE TABLE favorites (
+		url      TEXT PRIMARY KEY,
+		name     TEXT NOT NULL,
+		bitrate  INTEGER NOT NULL DEFAULT 0,
+		country  TEXT NOT NULL DEFAULT '',
+		tags     TEXT NOT NULL DEFAULT '',
+		added_at INTEGER NOT NULL
+	);
+	CREATE TABLE history (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		url       TEXT NOT NULL,
+		name      TEXT NOT NULL,
+		bitrate   INTEGER NOT NULL DEFAULT 0,
+		country   TEXT NOT NULL DEFAULT '',
+		tags      TEXT NOT NULL DEFAULT '',
+		played_at INTEGER NOT NULL
+	);
+	CREATE TABLE settings (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	CREATE TABLE cache (
+		key        TEXT PRIMARY KEY,
+		value      BLOB NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	);`,
+	// 2: per-station change time for sync
+	`ALTER TABLE favorites ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+	UPDATE favorites SET updated_at = added_at;`,
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on", path))
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Failed to open SQLite storage %s", path)
+		return nil, err
+	}
+
+	s := &SQLiteStore{db: db, path: path}
+	if err := s.migrate(); err != nil {
+		_ = db.Close()
+		logger.Log.Error().Err(err).Msgf("Failed to migrate SQLite storage %s", path)
+		return nil, err
+	}
+
+	logger.Log.Info().Msgf("SQLite storage opened from %s successfully", path)
+	return s, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("%w: database version %d, supported %d", ErrUnsupportedVersion, version, len(sqliteMigrations))
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migrating to version %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		logger.Log.Info().Msgf("SQLite storage migrated to version %d", version+1)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddFavorite(station client.Station) error {
+	now := time.Now().UnixNano()
+	_, err := s.db.Exec(`
+		INSERT INTO favorites (url, name, bitrate, country, tags, added_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			name = excluded.name,
+			bitrate = excluded.bitrate,
+			country = excluded.country,
+			tags = excluded.tags,
+			updated_at = excluded.updated_at`,
+		station.URL, station.Name, station.Bitrate, station.Country, station.Tags, now, now)
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Error adding favorite %s", station.URL)
+	}
+	return err
+}
+
+func (s *SQLiteStore) RemoveFavorite(url string) error {
+	_, err := s.db.Exec(`DELETE FROM favorites WHERE url = ?`, url)
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Error removing favorite %s", url)
+	}
+	return err
+}
+
+func (s *SQLiteStore) IsFavorite(url string) bool {
+	var one int
+	err := s.db.QueryRow(`SELECT 1 FROM favorites WHERE url = ?`, url).Scan(&one)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Log.Error().Err(err).Msgf("Error checking favorite %s", url)
+	}
+	return err == nil
+}
+
+func (s *SQLiteStore) ListFavorites() []client.Station {
+	rows, err := s.db.Query(`SELECT url, name, bitrate, country, tags FROM favorites ORDER BY added_at, url`)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Error listing favorites")
+		return nil
+	}
+	defer rows.Close()
+
+	var stations []client.Station
+	for rows.Next() {
+		var st client.Station
+		if err := rows.Scan(&st.URL, &st.Name, &st.Bitrate, &st.Country, &st.Tags); err != nil {
+			logger.Log.Error().Err(err).Msg("Error scanning favorite")
+			continue
+		}
+		stations = append(stations, st)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Log.Error().Err(err).Msg("Error listing favorites")
+	}
+	return stations
+}
+
+func (s *SQLiteStore) FavoritesSnapshot() map[string]FavoriteStation {
+	rows, err := s.db.Query(`SELECT url, name, bitrate, country, tags, added_at, updated_at FROM favorites`)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Error listing favorites")
+		return map[string]FavoriteStation{}
+	}
+	defer rows.Close()
+
+	favorites := make(map[string]FavoriteStation)
+	for rows.Next() {
+		var f FavoriteStation
+		var addedAt, updatedAt int64
+		if err := rows.Scan(&f.URL, &f.Name, &f.Bitrate, &f.Country, &f.Tags, &addedAt, &updatedAt); err != nil {
+			logger.Log.Error().Err(err).Msg("Error scanning favorite")
+			continue
+		}
+		f.AddedAt = fromUnixNano(addedAt)
+		f.UpdatedAt = fromUnixNano(updatedAt)
+		favorites[f.URL] = f
+	}
+	if err := rows.Err(); err != nil {
+		logger.Log.Error().Err(err).Msg("Error listing favorites")
+	}
+	return favorites
+}
+
+func (s *SQLiteStore) ReplaceFavorites(favorites map[string]FavoriteStation) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM favorites`); err != nil {
+		return err
+	}
+	for _, f := range favorites {
+		if _, err := tx.Exec(`
+			INSERT INTO favorites (url, name, bitrate, country, tags, added_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			f.URL, f.Name, f.Bitrate, f.Country, f.Tags, unixNano(f.AddedAt), unixNano(f.UpdatedAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) PatchFavorites(old, new map[string]FavoriteStation) error {
+	diff := DiffFavorites(old, new)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, url := range append(diff.Added, diff.Updated...) {
+		f := new[url]
+		if _, err := tx.Exec(`
+			INSERT INTO favorites (url, name, bitrate, country, tags, added_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(url) DO UPDATE SET
+				name = excluded.name,
+				bitrate = excluded.bitrate,
+				country = excluded.country,
+				tags = excluded.tags,
+				added_at = excluded.added_at,
+				updated_at = excluded.updated_at`,
+			f.URL, f.Name, f.Bitrate, f.Country, f.Tags, unixNano(f.AddedAt), unixNano(f.UpdatedAt)); err != nil {
+			return err
+		}
+	}
+	for _, url := range diff.Removed {
+		if _, err := tx.Exec(`DELETE FROM favorites WHERE url = ?`, url); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+func (s *SQLiteStore) AddHistory(station client.Station, playedAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO history (url, name, bitrate, country, tags, played_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		station.URL, station.Name, station.Bitrate, station.Country, station.Tags, playedAt.UnixNano()); err != nil {
+		logger.Log.Error().Err(err).Msgf("Error adding history entry %s", station.URL)
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM history WHERE id NOT IN (
+			SELECT id FROM history ORDER BY id DESC LIMIT ?
+		)`, MaxHistory); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) ListHistory(limit int) ([]HistoryEntry, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`
+		SELECT url, name, bitrate, country, tags, played_at
+		FROM history ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		var playedAt int64
+		if err := rows.Scan(&e.URL, &e.Name, &e.Bitrate, &e.Country, &e.Tags, &playedAt); err != nil {
+			return nil, err
+		}
+		e.PlayedAt = time.Unix(0, playedAt).UTC()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteStore) GetSetting(key string) (string, bool) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Log.Error().Err(err).Msgf("Error reading setting %s", key)
+		}
+		return "", false
+	}
+	return value, true
+}
+
+func (s *SQLiteStore) SetSetting(key, value string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+func (s *SQLiteStore) GetCache(key string) ([]byte, bool) {
+	var value []byte
+	var expiresAt int64
+	err := s.db.QueryRow(`SELECT value, expires_at FROM cache WHERE key = ?`, key).Scan(&value, &expiresAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Log.Error().Err(err).Msgf("Error reading cache %s", key)
+		}
+		return nil, false
+	}
+	if expiresAt != 0 && time.Now().UnixNano() > expiresAt {
+		return nil, false
+	}
+	return value, true
+}
+
+func (s *SQLiteStore) SetCache(key string, value []byte, ttl time.Duration) error {
+	var expiresAt int64
+	now := time.Now()
+	if ttl > 0 {
+		expiresAt = now.Add(ttl).UnixNano()
+	}
+	if value == nil {
+		value = []byte{}
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM cache WHERE expires_at != 0 AND expires_at < ?`, now.UnixNano()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO cache (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		key, value, expiresAt)
+	return err
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
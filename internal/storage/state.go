@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"radio/pkg/logger"
+)
+
+// stateSaveDelay batches history and cache writes: a burst of plays or
+// cache fills is saved once, after things settle.
+const stateSaveDelay = 2 * time.Second
+
+// stateFormat is the side file holding play history and cached data. They
+// change on every play and only matter to this machine, so they are kept
+// out of the favorites file, which is merged, backed up, watched and synced.
+type stateFormat struct {
+	History []HistoryEntry        `json:"history"`
+	Cache   map[string]cacheEntry `json:"cache"`
+}
+
+// statePath turns favorites.json into favorites.state.json.
+func statePath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".state" + ext
+}
+
+// loadState reads the state file. Without one, history and cache found in
+// the favorites file by older builds are adopted; it reports whether that
+// happened, so the caller can write them to their new place.
+func (s *Storage) loadState() (adopted bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(statePath(s.path))
+	if os.IsNotExist(err) {
+		if s.legacy == nil {
+			return false, nil
+		}
+		s.history = s.legacy.History
+		if s.legacy.Cache != nil {
+			s.cache = s.legacy.Cache
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var st stateFormat
+	if err := json.Unmarshal(data, &st); err != nil {
+		// History and cache can be rebuilt; don't refuse to start over them.
+		logger.Log.Warn().Err(err).Msgf("Ignoring unreadable state file %s", statePath(s.path))
+		return false, nil
+	}
+	s.history = st.History
+	if st.Cache != nil {
+		s.cache = st.Cache
+	}
+	return false, nil
+}
+
+// scheduleStateSave saves the state file after stateSaveDelay, unless a
+// save is already pending. The caller must hold s.mu.
+func (s *Storage) scheduleStateSave() {
+	if s.stateTimer != nil {
+		return
+	}
+	s.stateTimer = time.AfterFunc(stateSaveDelay, func() {
+		if err := s.flushState(); err != nil {
+			logger.Log.Error().Err(err).Msgf("Error writing state file %s", statePath(s.path))
+		}
+	})
+}
+
+// flushState writes the state file now if it has unsaved changes.
+func (s *Storage) flushState() error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	s.mu.Lock()
+	if s.stateTimer != nil {
+		s.stateTimer.Stop()
+		s.stateTimer = nil
+	}
+	if !s.stateDirty {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stateDirty = false
+	data, err := json.Marshal(stateFormat{History: s.history, Cache: s.cache})
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := WriteFileAtomic(statePath(s.path), data, 0644); err != nil {
+		s.mu.Lock()
+		s.stateDirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -1,30 +1,86 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"radio/internal/client"
 	"radio/pkg/logger"
+	"sort"
 	"sync"
+	"time"
 )
 
+// Storage is the JSON-file implementation of Store.
 type Storage struct {
 	Favorites map[string]FavoriteStation `json:"favorites"`
+	history   []HistoryEntry
+	settings  map[string]string
+	cache     map[string]cacheEntry
 	path      string
 	mu        sync.Mutex
+	// loadedVersion is the schema version the file had when last read.
+	loadedVersion int
+	// base is the favorites as last read from or written to disk, the common
+	// ancestor for merging external edits.
+	base map[string]FavoriteStation
+	// lastHash identifies the file contents last seen, so the watcher can
+	// ignore events caused by our own writes.
+	lastHash [sha256.Size]byte
+
+	// history and cache live in the state file, see state.go. legacy holds
+	// the ones an older build left in the favorites file.
+	legacy     *stateFormat
+	stateDirty bool
+	stateTimer *time.Timer
+	// stateMu keeps state file writes in order.
+	stateMu sync.Mutex
 }
 
 type FavoriteStation struct {
-	URL     string `json:"url"`
-	Name    string `json:"name"`
-	Bitrate int    `json:"bitrate"`
-	Country string `json:"country"`
-	Tags    string `json:"tags"`
+	URL     string    `json:"url"`
+	Name    string    `json:"name"`
+	Bitrate int       `json:"bitrate"`
+	Country string    `json:"country"`
+	Tags    string    `json:"tags"`
+	AddedAt time.Time `json:"added_at"`
+	// UpdatedAt is when the station was last added or changed; sync uses it
+	// to pick the newer side of a conflict.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type cacheEntry struct {
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+type fileFormat struct {
+	Version   int                        `json:"version"`
+	Favorites map[string]FavoriteStation `json:"favorites"`
+	Settings  map[string]string          `json:"settings"`
+	// History and Cache are only read, from files written before they
+	// moved to the state file.
+	History []HistoryEntry        `json:"history,omitempty"`
+	Cache   map[string]cacheEntry `json:"cache,omitempty"`
+}
+
+func newFileFormat() *fileFormat {
+	return &fileFormat{
+		Favorites: make(map[string]FavoriteStation),
+		Settings:  make(map[string]string),
+		Cache:     make(map[string]cacheEntry),
+	}
 }
 
 func NewStorage(path string) (*Storage, error) {
 	s := &Storage{
 		Favorites: make(map[string]FavoriteStation),
+		settings:  make(map[string]string),
+		cache:     make(map[string]cacheEntry),
 		path:      path,
 	}
 
@@ -33,6 +89,28 @@ func NewStorage(path string) (*Storage, error) {
 		return nil, err
 	}
 
+	adopted, err := s.loadState()
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Failed to load state file %s", statePath(path))
+		return nil, err
+	}
+	if adopted {
+		logger.Log.Info().Msgf("Moving history and cache from %s to %s", path, statePath(path))
+		s.stateDirty = true
+		if err := s.flushState(); err != nil {
+			logger.Log.Error().Err(err).Msgf("Failed to write state file %s", statePath(path))
+			return nil, err
+		}
+	}
+
+	if adopted || (s.loadedVersion != 0 && s.loadedVersion < CurrentVersion) {
+		logger.Log.Info().Msgf("Upgrading storage file %s from version %d to %d", path, s.loadedVersion, CurrentVersion)
+		if err := s.update(func() {}); err != nil {
+			logger.Log.Error().Err(err).Msgf("Failed to write upgraded storage file %s", path)
+			return nil, err
+		}
+	}
+
 	logger.Log.Info().Msgf("Storage loaded from %s successfully", path)
 	return s, nil
 }
@@ -41,78 +119,227 @@ func (s *Storage) load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+	if data, err := os.ReadFile(s.path); err == nil {
+		s.lastHash = sha256.Sum256(data)
+	}
+	return s.loadLocked()
+}
+
+// refreshLocked re-reads the file and three-way merges it with any local
+// changes that have not reached disk yet. The caller must hold s.mu.
+func (s *Storage) refreshLocked() (ChangeEvent, error) {
+	local := s.Favorites
+	base := s.base
+
+	if err := s.loadLocked(); err != nil {
+		return ChangeEvent{}, err
+	}
+
+	merged, conflicts := mergeFavorites(base, local, s.Favorites)
+	for _, c := range conflicts {
+		logger.Log.Warn().Msgf("Favorite %s changed both locally and on disk, keeping the version on disk", c.URL)
+	}
+	s.Favorites = merged
+
+	ev := diffFavorites(local, merged)
+	ev.Conflicts = conflicts
+	return ev, nil
+}
+
+func (s *Storage) apply(f *fileFormat, version int) {
+	s.Favorites = f.Favorites
+	s.base = cloneFavorites(f.Favorites)
+	s.settings = f.Settings
+	s.legacy = nil
+	if len(f.History) > 0 || len(f.Cache) > 0 {
+		s.legacy = &stateFormat{History: f.History, Cache: f.Cache}
+	}
+	s.loadedVersion = version
+}
+
+// loadLocked reads the storage file, falling back to the rotating backup
+// when the main file is corrupt. The caller must hold s.mu.
+func (s *Storage) loadLocked() error {
+	f, version, err := readFile(s.path)
+	switch {
+	case os.IsNotExist(err):
 		logger.Log.Warn().Msgf("Storage file %s does not exist, starting with empty favorites", s.path)
+		s.apply(newFileFormat(), 0)
+		return nil
+	case err == nil && f == nil:
+		if backup, bversion, berr := readFile(s.backupPath()); berr == nil && backup != nil && len(backup.Favorites) > 0 {
+			logger.Log.Warn().Msgf("Storage file %s is empty, restored %d favorites from backup", s.path, len(backup.Favorites))
+			s.apply(backup, bversion)
+			return nil
+		}
+		logger.Log.Warn().Msgf("Storage file %s is empty, initializing with empty favorites", s.path)
+		s.apply(newFileFormat(), 0)
+		return nil
+	case err == nil:
+		s.apply(f, version)
+		logger.Log.Info().Msgf("Storage loaded from %s successfully", s.path)
 		return nil
 	}
 
-	data, err := os.ReadFile(s.path)
-	if err != nil {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) && !errors.Is(err, io.ErrUnexpectedEOF) {
 		logger.Log.Error().Err(err).Msgf("Error reading storage file %s", s.path)
 		return err
 	}
 
-	if len(data) == 0 {
-		logger.Log.Warn().Msgf("Storage file %s is empty, initializing with empty favorites", s.path)
-		s.Favorites = make(map[string]FavoriteStation)
-		return nil
+	logger.Log.Error().Err(err).Msgf("Storage file %s is corrupt, trying backup", s.path)
+	backup, bversion, berr := readFile(s.backupPath())
+	if berr != nil {
+		logger.Log.Error().Err(berr).Msgf("Error reading storage backup %s", s.backupPath())
+		return fmt.Errorf("storage file %s is corrupt and no usable backup: %w", s.path, err)
+	}
+
+	corruptPath := fmt.Sprintf("%s.corrupt-%d", s.path, time.Now().Unix())
+	if rerr := os.Rename(s.path, corruptPath); rerr != nil {
+		logger.Log.Warn().Err(rerr).Msgf("Failed to move corrupt storage file aside")
+	} else {
+		logger.Log.Warn().Msgf("Corrupt storage file kept as %s", corruptPath)
+	}
+
+	if backup == nil {
+		backup = newFileFormat()
+	}
+	s.apply(backup, bversion)
+	logger.Log.Warn().Msgf("Restored %d favorites from backup %s", len(backup.Favorites), s.backupPath())
+	return nil
+}
+
+// readFile decodes the file at path, migrating it to CurrentVersion, and
+// returns the version it was stored in. It returns a nil file and version 0
+// for an empty file.
+func readFile(path string) (*fileFormat, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, 0, nil
+	}
+
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	if doc == nil {
+		doc = make(document)
 	}
 
-	var tmp struct {
-		Favorites map[string]FavoriteStation `json:"favorites"`
+	version, err := migrate(doc, migrations, CurrentVersion, time.Now())
+	if err != nil {
+		return nil, version, err
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, version, err
+	}
+
+	f := newFileFormat()
+	if err := json.Unmarshal(migrated, f); err != nil {
+		return nil, version, err
+	}
+	if f.Favorites == nil {
+		f.Favorites = make(map[string]FavoriteStation)
+	}
+	if f.Settings == nil {
+		f.Settings = make(map[string]string)
 	}
+	if f.Cache == nil {
+		f.Cache = make(map[string]cacheEntry)
+	}
+	return f, version, nil
+}
+
+func (s *Storage) backupPath() string {
+	return s.path + ".bak"
+}
 
-	if err := json.Unmarshal(data, &tmp); err != nil {
-		logger.Log.Error().Err(err).Msgf("Error unmarshaling JSON from %s", s.path)
+// update runs fn as one read-modify-write cycle under both the in-process
+// mutex and the inter-process file lock. The file is re-read from disk
+// first, so changes made by another running instance are not overwritten.
+func (s *Storage) update(fn func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, err := acquireLock(s.path)
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Error locking storage file %s", s.path)
 		return err
 	}
+	defer func() {
+		if err := lock.release(); err != nil {
+			logger.Log.Warn().Err(err).Msgf("Error unlocking storage file %s", s.path)
+		}
+	}()
 
-	s.Favorites = tmp.Favorites
+	if _, err := s.refreshLocked(); err != nil {
+		return err
+	}
 
-	logger.Log.Info().Msgf("Storage loaded from %s successfully", s.path)
-	return nil
+	fn()
+	return s.save()
 }
 
+// save rotates the current file into the backup and atomically writes the
+// new contents. The caller must hold s.mu and the file lock.
 func (s *Storage) save() error {
-	data, err := json.MarshalIndent(struct {
-		Favorites map[string]FavoriteStation `json:"favorites"`
-	}{
+	data, err := json.MarshalIndent(fileFormat{
+		Version:   CurrentVersion,
 		Favorites: s.Favorites,
+		Settings:  s.settings,
 	}, "", "  ")
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Error marshaling storage data")
 		return err
 	}
 
-	if err := os.WriteFile(s.path, data, 0644); err != nil {
+	if prev, err := os.ReadFile(s.path); err == nil && json.Valid(prev) {
+		if err := WriteFileAtomic(s.backupPath(), prev, 0644); err != nil {
+			logger.Log.Warn().Err(err).Msgf("Error rotating storage backup %s", s.backupPath())
+		}
+	}
+
+	if err := WriteFileAtomic(s.path, data, 0644); err != nil {
 		logger.Log.Error().Err(err).Msgf("Error writing storage file %s", s.path)
 		return err
 	}
+	s.loadedVersion = CurrentVersion
+	s.base = cloneFavorites(s.Favorites)
+	s.lastHash = sha256.Sum256(data)
 
 	logger.Log.Info().Msgf("Storage saved successfully with %d favorites", len(s.Favorites))
 	return nil
 }
 
 func (s *Storage) AddFavorite(station client.Station) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.Favorites[station.URL] = FavoriteStation{
-		URL:     station.URL,
-		Name:    station.Name,
-		Bitrate: station.Bitrate,
-		Country: station.Country,
-		Tags:    station.Tags,
-	}
-	return s.save()
+	return s.update(func() {
+		now := time.Now().UTC()
+		addedAt := now
+		if existing, ok := s.Favorites[station.URL]; ok && !existing.AddedAt.IsZero() {
+			addedAt = existing.AddedAt
+		}
+		s.Favorites[station.URL] = FavoriteStation{
+			URL:       station.URL,
+			Name:      station.Name,
+			Bitrate:   station.Bitrate,
+			Country:   station.Country,
+			Tags:      station.Tags,
+			AddedAt:   addedAt,
+			UpdatedAt: now,
+		}
+	})
 }
 
 func (s *Storage) RemoveFavorite(url string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	delete(s.Favorites, url)
-	return s.save()
+	return s.update(func() {
+		delete(s.Favorites, url)
+	})
 }
 
 func (s *Storage) IsFavorite(url string) bool {
@@ -127,15 +354,133 @@ func (s *Storage) ListFavorites() []client.Station {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	stations := make([]client.Station, 0, len(s.Favorites))
+	favs := make([]FavoriteStation, 0, len(s.Favorites))
 	for _, fav := range s.Favorites {
-		stations = append(stations, client.Station{
-			URL:     fav.URL,
-			Name:    fav.Name,
-			Bitrate: fav.Bitrate,
-			Country: fav.Country,
-			Tags:    fav.Tags,
-		})
+		favs = append(favs, fav)
+	}
+	sort.Slice(favs, func(i, j int) bool {
+		if !favs[i].AddedAt.Equal(favs[j].AddedAt) {
+			return favs[i].AddedAt.Before(favs[j].AddedAt)
+		}
+		return favs[i].URL < favs[j].URL
+	})
+
+	stations := make([]client.Station, len(favs))
+	for i, fav := range favs {
+		stations[i] = fav.Station()
 	}
 	return stations
 }
+
+func (s *Storage) FavoritesSnapshot() map[string]FavoriteStation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return cloneFavorites(s.Favorites)
+}
+
+func (s *Storage) ReplaceFavorites(favorites map[string]FavoriteStation) error {
+	return s.update(func() {
+		s.Favorites = cloneFavorites(favorites)
+	})
+}
+
+func (s *Storage) PatchFavorites(old, new map[string]FavoriteStation) error {
+	diff := DiffFavorites(old, new)
+	return s.update(func() {
+		if s.Favorites == nil {
+			s.Favorites = make(map[string]FavoriteStation)
+		}
+		for _, url := range append(diff.Added, diff.Updated...) {
+			s.Favorites[url] = new[url]
+		}
+		for _, url := range diff.Removed {
+			delete(s.Favorites, url)
+		}
+	})
+}
+
+// AddHistory records a play in memory; the state file is written shortly
+// after, so playing a station never waits on the disk.
+func (s *Storage) AddHistory(station client.Station, playedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, newHistoryEntry(station, playedAt))
+	if len(s.history) > MaxHistory {
+		s.history = s.history[len(s.history)-MaxHistory:]
+	}
+	s.stateDirty = true
+	s.scheduleStateSave()
+	return nil
+}
+
+func (s *Storage) ListHistory(limit int) ([]HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.history)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	entries := make([]HistoryEntry, 0, n)
+	for i := len(s.history) - 1; i >= 0 && len(entries) < n; i-- {
+		entries = append(entries, s.history[i])
+	}
+	return entries, nil
+}
+
+func (s *Storage) GetSetting(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.settings[key]
+	return v, ok
+}
+
+func (s *Storage) SetSetting(key, value string) error {
+	return s.update(func() {
+		s.settings[key] = value
+	})
+}
+
+func (s *Storage) GetCache(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.cache[key]
+	if !ok || e.expired(time.Now()) {
+		return nil, false
+	}
+	return e.Value, true
+}
+
+// SetCache stores value in memory; like history, it is saved in batches.
+func (s *Storage) SetCache(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range s.cache {
+		if e.expired(now) {
+			delete(s.cache, k)
+		}
+	}
+	e := cacheEntry{Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = now.Add(ttl).UTC()
+	}
+	s.cache[key] = e
+	s.stateDirty = true
+	s.scheduleStateSave()
+	return nil
+}
+
+func (e cacheEntry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// Close writes any pending history and cache changes.
+func (s *Storage) Close() error {
+	return s.flushState()
+}
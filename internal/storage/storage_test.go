@@ -1,10 +1,14 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"radio/internal/client"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func tempFilePath(t *testing.T) string {
@@ -112,3 +116,158 @@ func TestEmptyStorageFile(t *testing.T) {
 		t.Error("expected empty favorites for empty file")
 	}
 }
+
+func TestSaveLeavesNoTempFiles(t *testing.T) {
+	path := tempFilePath(t)
+	s, _ := NewStorage(path)
+
+	if err := s.AddFavorite(testStation()); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("unexpected temp file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestCorruptFileFallsBackToBackup(t *testing.T) {
+	path := tempFilePath(t)
+	station := testStation()
+
+	s1, _ := NewStorage(path)
+	s1.AddFavorite(station)
+
+	other := testStation()
+	other.URL = "http://example.com/other"
+	s1.AddFavorite(other)
+
+	if err := os.WriteFile(path, []byte(`{"favorites": {"http://exa`), 0644); err != nil {
+		t.Fatalf("failed to corrupt file: %v", err)
+	}
+
+	s2, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("expected recovery from backup, got error: %v", err)
+	}
+
+	if !s2.IsFavorite(station.URL) {
+		t.Error("expected station from backup to be restored")
+	}
+	if s2.IsFavorite(other.URL) {
+		t.Error("expected backup to hold the previous version only")
+	}
+
+	matches, _ := filepath.Glob(path + ".corrupt-*")
+	if len(matches) != 1 {
+		t.Errorf("expected corrupt file to be kept aside, got %v", matches)
+	}
+}
+
+func TestCorruptFileWithoutBackup(t *testing.T) {
+	path := tempFilePath(t)
+
+	if err := os.WriteFile(path, []byte(`not json`), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := NewStorage(path); err == nil {
+		t.Fatal("expected error for corrupt file without backup")
+	}
+}
+
+func TestConcurrentInstancesDoNotClobber(t *testing.T) {
+	path := tempFilePath(t)
+
+	s1, _ := NewStorage(path)
+	s2, _ := NewStorage(path)
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			st := testStation()
+			st.URL = fmt.Sprintf("http://example.com/%d", i)
+			s := s1
+			if i%2 == 1 {
+				s = s2
+			}
+			if err := s.AddFavorite(st); err != nil {
+				t.Errorf("AddFavorite failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s3, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	if got := len(s3.ListFavorites()); got != n {
+		t.Errorf("expected %d favorites, got %d", n, got)
+	}
+}
+
+func TestHistoryStaysOutOfFavoritesFile(t *testing.T) {
+	path := tempFilePath(t)
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	s.AddFavorite(testStation())
+	before, _ := os.ReadFile(path)
+
+	s.AddHistory(testStation(), time.Now())
+	s.SetCache("c", []byte("x"), 0)
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("expected history and cache not to rewrite the favorites file")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(statePath(path)); err != nil {
+		t.Errorf("expected state file to be written on Close: %v", err)
+	}
+}
+
+func TestLegacyHistoryMovesToStateFile(t *testing.T) {
+	path := tempFilePath(t)
+	legacy := `{"version": 4, "favorites": {}, "settings": {},
+  "history": [{"url": "http://example.com/stream", "name": "Old", "played_at": "2025-01-02T03:04:05Z"}],
+  "cache": {"k": {"value": "eA=="}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to load legacy file: %v", err)
+	}
+	if h, _ := s.ListHistory(0); len(h) != 1 || h[0].Name != "Old" {
+		t.Errorf("expected legacy history to be kept, got %+v", h)
+	}
+	if v, _ := s.GetCache("k"); string(v) != "x" {
+		t.Errorf("expected legacy cache to be kept, got %q", v)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), `"history"`) || strings.Contains(string(data), `"cache"`) {
+		t.Errorf("expected history and cache to leave the favorites file:\n%s", data)
+	}
+
+	s2, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	if h, _ := s2.ListHistory(0); len(h) != 1 {
+		t.Errorf("expected history to be read from the state file, got %d entries", len(h))
+	}
+}
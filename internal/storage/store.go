@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"radio/internal/client"
+)
+
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
+
+// MaxHistory is the number of most recent plays a Store keeps.
+const MaxHistory = 200
+
+// Store persists everything the app remembers between runs.
+type Store interface {
+	AddFavorite(station client.Station) error
+	RemoveFavorite(url string) error
+	IsFavorite(url string) bool
+	// ListFavorites returns favorites in the order they were added, oldest
+	// first; stations added at the same time are ordered by URL.
+	ListFavorites() []client.Station
+	// FavoritesSnapshot returns all favorites keyed by URL, with timestamps.
+	FavoritesSnapshot() map[string]FavoriteStation
+	// ReplaceFavorites swaps the whole favorites set.
+	ReplaceFavorites(favorites map[string]FavoriteStation) error
+	// PatchFavorites applies the changes going from old to new, e.g. what
+	// a sync pulled, in one write. Favorites changed since old was taken
+	// are kept unless the patch touches them.
+	PatchFavorites(old, new map[string]FavoriteStation) error
+
+	AddHistory(station client.Station, playedAt time.Time) error
+	// ListHistory returns the most recent plays first; limit <= 0 means all.
+	ListHistory(limit int) ([]HistoryEntry, error)
+
+	GetSetting(key string) (string, bool)
+	SetSetting(key, value string) error
+
+	// GetCache returns a cached value unless it is missing or expired.
+	GetCache(key string) ([]byte, bool)
+	// SetCache stores value under key; ttl <= 0 means it never expires.
+	SetCache(key string, value []byte, ttl time.Duration) error
+
+	Close() error
+}
+
+type HistoryEntry struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Bitrate  int       `json:"bitrate"`
+	Country  string    `json:"country"`
+	Tags     string    `json:"tags"`
+	PlayedAt time.Time `json:"played_at"`
+}
+
+func newHistoryEntry(station client.Station, playedAt time.Time) HistoryEntry {
+	return HistoryEntry{
+		URL:      station.URL,
+		Name:     station.Name,
+		Bitrate:  station.Bitrate,
+		Country:  station.Country,
+		Tags:     station.Tags,
+		PlayedAt: playedAt.UTC(),
+	}
+}
+
+func (f FavoriteStation) Station() client.Station {
+	return client.Station{
+		URL:     f.URL,
+		Name:    f.Name,
+		Bitrate: f.Bitrate,
+		Country: f.Country,
+		Tags:    f.Tags,
+	}
+}
+
+// Open returns the Store for the given backend name.
+func Open(backend, path string) (Store, error) {
+	switch backend {
+	case "", BackendJSON:
+		return NewStorage(path)
+	case BackendSQLite:
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
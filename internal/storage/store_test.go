@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+)
+
+// testStoreConformance runs the behaviour every Store implementation must
+// share. open is called with the same path to simulate a restart.
+func testStoreConformance(t *testing.T, open func(path string) (Store, error)) {
+	newStore := func(t *testing.T) (Store, string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "store")
+		s, err := open(path)
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s, path
+	}
+
+	t.Run("favorites", func(t *testing.T) {
+		s, _ := newStore(t)
+		station := testStation()
+
+		if s.IsFavorite(station.URL) {
+			t.Error("expected IsFavorite to be false")
+		}
+		if err := s.AddFavorite(station); err != nil {
+			t.Fatalf("AddFavorite failed: %v", err)
+		}
+		if !s.IsFavorite(station.URL) {
+			t.Error("expected IsFavorite to be true after AddFavorite")
+		}
+
+		station.Name = "Renamed"
+		if err := s.AddFavorite(station); err != nil {
+			t.Fatalf("AddFavorite (update) failed: %v", err)
+		}
+		favs := s.ListFavorites()
+		if len(favs) != 1 {
+			t.Fatalf("expected 1 favorite, got %d", len(favs))
+		}
+		if favs[0] != station {
+			t.Errorf("expected %+v, got %+v", station, favs[0])
+		}
+
+		if err := s.RemoveFavorite(station.URL); err != nil {
+			t.Fatalf("RemoveFavorite failed: %v", err)
+		}
+		if s.IsFavorite(station.URL) {
+			t.Error("expected station to be removed")
+		}
+	})
+
+	t.Run("snapshot and replace", func(t *testing.T) {
+		s, _ := newStore(t)
+		s.AddFavorite(testStation())
+
+		snap := s.FavoritesSnapshot()
+		fav, ok := snap[testStation().URL]
+		if !ok {
+			t.Fatal("expected favorite in snapshot")
+		}
+		if fav.AddedAt.IsZero() || fav.UpdatedAt.IsZero() {
+			t.Errorf("expected timestamps to be set: %+v", fav)
+		}
+
+		stamp := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+		replacement := map[string]FavoriteStation{
+			"http://example.com/new": {
+				URL: "http://example.com/new", Name: "New", Bitrate: 64,
+				AddedAt: stamp, UpdatedAt: stamp,
+			},
+		}
+		if err := s.ReplaceFavorites(replacement); err != nil {
+			t.Fatalf("ReplaceFavorites failed: %v", err)
+		}
+		if s.IsFavorite(testStation().URL) {
+			t.Error("expected old favorite to be gone")
+		}
+		got := s.FavoritesSnapshot()["http://example.com/new"]
+		if got.Name != "New" || !got.UpdatedAt.Equal(stamp) || !got.AddedAt.Equal(stamp) {
+			t.Errorf("unexpected replaced favorite: %+v", got)
+		}
+	})
+
+	t.Run("patch keeps unrelated changes", func(t *testing.T) {
+		s, _ := newStore(t)
+		s.AddFavorite(client.Station{URL: "http://example.com/kept", Name: "Kept"})
+		s.AddFavorite(client.Station{URL: "http://example.com/old", Name: "Old"})
+		old := s.FavoritesSnapshot()
+
+		// Added after old was taken; the patch does not know about it.
+		s.AddFavorite(client.Station{URL: "http://example.com/later", Name: "Later"})
+
+		stamp := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+		patched := cloneFavorites(old)
+		delete(patched, "http://example.com/old")
+		patched["http://example.com/new"] = FavoriteStation{
+			URL: "http://example.com/new", Name: "New", AddedAt: stamp, UpdatedAt: stamp,
+		}
+		kept := patched["http://example.com/kept"]
+		kept.Name = "Renamed"
+		patched["http://example.com/kept"] = kept
+
+		if err := s.PatchFavorites(old, patched); err != nil {
+			t.Fatalf("PatchFavorites failed: %v", err)
+		}
+		got := s.FavoritesSnapshot()
+		if _, ok := got["http://example.com/old"]; ok {
+			t.Error("expected removed favorite to be gone")
+		}
+		if _, ok := got["http://example.com/later"]; !ok {
+			t.Error("expected favorite added after the snapshot to survive")
+		}
+		if n := got["http://example.com/new"]; n.Name != "New" || !n.AddedAt.Equal(stamp) {
+			t.Errorf("unexpected added favorite: %+v", n)
+		}
+		if got["http://example.com/kept"].Name != "Renamed" {
+			t.Errorf("expected updated favorite, got %+v", got["http://example.com/kept"])
+		}
+	})
+
+	t.Run("favorites are listed in order", func(t *testing.T) {
+		s, _ := newStore(t)
+		day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+		favs := make(map[string]FavoriteStation)
+		for i, url := range []string{"http://c", "http://a", "http://d", "http://b"} {
+			// Two stations share a timestamp to check the tie-break.
+			at := day.Add(time.Duration(min(i, 2)) * time.Hour)
+			favs[url] = FavoriteStation{URL: url, Name: url, AddedAt: at, UpdatedAt: at}
+		}
+		if err := s.ReplaceFavorites(favs); err != nil {
+			t.Fatalf("ReplaceFavorites failed: %v", err)
+		}
+
+		want := []string{"http://c", "http://a", "http://b", "http://d"}
+		for range 5 {
+			got := s.ListFavorites()
+			if len(got) != len(want) {
+				t.Fatalf("expected %d favorites, got %d", len(want), len(got))
+			}
+			for i := range want {
+				if got[i].URL != want[i] {
+					t.Fatalf("expected order %v, got %+v", want, got)
+				}
+			}
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		s, _ := newStore(t)
+		base := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+		for i := 0; i < 3; i++ {
+			st := testStation()
+			st.URL = fmt.Sprintf("http://example.com/%d", i)
+			if err := s.AddHistory(st, base.Add(time.Duration(i)*time.Minute)); err != nil {
+				t.Fatalf("AddHistory failed: %v", err)
+			}
+		}
+
+		entries, err := s.ListHistory(2)
+		if err != nil {
+			t.Fatalf("ListHistory failed: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(entries))
+		}
+		if entries[0].URL != "http://example.com/2" || entries[1].URL != "http://example.com/1" {
+			t.Errorf("expected most recent first, got %s, %s", entries[0].URL, entries[1].URL)
+		}
+		if !entries[0].PlayedAt.Equal(base.Add(2 * time.Minute)) {
+			t.Errorf("unexpected played_at: %v", entries[0].PlayedAt)
+		}
+
+		all, _ := s.ListHistory(0)
+		if len(all) != 3 {
+			t.Errorf("expected 3 entries without limit, got %d", len(all))
+		}
+	})
+
+	t.Run("history is capped", func(t *testing.T) {
+		s, _ := newStore(t)
+		for i := 0; i < MaxHistory+5; i++ {
+			if err := s.AddHistory(testStation(), time.Now()); err != nil {
+				t.Fatalf("AddHistory failed: %v", err)
+			}
+		}
+		all, _ := s.ListHistory(0)
+		if len(all) != MaxHistory {
+			t.Errorf("expected %d entries, got %d", MaxHistory, len(all))
+		}
+	})
+
+	t.Run("settings", func(t *testing.T) {
+		s, _ := newStore(t)
+
+		if _, ok := s.GetSetting("volume"); ok {
+			t.Error("expected missing setting")
+		}
+		if err := s.SetSetting("volume", "80"); err != nil {
+			t.Fatalf("SetSetting failed: %v", err)
+		}
+		if err := s.SetSetting("volume", "60"); err != nil {
+			t.Fatalf("SetSetting failed: %v", err)
+		}
+		if v, ok := s.GetSetting("volume"); !ok || v != "60" {
+			t.Errorf("expected 60, got %q (%v)", v, ok)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		s, _ := newStore(t)
+
+		if err := s.SetCache("forever", []byte("a"), 0); err != nil {
+			t.Fatalf("SetCache failed: %v", err)
+		}
+		if err := s.SetCache("expired", []byte("b"), time.Nanosecond); err != nil {
+			t.Fatalf("SetCache failed: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+
+		if v, ok := s.GetCache("forever"); !ok || string(v) != "a" {
+			t.Errorf("expected cached value, got %q (%v)", v, ok)
+		}
+		if _, ok := s.GetCache("expired"); ok {
+			t.Error("expected expired entry to be missing")
+		}
+		if _, ok := s.GetCache("missing"); ok {
+			t.Error("expected missing entry")
+		}
+	})
+
+	t.Run("persistence", func(t *testing.T) {
+		s, path := newStore(t)
+		station := testStation()
+
+		s.AddFavorite(station)
+		s.AddHistory(station, time.Now())
+		s.SetSetting("k", "v")
+		s.SetCache("c", []byte("x"), time.Hour)
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		s2, err := open(path)
+		if err != nil {
+			t.Fatalf("failed to reopen store: %v", err)
+		}
+		defer s2.Close()
+
+		if !s2.IsFavorite(station.URL) {
+			t.Error("expected favorite to persist")
+		}
+		if h, _ := s2.ListHistory(0); len(h) != 1 {
+			t.Errorf("expected 1 history entry, got %d", len(h))
+		}
+		if v, _ := s2.GetSetting("k"); v != "v" {
+			t.Errorf("expected setting to persist, got %q", v)
+		}
+		if v, _ := s2.GetCache("c"); string(v) != "x" {
+			t.Errorf("expected cache to persist, got %q", v)
+		}
+	})
+}
+
+func TestJSONStoreConformance(t *testing.T) {
+	testStoreConformance(t, func(path string) (Store, error) {
+		return Open(BackendJSON, path+".json")
+	})
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	testStoreConformance(t, func(path string) (Store, error) {
+		return Open(BackendSQLite, path+".db")
+	})
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open("yaml", tempFilePath(t)); err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+}
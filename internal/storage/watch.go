@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"time"
+
+	"radio/pkg/logger"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	watchDebounce = 200 * time.Millisecond
+	pollInterval  = 2 * time.Second
+)
+
+// Watcher is implemented by stores whose backing file can be edited while
+// the app is running, e.g. by hand or by a file sync tool.
+type Watcher interface {
+	// Watch reloads external changes until ctx is done and reports each one
+	// that changed the favorites.
+	Watch(ctx context.Context) (<-chan ChangeEvent, error)
+}
+
+func (s *Storage) Watch(ctx context.Context) (<-chan ChangeEvent, error) {
+	events := make(chan ChangeEvent, 8)
+
+	w, err := fsnotify.NewWatcher()
+	if err == nil {
+		// Watch the directory: atomic saves replace the file, which would
+		// silently drop a watch on the file itself.
+		err = w.Add(filepath.Dir(s.path))
+		if err != nil {
+			_ = w.Close()
+		}
+	}
+	if err != nil {
+		logger.Log.Warn().Err(err).Msgf("File notifications unavailable, polling %s every %v", s.path, pollInterval)
+		go s.poll(ctx, events)
+		return events, nil
+	}
+
+	go s.watch(ctx, w, events)
+	return events, nil
+}
+
+func (s *Storage) watch(ctx context.Context, w *fsnotify.Watcher, events chan<- ChangeEvent) {
+	defer close(events)
+	defer w.Close()
+
+	target := filepath.Clean(s.path)
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) != target {
+				continue
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			debounce = time.After(watchDebounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			logger.Log.Warn().Err(err).Msg("Storage watcher error")
+		case <-debounce:
+			debounce = nil
+			s.reloadExternal(events)
+		}
+	}
+}
+
+func (s *Storage) poll(ctx context.Context, events chan<- ChangeEvent) {
+	defer close(events)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastMod time.Time
+	var lastSize int64
+	if fi, err := os.Stat(s.path); err == nil {
+		lastMod, lastSize = fi.ModTime(), fi.Size()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fi, err := os.Stat(s.path)
+			if err != nil {
+				continue
+			}
+			if fi.ModTime().Equal(lastMod) && fi.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = fi.ModTime(), fi.Size()
+			s.reloadExternal(events)
+		}
+	}
+}
+
+// reloadExternal merges a changed file into memory. Half-written or missing
+// files are ignored: the next event will bring the finished version, and
+// treating them as corrupt would move the user's file aside.
+func (s *Storage) reloadExternal(events chan<- ChangeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	hash := sha256.Sum256(data)
+	if hash == s.lastHash {
+		return
+	}
+	if _, _, err := readFile(s.path); err != nil {
+		logger.Log.Warn().Err(err).Msgf("Ignoring unreadable change to %s", s.path)
+		return
+	}
+
+	lock, err := acquireLock(s.path)
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Error locking storage file %s", s.path)
+		return
+	}
+	defer lock.release()
+
+	ev, err := s.refreshLocked()
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Error reloading storage file %s", s.path)
+		return
+	}
+	s.lastHash = hash
+
+	// Local changes that never reached disk survive the merge; write them
+	// back so the file and memory agree again.
+	if !diffFavorites(s.base, s.Favorites).Empty() {
+		if err := s.save(); err != nil {
+			logger.Log.Error().Err(err).Msg("Error saving merged favorites")
+		}
+	}
+
+	if ev.Empty() {
+		return
+	}
+
+	logger.Log.Info().Msgf("Favorites changed on disk: %d added, %d removed, %d updated, %d conflicts",
+		len(ev.Added), len(ev.Removed), len(ev.Updated), len(ev.Conflicts))
+
+	select {
+	case events <- ev:
+	default:
+		logger.Log.Warn().Msg("Favorites change event dropped, receiver is behind")
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func writeExternal(t *testing.T, path string, favorites map[string]FavoriteStation) {
+	t.Helper()
+	data, err := json.Marshal(fileFormat{Version: CurrentVersion, Favorites: favorites})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestWatchReloadsExternalChanges(t *testing.T) {
+	path := tempFilePath(t)
+	s, _ := NewStorage(path)
+	station := testStation()
+	s.AddFavorite(station)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// Our own writes must not be reported.
+	other := testStation()
+	other.URL = "http://example.com/other"
+	s.AddFavorite(other)
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event for own write: %+v", ev)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	writeExternal(t, path, map[string]FavoriteStation{
+		"http://example.com/manual": fav("http://example.com/manual", "Manual"),
+		other.URL:                   fav(other.URL, other.Name),
+	})
+
+	select {
+	case ev := <-events:
+		if len(ev.Added) != 1 || ev.Added[0] != "http://example.com/manual" {
+			t.Errorf("unexpected added: %v", ev.Added)
+		}
+		if len(ev.Removed) != 1 || ev.Removed[0] != station.URL {
+			t.Errorf("unexpected removed: %v", ev.Removed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change event")
+	}
+
+	if !s.IsFavorite("http://example.com/manual") {
+		t.Error("expected manual edit to be loaded")
+	}
+	if s.IsFavorite(station.URL) {
+		t.Error("expected station removed on disk to be gone")
+	}
+}
+
+func TestReloadExternalKeepsUnsavedLocalChanges(t *testing.T) {
+	path := tempFilePath(t)
+	s, _ := NewStorage(path)
+	station := testStation()
+	s.AddFavorite(station)
+
+	// Simulate a local change whose save failed.
+	s.mu.Lock()
+	s.Favorites["http://example.com/local"] = fav("http://example.com/local", "Local")
+	edited := s.Favorites[station.URL]
+	edited.Name = "Local name"
+	s.Favorites[station.URL] = edited
+	s.mu.Unlock()
+
+	remote := fav(station.URL, "Remote name")
+	writeExternal(t, path, map[string]FavoriteStation{station.URL: remote})
+
+	events := make(chan ChangeEvent, 1)
+	s.reloadExternal(events)
+
+	ev := <-events
+	if len(ev.Conflicts) != 1 || ev.Conflicts[0].URL != station.URL {
+		t.Fatalf("expected conflict for %s, got %+v", station.URL, ev.Conflicts)
+	}
+	if !s.IsFavorite("http://example.com/local") {
+		t.Error("expected unsaved local station to survive")
+	}
+
+	reopened, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	if reopened.Favorites[station.URL].Name != "Remote name" {
+		t.Errorf("expected remote version to win conflict, got %q", reopened.Favorites[station.URL].Name)
+	}
+	if !reopened.IsFavorite("http://example.com/local") {
+		t.Error("expected merged result to be written back")
+	}
+}
+
+func TestReloadExternalIgnoresPartialWrites(t *testing.T) {
+	path := tempFilePath(t)
+	s, _ := NewStorage(path)
+	station := testStation()
+	s.AddFavorite(station)
+
+	if err := os.WriteFile(path, []byte(`{"favorites": {`), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	events := make(chan ChangeEvent, 1)
+	s.reloadExternal(events)
+
+	if len(events) != 0 {
+		t.Error("expected no event for unreadable file")
+	}
+	if !s.IsFavorite(station.URL) {
+		t.Error("expected favorites to be kept")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected partial file to be left in place: %v", err)
+	}
+}
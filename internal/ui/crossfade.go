@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"radio/internal/client"
+	"radio/internal/eq"
+	"radio/internal/player"
+)
+
+// crossfader is implemented by backends that can switch stations without
+// a gap.
+type crossfader interface {
+	Crossfade(ctx context.Context, url string, d time.Duration, sound player.Sound) error
+}
+
+type crossfadeState struct {
+	mode     player.CrossfadeMode
+	duration time.Duration
+	// gen identifies the latest switch; results of earlier ones are
+	// dropped.
+	gen int
+}
+
+type crossfadeDoneMsg struct {
+	gen      int
+	station  client.Station
+	eq       eq.Settings
+	loudness loudnessState
+	err      error
+}
+
+// SetCrossfade sets which station switches fade across, and over how long.
+func (m *UIModel) SetCrossfade(mode player.CrossfadeMode, d time.Duration) {
+	m.crossfade.mode = mode
+	m.crossfade.duration = d
+}
+
+func (m *UIModel) cycleCrossfade() {
+	m.crossfade.mode = m.crossfade.mode.Next()
+	m.status = "Crossfade: " + m.crossfade.mode.String()
+}
+
+// switchStation moves to item, fading across when the crossfade mode
+// covers this switch; scanning says auto-switch picked it. Otherwise it
+// is a plain PlayStation.
+func (m *UIModel) switchStation(item StationItem, scanning bool) tea.Cmd {
+	xf, ok := m.player.(crossfader)
+	fade := m.crossfade.mode == player.CrossfadeAlways ||
+		(m.crossfade.mode == player.CrossfadeScan && scanning)
+	if !ok || !fade || m.crossfade.duration <= 0 || m.playing == nil || m.playing.URL == item.Station.URL {
+		return m.PlayStation(item, true)
+	}
+
+	m.crossfade.gen++
+	m.cancelStart()
+	gen, station := m.crossfade.gen, item.Station
+	settings := m.loadEQ(station.URL)
+	loudness := m.loudnessFor(station.URL)
+	sound := player.Sound{EQ: settings, GainDB: loudness.gainDB, Stream: m.stationStream(station.URL)}
+	ctx, d := m.ctx, m.crossfade.duration
+
+	m.status = "Fading to " + station.Name + "…"
+	r, resolve := m.player.(player.StreamResolver)
+	resolve = resolve && isAdaptive(station)
+	return func() tea.Msg {
+		if resolve {
+			_ = r.ResolveStream(ctx, station.URL)
+		}
+		err := xf.Crossfade(ctx, station.URL, d, sound)
+		return crossfadeDoneMsg{gen: gen, station: station, eq: settings, loudness: loudness, err: err}
+	}
+}
+
+// handleCrossfadeDone takes over the new station once it plays. When it
+// failed to start the old one is still on.
+func (m *UIModel) handleCrossfadeDone(msg crossfadeDoneMsg) {
+	if msg.gen != m.crossfade.gen {
+		return
+	}
+	if msg.err != nil {
+		if !errors.Is(msg.err, context.Canceled) {
+			m.status = fmt.Sprintf("Could not switch to %s: %v", msg.station.Name, msg.err)
+		}
+		return
+	}
+
+	// Scheduled recordings keep going when the station changes.
+	if m.recordingSchedule == "" {
+		m.stopRecording()
+	}
+	m.eq.station, m.eq.settings = msg.station.URL, msg.eq
+	m.setLoudness(msg.loudness)
+	m.status = ""
+	// The old stream is stopped; the new one runs on m.ctx.
+	m.endStream()
+	m.stationStarted(msg.station)
+}
@@ -0,0 +1,234 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"radio/internal/client"
+	"radio/internal/favicon"
+	"radio/internal/hls"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	faviconCols = 16
+	faviconRows = 8
+)
+
+type faviconMsg struct {
+	url   string
+	image string
+	err   error
+}
+
+// detailsVariantMsg carries the HLS or DASH variant of the playing
+// station.
+type detailsVariantMsg struct {
+	url     string
+	variant *hls.Variant
+}
+
+type detailsView struct {
+	open    bool
+	station client.Station
+	variant *hls.Variant
+	image   string
+	imgErr  error
+	loading bool
+	// drewKitty is set once a Kitty image was shown, so the main view
+	// knows to clear it.
+	drewKitty bool
+}
+
+func (m *UIModel) openDetails() tea.Cmd {
+	item, ok := m.list.SelectedItem().(StationItem)
+	if !ok {
+		return nil
+	}
+	m.details = detailsView{open: true, station: item.Station, drewKitty: m.details.drewKitty}
+
+	var cmds []tea.Cmd
+	if m.playing != nil && m.playing.URL == item.Station.URL {
+		pl, url := m.player, item.Station.URL
+		cmds = append(cmds, func() tea.Msg {
+			info, _ := pl.StreamInfo()
+			return detailsVariantMsg{url: url, variant: info.Variant}
+		})
+	}
+
+	if item.Station.Favicon != "" && m.favicons != nil {
+		m.details.loading = true
+		cache, proto, ctx, url := m.favicons, m.imageProtocol, m.ctx, item.Station.Favicon
+		cmds = append(cmds, func() tea.Msg {
+			ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+			defer cancel()
+			img, err := cache.Get(ctx, url)
+			if err != nil {
+				return faviconMsg{url: url, err: err}
+			}
+			return faviconMsg{url: url, image: favicon.Render(img, proto, faviconCols, faviconRows)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *UIModel) handleDetailsVariant(msg detailsVariantMsg) {
+	if m.details.open && msg.url == m.details.station.URL {
+		m.details.variant = msg.variant
+	}
+}
+
+func (m *UIModel) handleFavicon(msg faviconMsg) {
+	if !m.details.open || msg.url != m.details.station.Favicon {
+		return
+	}
+	m.details.loading = false
+	m.details.image = msg.image
+	m.details.imgErr = msg.err
+	if msg.err == nil && m.imageProtocol == favicon.Kitty {
+		m.details.drewKitty = true
+	}
+}
+
+func (m *UIModel) updateDetails(msg tea.KeyMsg) tea.Cmd {
+	st := m.details.station
+	switch msg.String() {
+	case "esc", "d", "q":
+		m.details.open = false
+	case "o":
+		if st.Homepage == "" {
+			m.status = "Station has no homepage"
+			return nil
+		}
+		if err := openBrowser(st.Homepage); err != nil {
+			m.status = fmt.Sprintf("Failed to open browser: %v", err)
+		} else {
+			m.status = "Opened " + st.Homepage
+		}
+	case "enter":
+		return m.PlayStation(StationItem{Station: st}, true)
+	case "a":
+		if m.storage.IsFavorite(st.URL) {
+			_ = m.storage.RemoveFavorite(st.URL)
+		} else {
+			_ = m.storage.AddFavorite(st)
+		}
+		m.refreshList()
+	}
+	return nil
+}
+
+// openBrowser opens a web page. Homepages come from the station directory,
+// so anything but http and https is refused: the system opener would hand
+// file: and other schemes to whatever handler is registered for them.
+func openBrowser(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid homepage: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("not a web address: %q", rawURL)
+	}
+	page := u.String()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", page)
+	case "darwin":
+		cmd = exec.Command("open", page)
+	default:
+		cmd = exec.Command("xdg-open", page)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+func (m *UIModel) renderDetails() string {
+	st := m.details.station
+
+	var image string
+	switch {
+	case m.details.loading:
+		image = loadingStyle.Render("Loading logo...") + "\n"
+	case m.details.image != "":
+		// Printed as is: graphics escapes must not go through lipgloss.
+		image = m.details.image
+	case st.Favicon == "":
+		image = placeholder.Render("No logo") + "\n"
+	case m.details.imgErr != nil:
+		image = placeholder.Render(truncateText("Logo unavailable: "+m.details.imgErr.Error(), 60)) + "\n"
+	}
+
+	var lines []string
+	row := func(label, value string) {
+		if value != "" {
+			lines = append(lines, infoLabelStyle.Render(label)+" "+value)
+		}
+	}
+
+	fav := "no"
+	if m.storage.IsFavorite(st.URL) {
+		fav = "yes"
+	}
+	check := "ok"
+	if m.isDead(st) {
+		check = "failing"
+	}
+	country := st.Country
+	if st.CountryCode != "" {
+		country += " (" + st.CountryCode + ")"
+	}
+
+	lines = append(lines, titleStyle.Render(st.Name), "")
+	row("Stream:", st.URL)
+	row("Homepage:", st.Homepage)
+	row("Country:", country)
+	row("Language:", st.Language)
+	codec := st.Codec
+	if st.HLS == 1 {
+		codec += " (HLS)"
+	}
+	row("Codec:", codec)
+	if v := m.details.variant; v != nil {
+		row("Variant:", v.Label())
+	}
+	if st.Bitrate > 0 {
+		row("Bitrate:", fmt.Sprintf("%d kbps", st.Bitrate))
+	}
+	if l, ok := m.knownLoudness(st.URL); ok {
+		row("Loudness:", l.label())
+	} else {
+		row("Loudness:", "not measured yet")
+	}
+	row("Tags:", strings.ReplaceAll(st.Tags, ",", ", "))
+	row("Votes:", fmt.Sprint(st.Votes))
+	row("Clicks:", fmt.Sprint(st.ClickCount))
+	row("Check:", check)
+	row("Favorite:", fav)
+	row("Logo:", st.Favicon)
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#555555")).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+
+	var parts []string
+	if m.status != "" {
+		parts = append(parts, positionStyle.Render(m.status))
+	}
+	parts = append(parts, helpStyle.Render("o: open homepage • Enter: play • a: toggle favorite • Esc/d: back"))
+
+	return image + lipgloss.JoinVertical(lipgloss.Left, append([]string{box}, parts...)...)
+}
@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"radio/internal/player"
+	"radio/pkg/logger"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// AudioDeviceSetting stores the output picked in the device view.
+const AudioDeviceSetting = "audio.device"
+
+const deviceListTimeout = 10 * time.Second
+
+type deviceView struct {
+	open    bool
+	loading bool
+	cursor  int
+	devices []player.AudioDevice
+	err     error
+	// chosen is the output streams should play on, "" for the default.
+	chosen string
+}
+
+type devicesMsg struct {
+	devices []player.AudioDevice
+	err     error
+}
+
+type deviceSetMsg struct {
+	device player.AudioDevice
+	err    error
+}
+
+// deviceMissingMsg reports at startup that the chosen output is gone.
+type deviceMissingMsg struct{ name string }
+
+// SetAudioDevice records the output the player was told to use, from the
+// -audio-device flag or the saved setting.
+func (m *UIModel) SetAudioDevice(name string) {
+	m.devices.chosen = name
+}
+
+func (m *UIModel) listDevices() tea.Cmd {
+	pl, ctx := m.player, m.ctx
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(ctx, deviceListTimeout)
+		defer cancel()
+		devices, err := pl.AudioDevices(ctx)
+		return devicesMsg{devices: devices, err: err}
+	}
+}
+
+// checkDevice warns when the chosen output is not connected; the player
+// falls back to the default on its own.
+func (m *UIModel) checkDevice() tea.Cmd {
+	name := m.devices.chosen
+	if name == "" {
+		return nil
+	}
+	pl, ctx := m.player, m.ctx
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(ctx, deviceListTimeout)
+		defer cancel()
+		devices, err := pl.AudioDevices(ctx)
+		if err != nil {
+			logger.Log.Warn().Err(err).Msg("Failed to list audio devices")
+			return nil
+		}
+		for _, d := range devices {
+			if d.Name == name {
+				return nil
+			}
+		}
+		return deviceMissingMsg{name: name}
+	}
+}
+
+func (m *UIModel) openDeviceView() tea.Cmd {
+	m.devices.open = true
+	m.devices.loading = true
+	m.devices.err = nil
+	return m.listDevices()
+}
+
+func (m *UIModel) handleDevices(msg devicesMsg) {
+	m.devices.loading = false
+	m.devices.devices = msg.devices
+	m.devices.err = msg.err
+	// Row 0 is the default device.
+	m.devices.cursor = 0
+	for i, d := range msg.devices {
+		if d.Name == m.devices.chosen {
+			m.devices.cursor = i + 1
+		}
+	}
+}
+
+// updateDeviceView handles keys while the device view is open.
+func (m *UIModel) updateDeviceView(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "o", "q":
+		m.devices.open = false
+	case "up", "k":
+		if m.devices.cursor > 0 {
+			m.devices.cursor--
+		}
+	case "down", "j":
+		if m.devices.cursor < len(m.devices.devices) {
+			m.devices.cursor++
+		}
+	case "r":
+		m.devices.loading = true
+		return m.listDevices()
+	case "enter":
+		if m.devices.loading {
+			return nil
+		}
+		var device player.AudioDevice
+		if m.devices.cursor > 0 {
+			device = m.devices.devices[m.devices.cursor-1]
+		}
+		m.devices.open = false
+		pl := m.player
+		return func() tea.Msg {
+			return deviceSetMsg{device: device, err: pl.SetAudioDevice(device.Name)}
+		}
+	}
+	return nil
+}
+
+func (m *UIModel) handleDeviceSet(msg deviceSetMsg) {
+	name := deviceLabel(msg.device)
+	if msg.err != nil {
+		if errors.Is(msg.err, player.ErrDeviceNotFound) {
+			m.status = fmt.Sprintf("Output %s is not connected", name)
+		} else {
+			m.status = fmt.Sprintf("Failed to switch output: %v", msg.err)
+		}
+		return
+	}
+	m.devices.chosen = msg.device.Name
+	if err := m.storage.SetSetting(AudioDeviceSetting, msg.device.Name); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to save audio device")
+	}
+	m.status = "Output: " + name
+}
+
+func deviceLabel(d player.AudioDevice) string {
+	switch {
+	case d.Name == "":
+		return "default"
+	case d.Description != "":
+		return d.Description
+	default:
+		return d.Name
+	}
+}
+
+func (m *UIModel) renderDeviceView() string {
+	var lines []string
+	lines = append(lines, titleStyle.Render("🔈 Output device"))
+
+	switch {
+	case m.devices.loading:
+		lines = append(lines, loadingStyle.Render("Looking for devices..."))
+	case m.devices.err != nil:
+		lines = append(lines, errorStyle.Render(fmt.Sprintf("Could not list devices: %v", m.devices.err)))
+	}
+
+	rows := append([]player.AudioDevice{{}}, m.devices.devices...)
+	found := m.devices.chosen == ""
+	for i, d := range rows {
+		mark := "  "
+		if d.Name == m.devices.chosen {
+			mark = "● "
+			found = true
+		}
+		row := mark + truncateText(deviceLabel(d), 40)
+		if d.Name != "" && d.Description != "" {
+			row += placeholder.Render("  " + truncateText(d.Name, 50))
+		}
+		if i == m.devices.cursor {
+			row = scheduleSelectedStyle.Render("> " + row)
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+	if !found && !m.devices.loading && m.devices.err == nil {
+		lines = append(lines, "", errorStyle.Render(fmt.Sprintf("%s is not connected; playing on the default", m.devices.chosen)))
+	}
+
+	if m.status != "" {
+		lines = append(lines, positionStyle.Render(m.status))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#555555")).
+		Padding(0, 1).
+		MarginTop(1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+
+	help := helpStyle.Render("↑/↓: select • Enter: use • r: refresh • Esc/o: back")
+	return lipgloss.JoinVertical(lipgloss.Left, box, m.renderPlayer(), help)
+}
@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"radio/internal/eq"
+	"radio/pkg/logger"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const eqDefaultKey = "eq.default"
+
+type eqView struct {
+	open bool
+	band int
+	// settings apply to station, or are the default when it is "".
+	settings eq.Settings
+	station  string
+}
+
+func eqKey(stationURL string) string {
+	if stationURL == "" {
+		return eqDefaultKey
+	}
+	return "eq.station." + stationURL
+}
+
+// loadEQ returns the settings remembered for a station, falling back to
+// the default and then to flat.
+func (m *UIModel) loadEQ(stationURL string) eq.Settings {
+	for _, key := range []string{eqKey(stationURL), eqDefaultKey} {
+		raw, ok := m.storage.GetSetting(key)
+		if !ok {
+			continue
+		}
+		var s eq.Settings
+		if err := json.Unmarshal([]byte(raw), &s); err != nil {
+			logger.Log.Warn().Err(err).Str("key", key).Msg("Ignoring unreadable equalizer settings")
+			continue
+		}
+		return s
+	}
+	return eq.Flat()
+}
+
+func (m *UIModel) saveEQ(key string, s eq.Settings) {
+	data, err := json.Marshal(s)
+	if err == nil {
+		err = m.storage.SetSetting(key, string(data))
+	}
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to save equalizer settings")
+	}
+}
+
+// useStationEQ loads and applies a station's equalizer before it plays.
+func (m *UIModel) useStationEQ(stationURL string) {
+	m.eq.station = stationURL
+	m.eq.settings = m.loadEQ(stationURL)
+	if err := m.player.SetEqualizer(m.eq.settings); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to apply equalizer")
+	}
+}
+
+// setEQ applies s and remembers it for the playing station, or as the
+// default when nothing plays.
+func (m *UIModel) setEQ(s eq.Settings) {
+	station := ""
+	if m.playing != nil {
+		station = m.playing.URL
+	}
+	m.eq.station = station
+	m.eq.settings = s
+	m.saveEQ(eqKey(station), s)
+
+	if err := m.player.SetEqualizer(s); err != nil {
+		m.status = fmt.Sprintf("Equalizer not applied: %v", err)
+		return
+	}
+	m.status = "EQ: " + s.Label()
+}
+
+// currentEQ is what the keys act on: the playing station's settings, or
+// the default.
+func (m *UIModel) currentEQ() eq.Settings {
+	station := ""
+	if m.playing != nil {
+		station = m.playing.URL
+	}
+	if station != m.eq.station || m.eq.settings.Preset == "" {
+		m.eq.station = station
+		m.eq.settings = m.loadEQ(station)
+	}
+	return m.eq.settings
+}
+
+func (m *UIModel) cycleEQPreset() {
+	m.setEQ(m.currentEQ().NextPreset())
+}
+
+func (m *UIModel) openEQView() {
+	m.currentEQ()
+	m.eq.open = true
+}
+
+func (m *UIModel) updateEQView(msg tea.KeyMsg) tea.Cmd {
+	s := m.currentEQ()
+	switch msg.String() {
+	case "esc", "E", "q":
+		m.eq.open = false
+	case "left", "h":
+		m.eq.band = (m.eq.band + len(eq.Bands) - 1) % len(eq.Bands)
+	case "right", "l":
+		m.eq.band = (m.eq.band + 1) % len(eq.Bands)
+	case "up", "k":
+		m.setEQ(s.AdjustBand(m.eq.band, 1))
+	case "down", "j":
+		m.setEQ(s.AdjustBand(m.eq.band, -1))
+	case "0":
+		m.setEQ(s.AdjustBand(m.eq.band, -s.Gains[m.eq.band]))
+	case "e", "p":
+		m.setEQ(s.NextPreset())
+	case "n":
+		s.Normalize = !s.Normalize
+		m.setEQ(s)
+	case "f":
+		flat := eq.Flat()
+		flat.Normalize = s.Normalize
+		m.setEQ(flat)
+	case "D":
+		m.saveEQ(eqDefaultKey, s)
+		m.status = "EQ: saved as default for stations without their own"
+	}
+	return nil
+}
+
+func formatBand(freq float64) string {
+	if freq >= 1000 {
+		return fmt.Sprintf("%gk", freq/1000)
+	}
+	return fmt.Sprintf("%g", freq)
+}
+
+// eqSlider draws a gain as a bar growing left or right from the centre.
+func eqSlider(gain float64) string {
+	const half = eq.MaxGain
+	cells := int(math.Round(math.Abs(gain)))
+	left, right := strings.Repeat(" ", half), strings.Repeat(" ", half)
+	if gain < 0 {
+		left = strings.Repeat(" ", half-cells) + strings.Repeat("█", cells)
+	} else if gain > 0 {
+		right = strings.Repeat("█", cells) + strings.Repeat(" ", half-cells)
+	}
+	return "▕" + left + "│" + right + "▏"
+}
+
+func (m *UIModel) renderEQView() string {
+	s := m.eq.settings
+	target := "default (nothing playing)"
+	if m.playing != nil {
+		target = m.playing.Name
+	}
+
+	lines := []string{
+		titleStyle.Render("🎚 Equalizer"),
+		positionStyle.Render("For: " + truncateText(target, 40)),
+		"",
+	}
+	for i, freq := range eq.Bands {
+		row := fmt.Sprintf("%5s Hz %s %+5.1f dB", formatBand(freq), eqSlider(s.Gains[i]), s.Gains[i])
+		if i == m.eq.band {
+			row = scheduleSelectedStyle.Render("> " + row)
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+
+	norm := "off"
+	if s.Normalize {
+		norm = "on"
+	}
+	lines = append(lines, "",
+		infoLabelStyle.Render("Preset:")+" "+s.Preset,
+		infoLabelStyle.Render("Normalize:")+" "+norm,
+	)
+	if m.status != "" {
+		lines = append(lines, positionStyle.Render(m.status))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#555555")).
+		Padding(0, 1).
+		MarginTop(1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+
+	help := helpStyle.Render("←/→: band • ↑/↓: ±1 dB • 0: reset band • e: next preset • f: flat • n: normalize • D: save as default • Esc/E: back")
+	return lipgloss.JoinVertical(lipgloss.Left, box, m.renderPlayer(), help)
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"radio/internal/client"
+	"radio/internal/health"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type healthResultMsg struct {
+	gen int
+	res health.Result
+}
+
+type healthDoneMsg struct{ gen int }
+
+type healthState struct {
+	results map[string]health.Result
+	gen     int
+	ch      <-chan health.Result
+	cancel  context.CancelFunc
+	total   int
+	done    int
+	// pruning is set while checking favorites for "P"; pruneReady once
+	// the dead ones are known and a second "P" removes them.
+	pruning    bool
+	pruneReady bool
+}
+
+// isDead uses our own probe when there is one, otherwise radio-browser's
+// last check, which favorites do not carry.
+func (m *UIModel) isDead(st client.Station) bool {
+	if res, ok := m.health.results[st.URL]; ok {
+		return !res.Alive
+	}
+	return !m.favoritesMode && st.LastCheckOK == 0
+}
+
+func (m *UIModel) stationItem(st client.Station) StationItem {
+	item := StationItem{Station: st, Dead: m.isDead(st)}
+	if res, ok := m.health.results[st.URL]; ok {
+		switch {
+		case !res.Alive:
+			item.Note = "dead: " + res.Reason
+		case res.Bitrate > 0:
+			item.Note = fmt.Sprintf("~%d kbps measured", res.Bitrate)
+		}
+	}
+	return item
+}
+
+func (m *UIModel) checkStations(stations []client.Station, pruning bool) tea.Cmd {
+	if m.checker == nil {
+		return nil
+	}
+	if m.health.cancel != nil {
+		m.health.cancel()
+	}
+	if len(stations) == 0 {
+		m.status = "No stations to check"
+		return nil
+	}
+
+	urls := make([]string, 0, len(stations))
+	for _, st := range stations {
+		urls = append(urls, st.URL)
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.health.gen++
+	m.health.cancel = cancel
+	m.health.ch = m.checker.CheckAll(ctx, urls)
+	m.health.total = len(urls)
+	m.health.done = 0
+	m.health.pruning = pruning
+	m.health.pruneReady = false
+	m.status = fmt.Sprintf("Checking %d stream(s)...", len(urls))
+	return waitForHealth(m.health.gen, m.health.ch)
+}
+
+func waitForHealth(gen int, ch <-chan health.Result) tea.Cmd {
+	return func() tea.Msg {
+		res, ok := <-ch
+		if !ok {
+			return healthDoneMsg{gen: gen}
+		}
+		return healthResultMsg{gen: gen, res: res}
+	}
+}
+
+// checkVisible probes every station in the current list.
+func (m *UIModel) checkVisible() tea.Cmd {
+	var stations []client.Station
+	for _, item := range m.list.Items() {
+		if si, ok := item.(StationItem); ok {
+			stations = append(stations, si.Station)
+		}
+	}
+	return m.checkStations(stations, false)
+}
+
+func (m *UIModel) handleHealthResult(msg healthResultMsg) tea.Cmd {
+	if msg.gen != m.health.gen {
+		return nil
+	}
+	if m.health.results == nil {
+		m.health.results = make(map[string]health.Result)
+	}
+	m.health.results[msg.res.URL] = msg.res
+	m.health.done++
+	m.status = fmt.Sprintf("Checking streams: %d/%d", m.health.done, m.health.total)
+	m.refreshList()
+	return waitForHealth(msg.gen, m.health.ch)
+}
+
+func (m *UIModel) handleHealthDone(msg healthDoneMsg) {
+	if msg.gen != m.health.gen {
+		return
+	}
+	m.health.cancel()
+	m.health.cancel = nil
+
+	dead := len(m.deadFavorites())
+	if !m.health.pruning {
+		m.status = fmt.Sprintf("Checked %d stream(s)", m.health.done)
+		return
+	}
+	m.health.pruning = false
+	if dead == 0 {
+		m.status = "All favorites are alive"
+		return
+	}
+	m.health.pruneReady = true
+	m.status = fmt.Sprintf("%d dead favorite(s); press P again to remove them", dead)
+}
+
+func (m *UIModel) deadFavorites() []client.Station {
+	var dead []client.Station
+	for _, st := range m.storage.ListFavorites() {
+		if res, ok := m.health.results[st.URL]; ok && !res.Alive {
+			dead = append(dead, st)
+		}
+	}
+	return dead
+}
+
+// pruneDeadFavorites checks the favorites on the first press and removes
+// the dead ones on the second.
+func (m *UIModel) pruneDeadFavorites() tea.Cmd {
+	if !m.health.pruneReady {
+		return m.checkStations(m.storage.ListFavorites(), true)
+	}
+
+	m.health.pruneReady = false
+	removed := 0
+	for _, st := range m.deadFavorites() {
+		if err := m.storage.RemoveFavorite(st.URL); err != nil {
+			m.status = fmt.Sprintf("Failed to remove %s: %v", st.Name, err)
+			return nil
+		}
+		removed++
+	}
+	m.status = fmt.Sprintf("Removed %d dead favorite(s)", removed)
+	m.refreshList()
+	return nil
+}
+
+func (m *UIModel) refreshList() {
+	if m.favoritesMode {
+		m.showFavorites()
+	} else {
+		m.filterStations(m.textinput.Value())
+	}
+}
@@ -1,7 +1,12 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	"radio/internal/client"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -42,3 +47,62 @@ func colorTags(tags []string) string {
 	}
 	return b.String()
 }
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d/time.Minute) % 60
+	s := int(d/time.Second) % 60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// progressBar renders frac (0-1) as a bar width cells wide.
+func progressBar(frac float64, width int) string {
+	if frac < 0 {
+		frac = 0
+	} else if frac > 1 {
+		frac = 1
+	}
+	filled := int(frac*float64(width) + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// friendlyError explains station directory errors in plain words; others
+// are shown as they are.
+func friendlyError(err error) string {
+	var se *client.StatusError
+	switch {
+	case errors.Is(err, client.ErrRateLimited):
+		if errors.As(err, &se) && se.RetryAfter > 0 {
+			return fmt.Sprintf("The station directory is busy. Try again in %s.", se.RetryAfter.Round(time.Second))
+		}
+		return "The station directory is busy. Try again in a minute."
+	case errors.Is(err, client.ErrNotFound):
+		return "The station directory could not find that (404)."
+	case errors.Is(err, client.ErrServer):
+		errors.As(err, &se)
+		return fmt.Sprintf("The station directory is having problems (%d). Try again later.", se.StatusCode)
+	case errors.Is(err, client.ErrTimeout):
+		return "The station directory did not answer in time. Check your connection."
+	case errors.Is(err, client.ErrNetwork):
+		return "Cannot reach the station directory. Check your connection or proxy settings."
+	default:
+		return err.Error()
+	}
+}
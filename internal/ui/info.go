@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"radio/internal/client"
+	"radio/internal/health"
+	"radio/internal/player"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const infoRefresh = 2 * time.Second
+
+type streamInfoMsg struct {
+	gen  int
+	info player.StreamInfo
+	err  error
+}
+
+type probeMsg struct {
+	gen int
+	res health.Result
+}
+
+type infoTickMsg struct{ gen int }
+
+type infoPanel struct {
+	open    bool
+	gen     int
+	station *client.Station
+	stream  *player.StreamInfo
+	probe   *health.Result
+}
+
+// infoTarget is the playing station, or the selected one when nothing
+// plays.
+func (m *UIModel) infoTarget() *client.Station {
+	if m.playing != nil {
+		st := *m.playing
+		return &st
+	}
+	if item, ok := m.list.SelectedItem().(StationItem); ok {
+		st := item.Station
+		return &st
+	}
+	return nil
+}
+
+func (m *UIModel) toggleInfo() tea.Cmd {
+	m.info.gen++
+	if m.info.open {
+		m.info.open = false
+		return nil
+	}
+	m.info.open = true
+	m.info.station = nil
+	return tea.Batch(m.refreshInfo(), infoTick(m.info.gen))
+}
+
+func infoTick(gen int) tea.Cmd {
+	return tea.Tick(infoRefresh, func(time.Time) tea.Msg {
+		return infoTickMsg{gen: gen}
+	})
+}
+
+// refreshInfo re-reads the player, and probes the stream again when the
+// panel has moved to another station.
+func (m *UIModel) refreshInfo() tea.Cmd {
+	target := m.infoTarget()
+	if target == nil {
+		m.info.station = nil
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	gen := m.info.gen
+	if m.info.station == nil || m.info.station.URL != target.URL {
+		m.info.station = target
+		m.info.stream = nil
+		m.info.probe = nil
+		if m.checker != nil {
+			checker, ctx, url := m.checker, m.ctx, target.URL
+			cmds = append(cmds, func() tea.Msg {
+				return probeMsg{gen: gen, res: checker.Check(ctx, url)}
+			})
+		}
+	}
+
+	if m.playing != nil && m.playing.URL == target.URL {
+		pl := m.player
+		cmds = append(cmds, func() tea.Msg {
+			info, err := pl.StreamInfo()
+			return streamInfoMsg{gen: gen, info: info, err: err}
+		})
+	} else {
+		m.info.stream = nil
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *UIModel) handleInfoTick(msg infoTickMsg) tea.Cmd {
+	if !m.info.open || msg.gen != m.info.gen {
+		return nil
+	}
+	return tea.Batch(m.refreshInfo(), infoTick(msg.gen))
+}
+
+func (m *UIModel) handleStreamInfo(msg streamInfoMsg) {
+	if msg.gen != m.info.gen || msg.err != nil {
+		return
+	}
+	m.info.stream = &msg.info
+}
+
+func (m *UIModel) handleProbe(msg probeMsg) {
+	if msg.gen != m.info.gen || m.info.station == nil || msg.res.URL != m.info.station.URL {
+		return
+	}
+	m.info.probe = &msg.res
+}
+
+func (m *UIModel) renderInfoPanel() string {
+	st := m.info.station
+	if st == nil {
+		return infoPanelStyle.Render(placeholder.Render("No station selected"))
+	}
+
+	var lines []string
+	row := func(label, value string) {
+		if value != "" {
+			lines = append(lines, infoLabelStyle.Render(label)+" "+value)
+		}
+	}
+
+	lines = append(lines, titleStyle.Render(truncateText(st.Name, 28)), "")
+	row("Homepage:", truncateText(st.Homepage, 32))
+	row("Language:", st.Language)
+	row("Country:", st.Country)
+	row("Claimed:", fmt.Sprintf("%s %d kbps", st.Codec, st.Bitrate))
+
+	if s := m.info.stream; s != nil {
+		lines = append(lines, "")
+		format := s.Codec
+		if s.SampleRate > 0 {
+			format += fmt.Sprintf(" • %.1f kHz", float64(s.SampleRate)/1000)
+		}
+		if s.Channels > 0 {
+			format += fmt.Sprintf(" • %d ch", s.Channels)
+		}
+		row("Codec:", format)
+		if s.Bitrate > 0 {
+			row("Bitrate:", fmt.Sprintf("%d kbps", s.Bitrate/1000))
+		}
+		row("Container:", s.FileFormat)
+		if s.Variant != nil {
+			row("Variant:", truncateText(s.Variant.Label(), 30))
+		}
+		row("Buffer:", fmt.Sprintf("%.1fs (%d%%)", s.CacheSeconds, s.BufferFill))
+		if s.AudioDevice != m.devices.chosen {
+			row("Output:", "default ("+truncateText(m.devices.chosen, 20)+" missing)")
+		} else if s.AudioDevice != "" {
+			row("Output:", truncateText(s.AudioDevice, 30))
+		}
+		if s.LoudnessMeasured > 0 {
+			row("Loudness:", fmt.Sprintf("%.1f LUFS (%s)", s.LUFS, s.LoudnessMeasured.Round(time.Second)))
+		}
+		row("Genre:", s.Metadata["icy-genre"])
+		row("Stream URL:", truncateText(s.Metadata["icy-url"], 30))
+		row("Now:", truncateText(s.Metadata["icy-title"], 30))
+	}
+
+	switch p := m.info.probe; {
+	case p == nil:
+		lines = append(lines, "", loadingStyle.Render("Probing stream..."))
+	case !p.Alive:
+		lines = append(lines, "", errorStyle.Render("Dead: "+p.Reason))
+	default:
+		lines = append(lines, "")
+		row("Server:", p.Server)
+		row("Type:", p.ContentType)
+		if p.Bitrate > 0 {
+			row("Measured:", fmt.Sprintf("~%d kbps", p.Bitrate))
+		}
+		if m.info.stream == nil {
+			row("Genre:", p.ICY.Genre)
+			row("Stream URL:", truncateText(p.ICY.URL, 30))
+		}
+		row("About:", truncateText(p.ICY.Description, 30))
+		row("Latency:", p.Latency.Round(time.Millisecond).String())
+	}
+
+	return infoPanelStyle.Render(strings.Join(lines, "\n"))
+}
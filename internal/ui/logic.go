@@ -1,8 +1,8 @@
 package ui
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
 	"sort"
 	"strings"
 	"time"
@@ -10,6 +10,10 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"radio/internal/client"
+	"radio/internal/dash"
+	"radio/internal/hls"
+	"radio/internal/player"
+	"radio/pkg/logger"
 
 	"github.com/charmbracelet/bubbles/list"
 )
@@ -48,7 +52,7 @@ func (m *UIModel) filterStations(query string) {
 
 	items := make([]list.Item, 0, len(filtered))
 	for _, s := range filtered {
-		items = append(items, StationItem{Station: s})
+		items = append(items, m.stationItem(s))
 	}
 
 	m.list.SetItems(items)
@@ -59,54 +63,218 @@ func (m *UIModel) showFavorites() {
 
 	items := make([]list.Item, 0, len(stations))
 	for _, s := range stations {
-		items = append(items, StationItem{Station: s})
+		items = append(items, m.stationItem(s))
 	}
 
 	m.list.SetItems(items)
 }
-func (m *UIModel) PlayStation(item StationItem, stopFirst bool) {
-	if stopFirst {
-		_ = m.player.Stop()
+
+// stationReadyMsg says the playlist or manifest of a station was read,
+// so it can start without touching the network on the UI goroutine.
+type stationReadyMsg struct {
+	gen       int
+	item      StationItem
+	stopFirst bool
+	// alarm says the alarm started the station, to fade it in.
+	alarm  bool
+	status string
+}
+
+// isAdaptive reports whether station streams HLS, by its flag or by URL,
+// or DASH.
+func isAdaptive(station client.Station) bool {
+	return station.HLS == 1 || hls.IsPlaylistURL(station.URL) || dash.IsManifestURL(station.URL)
+}
+
+// PlayStation starts item. HLS and DASH stations first have their
+// playlist read in the returned command; others start right away.
+func (m *UIModel) PlayStation(item StationItem, stopFirst bool) tea.Cmd {
+	return m.playStation(item, stopFirst, false)
+}
+
+func (m *UIModel) playStation(item StationItem, stopFirst, alarm bool) tea.Cmd {
+	r, ok := m.player.(player.StreamResolver)
+	if !ok || !isAdaptive(item.Station) {
+		return m.startStation(item, stopFirst, alarm)
 	}
-	err := m.player.Play(m.ctx, item.Station.URL)
-	if err != nil {
-		m.err = fmt.Errorf("failed to play station: %w", err)
-		return
+
+	// A crossfade or another station still being read or connecting is
+	// superseded.
+	m.crossfade.gen++
+	m.cancelStart()
+	gen, ctx, url := m.crossfade.gen, m.ctx, item.Station.URL
+	status := "Reading the playlist of " + item.Station.Name + "…"
+	m.status = status
+	return func() tea.Msg {
+		// A failed read is logged by the player, which then lets mpv
+		// pick the variant itself.
+		_ = r.ResolveStream(ctx, url)
+		return stationReadyMsg{gen: gen, item: item, stopFirst: stopFirst, alarm: alarm, status: status}
+	}
+}
+
+func (m *UIModel) handleStationReady(msg stationReadyMsg) tea.Cmd {
+	if msg.gen != m.crossfade.gen {
+		return nil
+	}
+	if m.status == msg.status {
+		m.status = ""
+	}
+	return m.startStation(msg.item, msg.stopFirst, msg.alarm)
+}
+
+func (m *UIModel) startStation(item StationItem, stopFirst, alarm bool) tea.Cmd {
+	if alarm {
+		return m.startAlarm(item.Station)
+	}
+	return m.playNow(item, stopFirst, false, 0)
+}
+
+// startState holds the context of the stream started last with Play.
+// Cancelling it gives up on a start still connecting, which also frees
+// the player for Stop.
+type startState struct {
+	cancel context.CancelFunc
+	// pending is set until the start reports back.
+	pending bool
+}
+
+// stationStartedMsg reports whether the station of start gen plays.
+type stationStartedMsg struct {
+	gen     int
+	station client.Station
+	err     error
+	status  string
+	// alarm says the alarm started the station muted, to fade it in to
+	// volume.
+	alarm  bool
+	volume int
+}
+
+// playNow starts item on the player in the returned command, so the UI
+// keeps running while the stream connects. A later start or stop
+// abandons this one.
+func (m *UIModel) playNow(item StationItem, stopFirst, alarm bool, volume int) tea.Cmd {
+	// Scheduled recordings keep going when the user switches stations.
+	if m.playing != nil && m.playing.URL != item.Station.URL && m.recordingSchedule == "" {
+		m.stopRecording()
+	}
+	m.useStationEQ(item.Station.URL)
+	m.useStationLoudness(item.Station.URL)
+	m.useStationStream(item.Station.URL)
+	// A crossfade or start still in flight is superseded, and Play stops
+	// the stream started last.
+	m.crossfade.gen++
+	m.endStream()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.start = startState{cancel: cancel, pending: true}
+
+	gen, pl, station := m.crossfade.gen, m.player, item.Station
+	status := "Connecting to " + station.Name + "…"
+	m.status = status
+	return func() tea.Msg {
+		if stopFirst {
+			_ = pl.Stop()
+		}
+		err := pl.Play(ctx, station.URL)
+		return stationStartedMsg{gen: gen, station: station, err: err, status: status, alarm: alarm, volume: volume}
 	}
-	m.playing = &item.Station
+}
+
+func (m *UIModel) handleStationStarted(msg stationStartedMsg) tea.Cmd {
+	if msg.gen != m.crossfade.gen {
+		return nil
+	}
+	if m.status == msg.status {
+		m.status = ""
+	}
+	if msg.err != nil {
+		m.endStream()
+	} else {
+		m.start.pending = false
+	}
+
+	if msg.alarm {
+		return m.alarmStarted(msg.station, msg.volume, msg.err)
+	}
+	if msg.err != nil {
+		m.err = fmt.Errorf("failed to play station: %w", msg.err)
+		return nil
+	}
+	m.stationStarted(msg.station)
+	return nil
+}
+
+// cancelStart gives up on a start still connecting.
+func (m *UIModel) cancelStart() {
+	if m.start.pending {
+		m.endStream()
+	}
+}
+
+// endStream cancels the context of the stream started last, once it is
+// stopped or being replaced.
+func (m *UIModel) endStream() {
+	if m.start.cancel != nil {
+		m.start.cancel()
+	}
+	m.start = startState{}
+}
+
+// stationStarted records that station is now on air.
+func (m *UIModel) stationStarted(station client.Station) {
+	m.playing = &station
+	m.resetAutoSwitch()
+	m.recordHistory(station)
 	m.filterStations(m.textinput.Value())
 }
 
-func (m *UIModel) startAutoSwitchCmd() tea.Cmd {
-	return tea.Tick(m.autoSwitchDelay, func(t time.Time) tea.Msg {
-		m.autoSwitchRemaining = m.autoSwitchDelay
-		return autoSwitchMsg{}
+func (m *UIModel) recordHistory(station client.Station) {
+	if err := m.storage.AddHistory(station, time.Now()); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to record play history")
+	}
+}
+
+// autoSwitchTick ticks every second for the countdown; gen identifies the
+// scan session so ticks from before a toggle are dropped.
+func autoSwitchTick(gen int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return autoSwitchMsg{gen: gen}
 	})
 }
 
-func (m *UIModel) randomStation() {
-	items := m.list.Items()
-	if len(items) == 0 {
+// resetAutoSwitch restarts the countdown from the full dwell time.
+func (m *UIModel) resetAutoSwitch() {
+	if !m.autoSwitching {
 		return
 	}
+	m.autoSwitchDeadline = time.Now().Add(m.scan.Dwell)
+	m.autoSwitchRemaining = m.scan.Dwell
+}
 
-	rand.Seed(time.Now().UnixNano())
-	randomIndex := rand.Intn(len(items))
-	m.list.Select(randomIndex)
+func (m *UIModel) handleAutoSwitch(msg autoSwitchMsg) tea.Cmd {
+	if !m.autoSwitching || msg.gen != m.autoSwitchGen {
+		return nil
+	}
 
-	if item, ok := items[randomIndex].(StationItem); ok {
-		_ = m.player.Stop()
-		_ = m.player.Play(m.ctx, item.Station.URL)
-		m.playing = &item.Station
+	m.autoSwitchRemaining = time.Until(m.autoSwitchDeadline)
+	var cmd tea.Cmd
+	if m.autoSwitchRemaining <= 0 {
+		cmd = m.scanNext()
+		// The countdown is reset again when the station starts; keep
+		// ticking even if it could not.
+		m.resetAutoSwitch()
 	}
+	return tea.Batch(cmd, autoSwitchTick(msg.gen))
 }
 
 func (m *UIModel) toggleAutoSwitch() tea.Cmd {
+	m.autoSwitchGen++
 	if m.autoSwitching {
 		m.autoSwitching = false
 		return nil
-	} else {
-		m.autoSwitching = true
-		return m.startAutoSwitchCmd()
 	}
+	m.autoSwitching = true
+	m.resetAutoSwitch()
+	return autoSwitchTick(m.autoSwitchGen)
 }
@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"radio/internal/loudness"
+	"radio/pkg/logger"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	loudnessRefresh = 10 * time.Second
+	// loudnessMinMeasured is how much audio a measurement needs before
+	// it is trusted and saved.
+	loudnessMinMeasured = 20 * time.Second
+	// loudnessMaxWeight caps how much past listening counts when a new
+	// measurement is merged in, so a station that changes its mastering
+	// is followed.
+	loudnessMaxWeight = 10 * time.Minute
+)
+
+// stationLoudness is what is remembered about a station's level.
+type stationLoudness struct {
+	LUFS    float64   `json:"lufs"`
+	Seconds float64   `json:"seconds"`
+	Updated time.Time `json:"updated"`
+}
+
+// Gain is the correction applied to the station on playback.
+func (l stationLoudness) Gain() float64 {
+	return loudness.GainFor(l.LUFS)
+}
+
+type loudnessTickMsg struct{}
+
+type loudnessMsg struct {
+	url      string
+	lufs     float64
+	measured time.Duration
+}
+
+// loudnessState tracks the measurement of the playing station.
+type loudnessState struct {
+	station string
+	// base is what was stored when the station started playing; the
+	// session's measurement is merged into it.
+	base    stationLoudness
+	hasBase bool
+	gainDB  float64
+	// pending is the merged level not saved yet. It is kept in memory
+	// while the station plays and saved when it changes or stops.
+	pending *stationLoudness
+}
+
+func loudnessKey(url string) string {
+	return "loudness.station." + url
+}
+
+func (m *UIModel) loadLoudness(url string) (stationLoudness, bool) {
+	raw, ok := m.storage.GetSetting(loudnessKey(url))
+	if !ok {
+		return stationLoudness{}, false
+	}
+	var l stationLoudness
+	if err := json.Unmarshal([]byte(raw), &l); err != nil {
+		logger.Log.Warn().Err(err).Str("url", url).Msg("Ignoring unreadable station loudness")
+		return stationLoudness{}, false
+	}
+	return l, true
+}
+
+// knownLoudness is a station's level including the playing session.
+func (m *UIModel) knownLoudness(url string) (stationLoudness, bool) {
+	if m.loudness.station == url && m.loudness.pending != nil {
+		return *m.loudness.pending, true
+	}
+	return m.loadLoudness(url)
+}
+
+// loudnessFor loads a station's remembered level and the gain it plays
+// with. Stations never measured play unchanged until their next play.
+func (m *UIModel) loudnessFor(url string) loudnessState {
+	base, ok := m.loadLoudness(url)
+	l := loudnessState{station: url, base: base, hasBase: ok}
+	if ok {
+		l.gainDB = base.Gain()
+	}
+	return l
+}
+
+// useStationLoudness applies a station's remembered gain before it plays.
+func (m *UIModel) useStationLoudness(url string) {
+	m.setLoudness(m.loudnessFor(url))
+	if err := m.player.SetGainOffset(m.loudness.gainDB); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to apply station gain")
+	}
+}
+
+// setLoudness switches to another station's measurement, saving the
+// previous one first.
+func (m *UIModel) setLoudness(l loudnessState) {
+	m.saveLoudness()
+	m.loudness = l
+}
+
+// saveLoudness stores the playing station's level if it changed.
+func (m *UIModel) saveLoudness() {
+	l := m.loudness.pending
+	if l == nil {
+		return
+	}
+	m.loudness.pending = nil
+
+	data, err := json.Marshal(*l)
+	if err == nil {
+		err = m.storage.SetSetting(loudnessKey(m.loudness.station), string(data))
+	}
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to save station loudness")
+	}
+}
+
+func loudnessTick() tea.Cmd {
+	return tea.Tick(loudnessRefresh, func(time.Time) tea.Msg {
+		return loudnessTickMsg{}
+	})
+}
+
+func (m *UIModel) handleLoudnessTick() tea.Cmd {
+	cmds := []tea.Cmd{loudnessTick()}
+	if m.playing != nil {
+		pl, url := m.player, m.playing.URL
+		cmds = append(cmds, func() tea.Msg {
+			info, err := pl.StreamInfo()
+			if err != nil || info.LoudnessMeasured == 0 {
+				return nil
+			}
+			return loudnessMsg{url: url, lufs: info.LUFS, measured: info.LoudnessMeasured}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleLoudness keeps the playing station's level, merged with what was
+// measured on earlier plays, for saveLoudness.
+func (m *UIModel) handleLoudness(msg loudnessMsg) {
+	if m.playing == nil || m.playing.URL != msg.url || m.loudness.station != msg.url {
+		return
+	}
+	if msg.measured < loudnessMinMeasured {
+		return
+	}
+
+	merged := stationLoudness{LUFS: msg.lufs, Seconds: msg.measured.Seconds(), Updated: time.Now()}
+	if m.loudness.hasBase {
+		merged = mergeLoudness(m.loudness.base, merged)
+	}
+	m.loudness.pending = &merged
+}
+
+// mergeLoudness averages two measurements in the energy domain, weighted
+// by how long each listened.
+func mergeLoudness(old, cur stationLoudness) stationLoudness {
+	oldWeight := math.Min(old.Seconds, loudnessMaxWeight.Seconds())
+	total := oldWeight + cur.Seconds
+	if total <= 0 {
+		return cur
+	}
+	energy := func(lufs float64) float64 { return math.Pow(10, lufs/10) }
+	mean := (energy(old.LUFS)*oldWeight + energy(cur.LUFS)*cur.Seconds) / total
+	return stationLoudness{
+		LUFS:    10 * math.Log10(mean),
+		Seconds: total,
+		Updated: cur.Updated,
+	}
+}
+
+// label describes a remembered level for the details view.
+func (l stationLoudness) label() string {
+	return fmt.Sprintf("%.1f LUFS • gain %+.1f dB", l.LUFS, l.Gain())
+}
@@ -5,8 +5,18 @@ import (
 	"time"
 
 	"radio/internal/client"
+	"radio/internal/config"
+	"radio/internal/favicon"
+	"radio/internal/favsync"
+	"radio/internal/health"
+	"radio/internal/netconf"
 	"radio/internal/player"
+	"radio/internal/recorder"
+	"radio/internal/scan"
+	"radio/internal/scheduler"
 	"radio/internal/storage"
+	"radio/internal/visualizer"
+	"radio/pkg/logger"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -15,13 +25,50 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-type autoSwitchMsg struct{}
+type autoSwitchMsg struct{ gen int }
+
+type favoritesChangedMsg storage.ChangeEvent
+
+// searchState tracks the search in flight; id grows with every search.
+type searchState struct {
+	id     int
+	cancel context.CancelFunc
+}
 
 type UIModel struct {
 	autoSwitchRemaining time.Duration
-	autoSwitchDelay     time.Duration
+	autoSwitchDeadline  time.Time
+	autoSwitchGen       int
 	autoSwitching       bool
-	storage             *storage.Storage
+	scan                *scan.Scanner
+	scanFavorites       bool
+	storage             storage.Store
+	storageEvents       <-chan storage.ChangeEvent
+	syncer              *favsync.Syncer
+	syncInterval        time.Duration
+	syncing             bool
+	recorder            *recorder.Recorder
+	recordGen           int
+	recordingSchedule   string
+	scheduler           *scheduler.Scheduler
+	scheduleEvents      <-chan scheduler.Event
+	schedule            scheduleView
+	timers              timers
+	checker             *health.Checker
+	health              healthState
+	info                infoPanel
+	details             detailsView
+	viz                 vizState
+	eq                  eqView
+	loudness            loudnessState
+	crossfade           crossfadeState
+	start               startState
+	devices             deviceView
+	settings            settingsView
+	streamDefaults      config.StreamConfig
+	favicons            *favicon.Cache
+	imageProtocol       favicon.Protocol
+	status              string
 	favoritesMode       bool
 	list                list.Model
 	textinput           textinput.Model
@@ -34,16 +81,17 @@ type UIModel struct {
 	ctx                 context.Context
 	cancel              context.CancelFunc
 	client              *client.Client
-	player              *player.Player
+	player              player.Backend
 	lastInputTime       time.Time
 	searchVisible       bool
 	lastQuery           string
+	search              searchState
 	currentSort         SortMode
 	sortLabelStyle      lipgloss.Style
 	Width               int
 }
 
-func NewUIModel(client *client.Client, player *player.Player, storage *storage.Storage) *UIModel {
+func NewUIModel(client *client.Client, player player.Backend, storage storage.Store) *UIModel {
 	ti := textinput.New()
 	ti.Placeholder = "Search stations"
 	ti.CharLimit = 100
@@ -71,7 +119,10 @@ func NewUIModel(client *client.Client, player *player.Player, storage *storage.S
 	l.SetShowHelp(false)
 
 	return &UIModel{
-		autoSwitchDelay:     1 * time.Minute,
+		scan:                scan.New(scan.Sequential, time.Minute, nil),
+		checker:             health.New(nil),
+		favicons:            favicon.NewCache(favicon.DefaultDir(), nil),
+		imageProtocol:       favicon.DetectProtocol(nil),
 		autoSwitchRemaining: 1 * time.Minute,
 		storage:             storage,
 		favoritesMode:       false,
@@ -91,6 +142,51 @@ func NewUIModel(client *client.Client, player *player.Player, storage *storage.S
 	}
 }
 
+// SetNetwork makes stream checks, logo downloads and the visualizer's
+// decoder use the shared proxy and TLS settings.
+func (m *UIModel) SetNetwork(n *netconf.Network) {
+	m.checker = health.New(n.Client(0))
+	m.favicons = favicon.NewCache(favicon.DefaultDir(), n.Client(10*time.Second))
+	m.viz.source = visualizer.MPVSource{Network: n}
+}
+
 func (m *UIModel) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, m.spinner.Tick)
+	cmds := []tea.Cmd{textinput.Blink, m.spinner.Tick}
+
+	if w, ok := m.storage.(storage.Watcher); ok {
+		events, err := w.Watch(m.ctx)
+		if err != nil {
+			logger.Log.Warn().Err(err).Msg("Failed to watch favorites for external changes")
+		} else {
+			m.storageEvents = events
+			cmds = append(cmds, waitForStorageChange(events))
+		}
+	}
+
+	cmds = append(cmds, m.timerCmds()...)
+	cmds = append(cmds, loudnessTick())
+	if cmd := m.checkDevice(); cmd != nil {
+		cmds = append(cmds, cmd)
+	}
+
+	if m.scheduler != nil {
+		m.scheduleEvents = m.scheduler.Run(m.ctx)
+		cmds = append(cmds, waitForScheduleEvent(m.scheduleEvents))
+	}
+
+	if cmd := m.runSync(); cmd != nil {
+		cmds = append(cmds, cmd)
+	}
+
+	return tea.Batch(cmds...)
+}
+
+func waitForStorageChange(events <-chan storage.ChangeEvent) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return nil
+		}
+		return favoritesChangedMsg(ev)
+	}
 }
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+	"radio/internal/config"
+	"radio/internal/eq"
+	"radio/internal/player"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeBackend plays nothing. When stall is set, Play hangs like a stream
+// that never answers, until its context is cancelled.
+type fakeBackend struct {
+	stall bool
+
+	mu      sync.Mutex
+	running bool
+}
+
+func (b *fakeBackend) Play(ctx context.Context, url string) error {
+	if b.stall {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	b.running = true
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *fakeBackend) Stop() error {
+	b.mu.Lock()
+	b.running = false
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *fakeBackend) IsRunning() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.running
+}
+
+func (b *fakeBackend) Volume() int                                                { return 100 }
+func (b *fakeBackend) SetVolume(int) error                                        { return nil }
+func (b *fakeBackend) Fade(context.Context, int, time.Duration) error             { return nil }
+func (b *fakeBackend) StreamInfo() (player.StreamInfo, error)                     { return player.StreamInfo{}, nil }
+func (b *fakeBackend) SetEqualizer(eq.Settings) error                             { return nil }
+func (b *fakeBackend) SetGainOffset(float64) error                                { return nil }
+func (b *fakeBackend) AudioDevices(context.Context) ([]player.AudioDevice, error) { return nil, nil }
+func (b *fakeBackend) SetAudioDevice(string) error                                { return nil }
+func (b *fakeBackend) SetStreamConfig(config.StreamConfig) error                  { return nil }
+
+func TestStartingStationDoesNotBlock(t *testing.T) {
+	m := newTestModel(t)
+	backend := &fakeBackend{stall: true}
+	m.player = player.NewCrossfader(func() player.Backend { return backend })
+	station := client.Station{Name: "Slow FM", URL: "http://example.com/slow"}
+
+	// Play runs in the command, not in Update.
+	cmd := m.PlayStation(StationItem{Station: station}, true)
+	started := make(chan tea.Msg, 1)
+	go func() { started <- cmd() }()
+
+	// Stop gives up on the start instead of waiting for it.
+	stopped := make(chan struct{})
+	go func() {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stopping waited for the stream to connect")
+	}
+
+	select {
+	case msg := <-started:
+		m.Update(msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the abandoned start did not return")
+	}
+	if m.playing != nil || m.err != nil {
+		t.Errorf("playing = %v, err = %v after an abandoned start, want neither", m.playing, m.err)
+	}
+}
+
+func TestStartedStationIsPlaying(t *testing.T) {
+	m := newTestModel(t)
+	m.player = player.NewCrossfader(func() player.Backend { return &fakeBackend{} })
+	station := client.Station{Name: "Jazz FM", URL: "http://example.com/jazz"}
+
+	m.Update(m.PlayStation(StationItem{Station: station}, true)())
+	if m.playing == nil || m.playing.URL != station.URL {
+		t.Fatalf("playing = %v, want %s", m.playing, station.Name)
+	}
+	if m.status != "" {
+		t.Errorf("status = %q, want the connecting note cleared", m.status)
+	}
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"radio/internal/recorder"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type recordStartedMsg struct {
+	path string
+	err  error
+}
+
+// recordTickMsg refreshes the recording status; gen identifies the
+// recording it belongs to, so a restarted one does not tick twice.
+type recordTickMsg struct{ gen int }
+
+func (m *UIModel) SetRecorder(r *recorder.Recorder) {
+	m.recorder = r
+}
+
+func (m *UIModel) isRecording() bool {
+	return m.recorder != nil && m.recorder.Status().Recording
+}
+
+func (m *UIModel) toggleRecording() tea.Cmd {
+	if m.recorder == nil {
+		return nil
+	}
+	if m.isRecording() {
+		m.stopRecording()
+		return nil
+	}
+	if m.playing == nil {
+		m.status = "Play a station to record it"
+		return nil
+	}
+
+	rec := m.recorder
+	station := *m.playing
+	ctx := m.ctx
+	m.status = "Connecting recorder..."
+	return func() tea.Msg {
+		path, err := rec.Start(ctx, station)
+		return recordStartedMsg{path: path, err: err}
+	}
+}
+
+func (m *UIModel) stopRecording() {
+	if !m.isRecording() {
+		return
+	}
+	path := m.recorder.Status().Path
+	_ = m.recorder.Stop()
+	m.recordGen++
+	m.recordingSchedule = ""
+	m.status = fmt.Sprintf("Recording saved: %s", filepath.Base(path))
+}
+
+func recordTick(gen int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return recordTickMsg{gen: gen}
+	})
+}
+
+func (m *UIModel) handleRecordStarted(msg recordStartedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.recordingSchedule = ""
+		m.status = fmt.Sprintf("Recording failed: %v", msg.err)
+		return nil
+	}
+	m.status = fmt.Sprintf("Recording to %s", filepath.Base(msg.path))
+	m.recordGen++
+	return recordTick(m.recordGen)
+}
+
+func (m *UIModel) handleRecordTick(msg recordTickMsg) tea.Cmd {
+	if m.recorder == nil || msg.gen != m.recordGen {
+		return nil
+	}
+	st := m.recorder.Status()
+	if st.Err != nil {
+		_ = m.recorder.Stop()
+		m.status = fmt.Sprintf("Recording ended: %v (%s saved)", st.Err, formatBytes(st.Bytes))
+		return nil
+	}
+	if !st.Recording {
+		return nil
+	}
+	return recordTick(msg.gen)
+}
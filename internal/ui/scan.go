@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"radio/internal/client"
+	"radio/internal/scan"
+)
+
+const minDwell = 5 * time.Second
+
+// SetScan replaces the auto-switch scanner; favoritesOnly scans the
+// favorites instead of the current list.
+func (m *UIModel) SetScan(s *scan.Scanner, favoritesOnly bool) {
+	if s.Dwell < minDwell {
+		s.Dwell = minDwell
+	}
+	m.scan = s
+	m.scanFavorites = favoritesOnly
+}
+
+// dwellStep keeps short dwell times fine-grained and long ones quick to
+// reach.
+func dwellStep(d time.Duration) time.Duration {
+	switch {
+	case d < 30*time.Second:
+		return 5 * time.Second
+	case d < 2*time.Minute:
+		return 15 * time.Second
+	default:
+		return time.Minute
+	}
+}
+
+func (m *UIModel) decreaseDwell() {
+	d := m.scan.Dwell - dwellStep(m.scan.Dwell-time.Second)
+	if d < minDwell {
+		d = minDwell
+	}
+	m.scan.Dwell = d
+	m.resetAutoSwitch()
+	m.status = fmt.Sprintf("Scan: %s per station", d)
+}
+
+func (m *UIModel) increaseDwell() {
+	m.scan.Dwell += dwellStep(m.scan.Dwell)
+	m.resetAutoSwitch()
+	m.status = fmt.Sprintf("Scan: %s per station", m.scan.Dwell)
+}
+
+func (m *UIModel) scanCandidates() []client.Station {
+	if m.scanFavorites {
+		return m.storage.ListFavorites()
+	}
+	stations := make([]client.Station, 0, len(m.list.Items()))
+	for _, item := range m.list.Items() {
+		if si, ok := item.(StationItem); ok {
+			stations = append(stations, si.Station)
+		}
+	}
+	return stations
+}
+
+// scanNext switches to the station the scanner picks.
+func (m *UIModel) scanNext() tea.Cmd {
+	current := ""
+	if m.playing != nil {
+		current = m.playing.URL
+	}
+	station, ok := m.scan.Next(m.scanCandidates(), current)
+	if !ok {
+		m.status = "Scan: no stations match " + m.scanLabel()
+		return nil
+	}
+
+	for i, item := range m.list.Items() {
+		if si, ok := item.(StationItem); ok && si.Station.URL == station.URL {
+			m.list.Select(i)
+			break
+		}
+	}
+	return m.switchStation(StationItem{Station: station}, true)
+}
+
+func (m *UIModel) cycleScanMode() {
+	m.scan.SetMode(m.scan.Mode.Next())
+	m.status = "Scan mode: " + m.scan.Mode.String()
+}
+
+// cycleScanFilter steps through: everything, favorites, the selected
+// station's first tag, its country.
+func (m *UIModel) cycleScanFilter() {
+	var selected client.Station
+	if item, ok := m.list.SelectedItem().(StationItem); ok {
+		selected = item.Station
+	} else if m.playing != nil {
+		selected = *m.playing
+	}
+	tag := strings.TrimSpace(strings.Split(selected.Tags, ",")[0])
+
+	switch {
+	case !m.scanFavorites && m.scan.Filter == (scan.Filter{}):
+		m.scanFavorites = true
+	case m.scanFavorites && tag != "":
+		m.scanFavorites = false
+		m.scan.SetFilter(scan.Filter{Tag: tag})
+	case (m.scanFavorites || m.scan.Filter.Tag != "") && selected.Country != "":
+		m.scanFavorites = false
+		m.scan.SetFilter(scan.Filter{Country: selected.Country})
+	default:
+		m.scanFavorites = false
+		m.scan.SetFilter(scan.Filter{})
+	}
+	m.status = "Scan: " + m.scanLabel()
+}
+
+func (m *UIModel) scanLabel() string {
+	var parts []string
+	if m.scanFavorites {
+		parts = append(parts, "favorites")
+	}
+	if f := m.scan.Filter.String(); f != "" {
+		parts = append(parts, f)
+	}
+	if len(parts) == 0 {
+		return "all stations"
+	}
+	return strings.Join(parts, " ")
+}
+
+// lockStation keeps the current station and stops scanning.
+func (m *UIModel) lockStation() {
+	if !m.autoSwitching {
+		return
+	}
+	m.autoSwitching = false
+	m.autoSwitchGen++
+	if m.playing != nil {
+		m.status = "Locked on " + m.playing.Name
+	} else {
+		m.status = "Scan stopped"
+	}
+}
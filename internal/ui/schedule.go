@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"radio/internal/client"
+	"radio/internal/scheduler"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type scheduleEventMsg scheduler.Event
+
+type scheduleView struct {
+	open    bool
+	cursor  int
+	adding  bool
+	input   textinput.Model
+	station *client.Station
+}
+
+func (m *UIModel) SetScheduler(s *scheduler.Scheduler) {
+	m.scheduler = s
+}
+
+func waitForScheduleEvent(events <-chan scheduler.Event) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return nil
+		}
+		return scheduleEventMsg(ev)
+	}
+}
+
+func (m *UIModel) handleScheduleEvent(ev scheduler.Event) tea.Cmd {
+	sch := ev.Schedule
+	station := sch.Station()
+
+	switch {
+	case ev.Kind == scheduler.EventStart && sch.Action == scheduler.ActionPlay:
+		cmd := m.PlayStation(StationItem{Station: station}, true)
+		m.status = fmt.Sprintf("Scheduled: playing %s", station.Name)
+		return cmd
+
+	case ev.Kind == scheduler.EventStop && sch.Action == scheduler.ActionPlay:
+		if m.playing != nil && m.playing.URL == station.URL {
+			if m.recordingSchedule == "" {
+				m.stopRecording()
+			}
+			m.endStream()
+			_ = m.player.Stop()
+			m.playing = nil
+			m.saveLoudness()
+			m.filterStations(m.textinput.Value())
+		}
+		m.status = fmt.Sprintf("Scheduled: stopped %s", station.Name)
+
+	case ev.Kind == scheduler.EventStart && sch.Action == scheduler.ActionRecord:
+		if m.recorder == nil {
+			m.status = "Scheduled recording skipped: recorder not configured"
+			return nil
+		}
+		m.stopRecording()
+		m.recordingSchedule = sch.ID
+		rec := m.recorder
+		ctx := m.ctx
+		m.status = fmt.Sprintf("Scheduled: recording %s until %s", station.Name, ev.Until.Format("15:04"))
+		return func() tea.Msg {
+			path, err := rec.Start(ctx, station)
+			return recordStartedMsg{path: path, err: err}
+		}
+
+	case ev.Kind == scheduler.EventStop && sch.Action == scheduler.ActionRecord:
+		if m.recordingSchedule == sch.ID {
+			m.stopRecording()
+		}
+	}
+	return nil
+}
+
+func (m *UIModel) openScheduleView() {
+	if m.scheduler == nil {
+		m.status = "Scheduler is not available"
+		return
+	}
+	m.schedule.open = true
+	m.schedule.adding = false
+	m.schedule.cursor = 0
+	m.schedule.station = nil
+	if item, ok := m.list.SelectedItem().(StationItem); ok {
+		st := item.Station
+		m.schedule.station = &st
+	} else if m.playing != nil {
+		st := *m.playing
+		m.schedule.station = &st
+	}
+}
+
+// updateScheduleView handles keys while the schedule view is open.
+func (m *UIModel) updateScheduleView(msg tea.KeyMsg) tea.Cmd {
+	if m.schedule.adding {
+		switch msg.String() {
+		case "esc":
+			m.schedule.adding = false
+			m.schedule.input.Blur()
+		case "enter":
+			m.addSchedule(m.schedule.input.Value())
+		default:
+			var cmd tea.Cmd
+			m.schedule.input, cmd = m.schedule.input.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	schedules := m.scheduler.List()
+	var selected *scheduler.Schedule
+	if m.schedule.cursor < len(schedules) {
+		selected = &schedules[m.schedule.cursor]
+	}
+
+	switch msg.String() {
+	case "esc", "S", "q":
+		m.schedule.open = false
+	case "up", "k":
+		if m.schedule.cursor > 0 {
+			m.schedule.cursor--
+		}
+	case "down", "j":
+		if m.schedule.cursor < len(schedules)-1 {
+			m.schedule.cursor++
+		}
+	case "n":
+		if m.schedule.station == nil {
+			m.status = "Select a station before adding a schedule"
+			return nil
+		}
+		ti := textinput.New()
+		ti.Placeholder = "mon,wed 20:00-22:00 record"
+		ti.CharLimit = 60
+		ti.Width = 40
+		ti.Focus()
+		m.schedule.input = ti
+		m.schedule.adding = true
+		return textinput.Blink
+	case "d":
+		if selected != nil {
+			if err := m.scheduler.Remove(selected.ID); err != nil {
+				m.status = fmt.Sprintf("Failed to delete schedule: %v", err)
+			}
+			if m.schedule.cursor > 0 && m.schedule.cursor >= len(schedules)-1 {
+				m.schedule.cursor--
+			}
+		}
+	case "x":
+		if selected != nil {
+			if err := m.scheduler.SetEnabled(selected.ID, !selected.Enabled); err != nil {
+				m.status = fmt.Sprintf("Failed to update schedule: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// addSchedule parses "<days> <HH:MM>[-HH:MM] [play|record]" for the
+// station the view was opened on.
+func (m *UIModel) addSchedule(input string) {
+	fields := strings.Fields(input)
+	action := scheduler.ActionPlay
+	if n := len(fields); n > 0 {
+		switch strings.ToLower(fields[n-1]) {
+		case string(scheduler.ActionPlay):
+			fields = fields[:n-1]
+		case string(scheduler.ActionRecord):
+			action = scheduler.ActionRecord
+			fields = fields[:n-1]
+		}
+	}
+
+	sch, err := scheduler.ParseSpec(strings.Join(fields, " "), time.Now())
+	if err != nil {
+		m.status = fmt.Sprintf("Invalid schedule: %v", err)
+		return
+	}
+	sch.Action = action
+	sch.StationURL = m.schedule.station.URL
+	sch.StationName = m.schedule.station.Name
+	sch.Codec = m.schedule.station.Codec
+
+	if _, err := m.scheduler.Add(sch); err != nil {
+		m.status = fmt.Sprintf("Invalid schedule: %v", err)
+		return
+	}
+	m.status = fmt.Sprintf("Scheduled %s of %s: %s", action, sch.StationName, sch.Spec)
+	m.schedule.adding = false
+	m.schedule.input.Blur()
+}
+
+func (m *UIModel) renderScheduleView() string {
+	var lines []string
+	lines = append(lines, titleStyle.Render("⏰ Schedules"))
+
+	schedules := m.scheduler.List()
+	if len(schedules) == 0 {
+		lines = append(lines, placeholder.Render("No schedules yet. Press 'n' to add one for the selected station."))
+	}
+
+	now := time.Now()
+	for i, sch := range schedules {
+		check := "[ ]"
+		if sch.Enabled {
+			check = "[x]"
+		}
+		next := "-"
+		if t, ok := sch.Next(now); ok {
+			next = t.Format("Mon 02 Jan 15:04")
+		}
+		row := fmt.Sprintf("%s %-24s %-6s %-22s next: %s", check, truncateText(sch.Spec, 24), sch.Action, truncateText(sch.StationName, 22), next)
+		if m.scheduler.IsRunning(sch.ID) {
+			row += " ● active"
+		}
+		if i == m.schedule.cursor {
+			row = scheduleSelectedStyle.Render("> " + row)
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+
+	if m.schedule.adding {
+		lines = append(lines, "", fmt.Sprintf("New schedule for %s:", m.schedule.station.Name), m.schedule.input.View(),
+			placeholder.Render("<days> <HH:MM>[-HH:MM] [play|record], e.g. mondays 20:00-22:00 record"))
+	} else if m.schedule.station != nil {
+		lines = append(lines, "", positionStyle.Render("Station for new schedules: "+m.schedule.station.Name))
+	}
+
+	if m.status != "" {
+		lines = append(lines, positionStyle.Render(m.status))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#555555")).
+		Padding(0, 1).
+		MarginTop(1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+
+	help := helpStyle.Render("↑/↓: select • n: new • d: delete • x: enable/disable • Esc/S: back")
+	return lipgloss.JoinVertical(lipgloss.Left, box, m.renderPlayer(), help)
+}
@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"radio/internal/client"
+	"radio/internal/player"
+	"radio/internal/storage"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTestModel(t *testing.T) *UIModel {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "slow" {
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode([]client.Station{{Name: name + " FM", URL: "http://example.com/" + name}})
+	}))
+	t.Cleanup(srv.Close)
+
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "favorites.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	pl := player.NewCrossfader(func() player.Backend { return player.New() })
+	m := NewUIModel(client.NewClient(srv.URL, 5*time.Second, nil), pl, store)
+	t.Cleanup(m.cancel)
+	return m
+}
+
+func stationNames(m *UIModel) []string {
+	var names []string
+	for _, s := range m.allStations {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestSearchShowsOnlyLatestResults(t *testing.T) {
+	m := newTestModel(t)
+
+	slow := m.startSearch("slow")
+	slowID := m.search.id
+	slowMsg := make(chan any, 1)
+	go func() { slowMsg <- slow() }()
+
+	latest := m.startSearch("jazz")
+	m.Update(latest())
+	if got := stationNames(m); len(got) != 1 || got[0] != "jazz FM" {
+		t.Fatalf("stations = %v, want the jazz results", got)
+	}
+
+	// The superseded search was cancelled and reports so, late.
+	select {
+	case msg := <-slowMsg:
+		m.Update(msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the superseded search was not cancelled")
+	}
+	// An older search that did get results is dropped too.
+	m.Update(searchMsg{id: slowID, stations: []client.Station{{Name: "Stale FM"}}})
+
+	if got := stationNames(m); len(got) != 1 || got[0] != "jazz FM" {
+		t.Errorf("stations = %v after late results, want the jazz results", got)
+	}
+	if m.err != nil {
+		t.Errorf("err = %v, want none", m.err)
+	}
+}
+
+func TestCancelledSearchIsNotAnError(t *testing.T) {
+	m := newTestModel(t)
+	before := stationNames(m)
+
+	search := m.startSearch("slow")
+	// Quitting cancels the search in flight.
+	m.cancel()
+	msg := search()
+	if sm, ok := msg.(searchMsg); !ok || !errors.Is(sm.err, context.Canceled) {
+		t.Fatalf("search returned %#v, want a cancellation error", msg)
+	}
+	m.Update(msg)
+
+	if m.err != nil {
+		t.Errorf("err = %v after a cancelled search, want none", m.err)
+	}
+	if got := stationNames(m); len(got) != len(before) {
+		t.Errorf("stations = %v, want them untouched", got)
+	}
+}
+
+func TestTypingInSearchSkipsHotkeys(t *testing.T) {
+	m := newTestModel(t)
+	// With a station selected, most hotkeys would have something to act on.
+	m.Update(m.startSearch("jazz")())
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if !m.textinput.Focused() {
+		t.Fatal("expected tab to focus the search box")
+	}
+
+	for _, r := range "dory hP" {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if got := m.textinput.Value(); got != "dory hP" {
+		t.Errorf("search box = %q, want every key typed into it", got)
+	}
+	if m.details.open || m.devices.open || m.health.ch != nil || m.health.pruning {
+		t.Error("expected no hotkey to fire while typing")
+	}
+}
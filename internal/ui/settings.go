@@ -0,0 +1,318 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"radio/internal/client"
+	"radio/internal/config"
+	"radio/pkg/logger"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const streamDefaultKey = "stream.default"
+
+func streamStationKey(url string) string {
+	return "stream.station." + url
+}
+
+// settingsView edits stream settings, for all stations or, when station
+// is set, as overrides for one favorite.
+type settingsView struct {
+	open    bool
+	cursor  int
+	station *client.Station
+	editing bool
+	input   textinput.Model
+	// changed restarts the playing station on close.
+	changed bool
+}
+
+// streamField is a row of the settings view. get returns "" when unset,
+// and setting "" clears it.
+type streamField struct {
+	label string
+	unit  string
+	get   func(config.StreamConfig) string
+	set   func(*config.StreamConfig, string) error
+}
+
+func (f streamField) format(c config.StreamConfig) string {
+	v := f.get(c)
+	if v != "" && f.unit != "" {
+		v += " " + f.unit
+	}
+	return v
+}
+
+func intField(label, unit string, field func(*config.StreamConfig) *int) streamField {
+	return streamField{
+		label: label,
+		unit:  unit,
+		get: func(c config.StreamConfig) string {
+			if v := *field(&c); v > 0 {
+				return strconv.Itoa(v)
+			}
+			return ""
+		},
+		set: func(c *config.StreamConfig, s string) error {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				*field(c) = 0
+				return nil
+			}
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 0 {
+				return fmt.Errorf("%s must be a whole number", strings.ToLower(label))
+			}
+			*field(c) = v
+			return nil
+		},
+	}
+}
+
+var streamFields = []streamField{
+	intField("Cache size", "MiB", func(c *config.StreamConfig) *int { return &c.CacheMB }),
+	intField("Readahead", "s", func(c *config.StreamConfig) *int { return &c.ReadaheadSeconds }),
+	intField("Timeout", "s", func(c *config.StreamConfig) *int { return &c.TimeoutSeconds }),
+	{
+		label: "User agent",
+		get:   func(c config.StreamConfig) string { return c.UserAgent },
+		set: func(c *config.StreamConfig, s string) error {
+			c.UserAgent = strings.TrimSpace(s)
+			return nil
+		},
+	},
+	{
+		label: "HTTP proxy",
+		get:   func(c config.StreamConfig) string { return c.Proxy },
+		set: func(c *config.StreamConfig, s string) error {
+			s = strings.TrimSpace(s)
+			if s != "" {
+				u, err := url.Parse(s)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("proxy must look like http://host:port")
+				}
+			}
+			c.Proxy = s
+			return nil
+		},
+	},
+}
+
+// SetStreamDefaults sets the stream settings from the config file, which
+// the settings view builds on.
+func (m *UIModel) SetStreamDefaults(c config.StreamConfig) {
+	m.streamDefaults = c
+}
+
+func (m *UIModel) loadStream(key string) config.StreamConfig {
+	var c config.StreamConfig
+	raw, ok := m.storage.GetSetting(key)
+	if !ok {
+		return c
+	}
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		logger.Log.Warn().Err(err).Str("key", key).Msg("Ignoring unreadable stream settings")
+	}
+	return c
+}
+
+func (m *UIModel) saveStream(key string, c config.StreamConfig) {
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = m.storage.SetSetting(key, string(data))
+	}
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to save stream settings")
+	}
+}
+
+// defaultStream is the config file overridden by the settings view.
+func (m *UIModel) defaultStream() config.StreamConfig {
+	return m.streamDefaults.Override(m.loadStream(streamDefaultKey))
+}
+
+// stationStream is what a station plays with; only favorites keep
+// overrides.
+func (m *UIModel) stationStream(url string) config.StreamConfig {
+	c := m.defaultStream()
+	if m.storage.IsFavorite(url) {
+		c = c.Override(m.loadStream(streamStationKey(url)))
+	}
+	return c
+}
+
+// useStationStream applies a station's stream settings before it plays.
+func (m *UIModel) useStationStream(url string) {
+	if err := m.player.SetStreamConfig(m.stationStream(url)); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to apply stream settings")
+	}
+}
+
+// openSettingsView starts on the selected favorite, or the defaults when
+// the selection is not a favorite.
+func (m *UIModel) openSettingsView() {
+	m.settings = settingsView{open: true}
+	if item, ok := m.list.SelectedItem().(StationItem); ok && m.storage.IsFavorite(item.Station.URL) {
+		st := item.Station
+		m.settings.station = &st
+	}
+}
+
+func (m *UIModel) settingsKey() string {
+	if m.settings.station == nil {
+		return streamDefaultKey
+	}
+	return streamStationKey(m.settings.station.URL)
+}
+
+// updateSettingsView handles keys while the settings view is open.
+func (m *UIModel) updateSettingsView(msg tea.KeyMsg) tea.Cmd {
+	field := streamFields[m.settings.cursor]
+
+	if m.settings.editing {
+		switch msg.String() {
+		case "esc":
+			m.settings.editing = false
+		case "enter":
+			m.setStreamField(field, m.settings.input.Value())
+		default:
+			var cmd tea.Cmd
+			m.settings.input, cmd = m.settings.input.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "esc", ",", "q":
+		return m.closeSettingsView()
+	case "up", "k":
+		if m.settings.cursor > 0 {
+			m.settings.cursor--
+		}
+	case "down", "j":
+		if m.settings.cursor < len(streamFields)-1 {
+			m.settings.cursor++
+		}
+	case "tab":
+		m.toggleSettingsScope()
+	case "enter":
+		ti := textinput.New()
+		ti.SetValue(field.get(m.loadStream(m.settingsKey())))
+		ti.CharLimit = 200
+		ti.Width = 40
+		ti.Focus()
+		m.settings.input = ti
+		m.settings.editing = true
+		return textinput.Blink
+	case "x":
+		m.setStreamField(field, "")
+	}
+	return nil
+}
+
+// toggleSettingsScope switches between the defaults and the selected
+// favorite.
+func (m *UIModel) toggleSettingsScope() {
+	if m.settings.station != nil {
+		m.settings.station = nil
+		return
+	}
+	if item, ok := m.list.SelectedItem().(StationItem); ok && m.storage.IsFavorite(item.Station.URL) {
+		st := item.Station
+		m.settings.station = &st
+		return
+	}
+	m.status = "Select a favorite to tune it on its own"
+}
+
+func (m *UIModel) setStreamField(field streamField, value string) {
+	key := m.settingsKey()
+	c := m.loadStream(key)
+	if err := field.set(&c, value); err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.saveStream(key, c)
+	m.settings.editing = false
+	m.settings.changed = true
+	m.status = ""
+}
+
+// closeSettingsView restarts the playing station if its settings may have
+// changed, since they only apply when a stream starts.
+func (m *UIModel) closeSettingsView() tea.Cmd {
+	m.settings.open = false
+	if !m.settings.changed || m.playing == nil {
+		return nil
+	}
+	if st := m.settings.station; st != nil && st.URL != m.playing.URL {
+		return nil
+	}
+	cmd := m.PlayStation(StationItem{Station: *m.playing}, true)
+	m.status = "Restarted " + m.playing.Name + " with the new stream settings"
+	return cmd
+}
+
+func (m *UIModel) renderSettingsView() string {
+	var lines []string
+	scope := "All stations"
+	if m.settings.station != nil {
+		scope = m.settings.station.Name
+	}
+	lines = append(lines, titleStyle.Render("⚙ Stream settings: "+scope))
+
+	own := m.loadStream(m.settingsKey())
+	inherited := m.streamDefaults
+	if m.settings.station != nil {
+		inherited = m.defaultStream()
+	}
+	for i, f := range streamFields {
+		value := f.format(own)
+		if value == "" {
+			fallback := f.format(inherited)
+			if fallback == "" {
+				fallback = "player default"
+			}
+			value = placeholder.Render("(" + fallback + ")")
+		}
+		row := fmt.Sprintf("%-12s %s", f.label+":", value)
+		if i == m.settings.cursor {
+			row = scheduleSelectedStyle.Render("> ") + row
+		} else {
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+
+	if m.settings.editing {
+		f := streamFields[m.settings.cursor]
+		label := f.label
+		if f.unit != "" {
+			label += " (" + f.unit + ")"
+		}
+		lines = append(lines, "", label+":", m.settings.input.View(),
+			placeholder.Render("Enter: save • empty clears • Esc: cancel"))
+	}
+	if m.status != "" {
+		lines = append(lines, positionStyle.Render(m.status))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#555555")).
+		Padding(0, 1).
+		MarginTop(1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+
+	help := helpStyle.Render("↑/↓: select • Enter: edit • x: clear • Tab: all stations/selected favorite • Esc/,: back")
+	return lipgloss.JoinVertical(lipgloss.Left, box, m.renderPlayer(), help)
+}
@@ -10,6 +10,9 @@ type StationItem struct {
 	Station  client.Station
 	Playing  bool
 	Favorite bool
+	Dead     bool
+	// Note is shown after the description, e.g. the health check outcome.
+	Note     string
 	TitleStr string
 }
 
@@ -21,12 +24,19 @@ func (i StationItem) Title() string {
 	if i.Favorite {
 		title = "★ " + title
 	}
+	if i.Dead {
+		title = "✖ " + title
+	}
 	return truncate(title, 30)
 }
 
 func (i StationItem) Description() string {
 	tags := colorTags(strings.Split(i.Station.Tags, ","))
-	return fmt.Sprintf("%s • %dkbps • %s", i.Station.Country, i.Station.Bitrate, tags)
+	desc := fmt.Sprintf("%s • %dkbps • %s", i.Station.Country, i.Station.Bitrate, tags)
+	if i.Note != "" {
+		desc += " • " + i.Note
+	}
+	return desc
 }
 
 func (i StationItem) FilterValue() string {
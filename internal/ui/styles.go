@@ -33,6 +33,29 @@ var (
 			Italic(true).
 			Padding(0, 1)
 
+	recordingStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF3333")).
+			Bold(true).
+			Padding(0, 1)
+
+	scheduleSelectedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FFA500")).
+				Bold(true)
+
+	infoPanelStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#5FD3F3")).
+			Padding(0, 1).
+			MarginTop(1).
+			Width(46)
+
+	infoLabelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#888888")).
+			Width(11)
+
+	vizStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#6BCB77"))
+
 	tagColors = []lipgloss.Color{
 		"#FF6B6B", "#6BCB77", "#4D96FF", "#FFD93D", "#C77DFF",
 	}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"radio/internal/favsync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type syncDoneMsg struct {
+	result favsync.Result
+	err    error
+}
+
+type syncTickMsg struct{}
+
+// SetSyncer enables favorites sync; interval > 0 also syncs periodically.
+func (m *UIModel) SetSyncer(s *favsync.Syncer, interval time.Duration) {
+	m.syncer = s
+	m.syncInterval = interval
+}
+
+func (m *UIModel) runSync() tea.Cmd {
+	if m.syncer == nil || m.syncing {
+		return nil
+	}
+	m.syncing = true
+	m.status = "Syncing favorites..."
+
+	syncer := m.syncer
+	ctx := m.ctx
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		res, err := syncer.Sync(ctx)
+		return syncDoneMsg{result: res, err: err}
+	}
+}
+
+func (m *UIModel) handleSyncDone(msg syncDoneMsg) tea.Cmd {
+	m.syncing = false
+
+	switch {
+	case msg.err != nil:
+		m.status = fmt.Sprintf("Sync failed: %v", msg.err)
+	case len(msg.result.Conflicts) > 0:
+		m.status = fmt.Sprintf("Synced favorites, %d conflict(s) resolved", len(msg.result.Conflicts))
+	default:
+		pulled := len(msg.result.Pulled.Added) + len(msg.result.Pulled.Removed) + len(msg.result.Pulled.Updated)
+		m.status = fmt.Sprintf("Synced favorites (%d change(s) pulled)", pulled)
+	}
+
+	if m.favoritesMode {
+		m.showFavorites()
+	} else {
+		m.filterStations(m.textinput.Value())
+	}
+
+	if m.syncInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.syncInterval, func(time.Time) tea.Msg {
+		return syncTickMsg{}
+	})
+}
@@ -0,0 +1,319 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"radio/internal/client"
+	"radio/pkg/logger"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	sleepFade = time.Minute
+	alarmFade = 30 * time.Second
+)
+
+// sleepPresets are cycled with "t"; the last step turns the timer off.
+var sleepPresets = []time.Duration{15 * time.Minute, 30 * time.Minute, 45 * time.Minute, time.Hour, 90 * time.Minute}
+
+type sleepTickMsg struct{ gen int }
+
+type alarmTickMsg struct{ gen int }
+
+type fadeDoneMsg struct{ err error }
+
+type timers struct {
+	sleepAt     time.Time
+	sleepLength time.Duration
+	sleepGen    int
+	sleepFading bool
+	sleepVolume int
+
+	alarmAt      time.Time
+	alarmStation *client.Station
+	alarmGen     int
+	alarmPrompt  bool
+	alarmInput   textinput.Model
+	// alarmPending is the station chosen when the prompt opened; it is
+	// the one the alarm plays, whatever is selected by the time it fires.
+	alarmPending *client.Station
+
+	fadeCancel context.CancelFunc
+}
+
+// SetSleepTimer stops playback after d, fading out over the last minute.
+func (m *UIModel) SetSleepTimer(d time.Duration) {
+	m.cancelSleep()
+	if d <= 0 {
+		return
+	}
+	m.timers.sleepAt = time.Now().Add(d)
+	m.timers.sleepLength = d
+	m.timers.sleepGen++
+}
+
+// SetAlarm plays station at the given time, fading in.
+func (m *UIModel) SetAlarm(at time.Time, station client.Station) {
+	m.timers.alarmAt = at
+	m.timers.alarmStation = &station
+	m.timers.alarmGen++
+}
+
+// ParseAlarmTime returns the next time the clock shows hh:mm.
+func ParseAlarmTime(s string, now time.Time) (time.Time, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	h, err1 := strconv.Atoi(hh)
+	mi, err2 := strconv.Atoi(mm)
+	if !ok || err1 != nil || err2 != nil || h < 0 || h > 23 || mi < 0 || mi > 59 {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), h, mi, 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+func (m *UIModel) timerCmds() []tea.Cmd {
+	var cmds []tea.Cmd
+	if !m.timers.sleepAt.IsZero() {
+		cmds = append(cmds, sleepTick(m.timers.sleepGen))
+	}
+	if !m.timers.alarmAt.IsZero() {
+		cmds = append(cmds, alarmTick(m.timers.alarmGen))
+	}
+	return cmds
+}
+
+func sleepTick(gen int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return sleepTickMsg{gen: gen}
+	})
+}
+
+func alarmTick(gen int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return alarmTickMsg{gen: gen}
+	})
+}
+
+// cycleSleepTimer steps through the presets, then off.
+func (m *UIModel) cycleSleepTimer() tea.Cmd {
+	next := sleepPresets[0]
+	if !m.timers.sleepAt.IsZero() {
+		next = 0
+		for i, d := range sleepPresets {
+			if d == m.timers.sleepLength && i+1 < len(sleepPresets) {
+				next = sleepPresets[i+1]
+			}
+		}
+	}
+
+	m.SetSleepTimer(next)
+	if next == 0 {
+		m.status = "Sleep timer off"
+		return nil
+	}
+	m.status = fmt.Sprintf("Sleep timer: stopping in %s", next)
+	return sleepTick(m.timers.sleepGen)
+}
+
+func (m *UIModel) cancelSleep() {
+	if m.timers.sleepFading {
+		m.stopFade()
+		_ = m.player.SetVolume(m.timers.sleepVolume)
+	}
+	m.timers.sleepAt = time.Time{}
+	m.timers.sleepLength = 0
+	m.timers.sleepFading = false
+	m.timers.sleepGen++
+}
+
+func (m *UIModel) handleSleepTick(msg sleepTickMsg) tea.Cmd {
+	if msg.gen != m.timers.sleepGen || m.timers.sleepAt.IsZero() {
+		return nil
+	}
+
+	remaining := time.Until(m.timers.sleepAt)
+	if remaining <= 0 {
+		volume := m.timers.sleepVolume
+		fading := m.timers.sleepFading
+		m.stopFade()
+		m.timers.sleepFading = false
+		m.cancelSleep()
+
+		m.endStream()
+		if m.playing != nil {
+			if m.recordingSchedule == "" {
+				m.stopRecording()
+			}
+			_ = m.player.Stop()
+			m.playing = nil
+			m.saveLoudness()
+			m.filterStations(m.textinput.Value())
+		}
+		if fading {
+			_ = m.player.SetVolume(volume)
+		}
+		m.status = "Sleep timer: playback stopped"
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	if remaining <= sleepFade && !m.timers.sleepFading && m.playing != nil {
+		m.timers.sleepFading = true
+		m.timers.sleepVolume = m.player.Volume()
+		cmds = append(cmds, m.fade(0, remaining))
+	}
+	cmds = append(cmds, sleepTick(msg.gen))
+	return tea.Batch(cmds...)
+}
+
+func (m *UIModel) handleAlarmTick(msg alarmTickMsg) tea.Cmd {
+	if msg.gen != m.timers.alarmGen || m.timers.alarmAt.IsZero() {
+		return nil
+	}
+	if time.Now().Before(m.timers.alarmAt) {
+		return alarmTick(msg.gen)
+	}
+
+	station := *m.timers.alarmStation
+	m.timers.alarmAt = time.Time{}
+	m.timers.alarmStation = nil
+	return m.playStation(StationItem{Station: station}, true, true)
+}
+
+// startAlarm plays station silently, to fade it in once it plays.
+func (m *UIModel) startAlarm(station client.Station) tea.Cmd {
+	volume := m.player.Volume()
+	if volume == 0 {
+		volume = 100
+	}
+	_ = m.player.SetVolume(0)
+	return m.playNow(StationItem{Station: station}, true, true, volume)
+}
+
+// alarmStarted fades the alarm station in, or puts the volume back when
+// it did not start.
+func (m *UIModel) alarmStarted(station client.Station, volume int, err error) tea.Cmd {
+	if err != nil {
+		_ = m.player.SetVolume(volume)
+		m.status = fmt.Sprintf("Alarm: failed to play %s", station.Name)
+		return nil
+	}
+	m.stationStarted(station)
+	m.status = fmt.Sprintf("Alarm: good morning, playing %s", station.Name)
+	return m.fade(volume, alarmFade)
+}
+
+// fade ramps the player volume in the background, replacing any fade in
+// progress.
+func (m *UIModel) fade(target int, d time.Duration) tea.Cmd {
+	m.stopFade()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.timers.fadeCancel = cancel
+
+	pl := m.player
+	return func() tea.Msg {
+		return fadeDoneMsg{err: pl.Fade(ctx, target, d)}
+	}
+}
+
+func (m *UIModel) stopFade() {
+	if m.timers.fadeCancel != nil {
+		m.timers.fadeCancel()
+		m.timers.fadeCancel = nil
+	}
+}
+
+func (m *UIModel) handleFadeDone(msg fadeDoneMsg) {
+	if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+		logger.Log.Warn().Err(msg.err).Msg("Volume fade failed")
+	}
+}
+
+// toggleAlarm cancels the alarm, or asks for a time to wake up to the
+// selected favorite.
+func (m *UIModel) toggleAlarm() tea.Cmd {
+	if !m.timers.alarmAt.IsZero() {
+		m.timers.alarmAt = time.Time{}
+		m.timers.alarmStation = nil
+		m.timers.alarmGen++
+		m.status = "Alarm off"
+		return nil
+	}
+	station := m.alarmCandidate()
+	if station == nil {
+		m.status = "Add a favorite to wake up to first"
+		return nil
+	}
+	m.timers.alarmPending = station
+
+	ti := textinput.New()
+	ti.Placeholder = "07:30"
+	ti.CharLimit = 5
+	ti.Width = 10
+	ti.Focus()
+	m.timers.alarmInput = ti
+	m.timers.alarmPrompt = true
+	return textinput.Blink
+}
+
+// alarmCandidate is the selected station if it is a favorite, otherwise
+// the oldest favorite.
+func (m *UIModel) alarmCandidate() *client.Station {
+	if item, ok := m.list.SelectedItem().(StationItem); ok && m.storage.IsFavorite(item.Station.URL) {
+		st := item.Station
+		return &st
+	}
+	favorites := m.storage.ListFavorites()
+	if len(favorites) == 0 {
+		return nil
+	}
+	return &favorites[0]
+}
+
+func (m *UIModel) updateAlarmPrompt(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.timers.alarmPrompt = false
+		m.timers.alarmPending = nil
+		return nil
+	case "enter":
+		at, err := ParseAlarmTime(m.timers.alarmInput.Value(), time.Now())
+		if err != nil {
+			m.status = err.Error()
+			return nil
+		}
+		station := m.timers.alarmPending
+		m.timers.alarmPrompt = false
+		m.timers.alarmPending = nil
+		m.SetAlarm(at, *station)
+		m.status = fmt.Sprintf("Alarm set for %s: %s", at.Format("Mon 15:04"), station.Name)
+		return alarmTick(m.timers.alarmGen)
+	}
+
+	var cmd tea.Cmd
+	m.timers.alarmInput, cmd = m.timers.alarmInput.Update(msg)
+	return cmd
+}
+
+// timerInfo renders the sleep and alarm countdowns, empty when neither is
+// set.
+func (m *UIModel) timerInfo() string {
+	var parts []string
+	if !m.timers.sleepAt.IsZero() {
+		parts = append(parts, fmt.Sprintf("💤 %s", formatElapsed(time.Until(m.timers.sleepAt))))
+	}
+	if !m.timers.alarmAt.IsZero() {
+		parts = append(parts, fmt.Sprintf("⏰ %s in %s", m.timers.alarmAt.Format("15:04"), formatElapsed(time.Until(m.timers.alarmAt))))
+	}
+	return strings.Join(parts, " ")
+}
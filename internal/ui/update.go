@@ -2,29 +2,64 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
-	"time"
 
 	"radio/internal/client"
+	"radio/internal/scheduler"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-type searchMsg []client.Station
-type errMsg error
+// searchMsg carries the results of search id.
+type searchMsg struct {
+	id       int
+	stations []client.Station
+	err      error
+}
 
-func searchStations(ctx context.Context, query string) tea.Cmd {
+func searchStations(ctx context.Context, c *client.Client, id int, query string) tea.Cmd {
 	return func() tea.Msg {
-		c := client.NewClient("", 10*time.Second)
 		stations, err := c.SearchStations(ctx, map[string]string{
 			"name": query,
 		})
-		if err != nil {
-			return errMsg(err)
+		return searchMsg{id: id, stations: stations, err: err}
+	}
+}
+
+// startSearch cancels the search in flight, if any, and starts one for
+// query.
+func (m *UIModel) startSearch(query string) tea.Cmd {
+	if m.search.cancel != nil {
+		m.search.cancel()
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.search.id++
+	m.search.cancel = cancel
+	return searchStations(ctx, m.client, m.search.id, query)
+}
+
+// handleSearch shows the results of the latest search; earlier ones that
+// arrive late are dropped.
+func (m *UIModel) handleSearch(msg searchMsg) {
+	if msg.id != m.search.id {
+		return
+	}
+	m.search.cancel()
+	m.search.cancel = nil
+	m.loading = false
+
+	if msg.err != nil {
+		if !errors.Is(msg.err, context.Canceled) {
+			m.err = msg.err
 		}
-		return searchMsg(stations)
+		return
 	}
+	m.err = nil
+	m.allStations = msg.stations
+	m.filterStations(m.textinput.Value())
 }
 
 func (m *UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -32,8 +67,40 @@ func (m *UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.schedule.open && msg.String() != "ctrl+c" {
+			return m, m.updateScheduleView(msg)
+		}
+		if m.details.open && msg.String() != "ctrl+c" {
+			return m, m.updateDetails(msg)
+		}
+		if m.eq.open && msg.String() != "ctrl+c" {
+			return m, m.updateEQView(msg)
+		}
+		if m.devices.open && msg.String() != "ctrl+c" {
+			return m, m.updateDeviceView(msg)
+		}
+		if m.settings.open && msg.String() != "ctrl+c" {
+			return m, m.updateSettingsView(msg)
+		}
+		if m.timers.alarmPrompt && msg.String() != "ctrl+c" {
+			return m, m.updateAlarmPrompt(msg)
+		}
+		// While a query is typed, letters are text, not hotkeys.
+		if m.searchVisible && m.textinput.Focused() {
+			switch msg.String() {
+			case "tab", "enter", "esc", "ctrl+c":
+			default:
+				var cmd tea.Cmd
+				m.textinput, cmd = m.textinput.Update(msg)
+				m.filterStations(m.textinput.Value())
+				return m, cmd
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "esc":
+			m.stopRecording()
+			m.saveLoudness()
 			m.cancel()
 			return m, tea.Quit
 
@@ -52,13 +119,13 @@ func (m *UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.loading = true
 					m.err = nil
 					m.lastQuery = query
-					cmds = append(cmds, searchStations(m.ctx, query))
+					cmds = append(cmds, m.startSearch(query))
 					m.list.SetItems([]list.Item{})
 				}
 			} else if !m.searchVisible && len(m.list.Items()) > 0 {
 				i, ok := m.list.SelectedItem().(StationItem)
 				if ok {
-					m.PlayStation(i, true)
+					cmds = append(cmds, m.switchStation(i, false))
 				}
 			}
 
@@ -77,15 +144,67 @@ func (m *UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 		case "[":
-			if m.autoSwitchDelay > 1*time.Minute {
-				m.autoSwitchDelay -= 1 * time.Minute
-			}
+			m.decreaseDwell()
 		case "]":
-			m.autoSwitchDelay += 1 * time.Minute
+			m.increaseDwell()
 
 		case "m":
 			cmds = append(cmds, m.toggleAutoSwitch())
 
+		case "M":
+			m.cycleScanMode()
+
+		case "F":
+			m.cycleScanFilter()
+
+		case "l":
+			m.lockStation()
+
+		case "h":
+			cmds = append(cmds, m.checkVisible())
+
+		case "P":
+			cmds = append(cmds, m.pruneDeadFavorites())
+
+		case "i":
+			cmds = append(cmds, m.toggleInfo())
+
+		case "d":
+			cmds = append(cmds, m.openDetails())
+
+		case "v":
+			cmds = append(cmds, m.toggleVisualizer())
+
+		case "e":
+			m.cycleEQPreset()
+
+		case "E":
+			m.openEQView()
+
+		case "X":
+			m.cycleCrossfade()
+
+		case "o":
+			cmds = append(cmds, m.openDeviceView())
+
+		case ",":
+			m.openSettingsView()
+
+		case "y":
+			cmds = append(cmds, m.runSync())
+
+		case "r":
+			cmds = append(cmds, m.toggleRecording())
+
+		case "S":
+			m.openScheduleView()
+
+		case "t":
+			cmds = append(cmds, m.cycleSleepTimer())
+
+		case "w":
+			cmds = append(cmds, m.toggleAlarm())
+
 		case "z":
 			if m.favoritesMode {
 				m.favoritesMode = false
@@ -96,9 +215,18 @@ func (m *UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "s":
+			// A station still being read, connecting or faded to is not
+			// started.
+			m.crossfade.gen++
+			m.endStream()
 			if m.playing != nil {
+				if !m.timers.sleepAt.IsZero() {
+					m.cancelSleep()
+				}
+				m.stopRecording()
 				_ = m.player.Stop()
 				m.playing = nil
+				m.saveLoudness()
 				m.filterStations(m.textinput.Value())
 			}
 		case "1":
@@ -130,20 +258,96 @@ func (m *UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.list.SetSize(msg.Width-32, availableHeight)
 
 	case autoSwitchMsg:
-		if m.autoSwitching {
-			m.randomStation()
-			cmds = append(cmds, m.startAutoSwitchCmd())
+		cmds = append(cmds, m.handleAutoSwitch(msg))
+
+	case crossfadeDoneMsg:
+		m.handleCrossfadeDone(msg)
+
+	case stationReadyMsg:
+		cmds = append(cmds, m.handleStationReady(msg))
+
+	case stationStartedMsg:
+		cmds = append(cmds, m.handleStationStarted(msg))
+
+	case devicesMsg:
+		m.handleDevices(msg)
+
+	case deviceSetMsg:
+		m.handleDeviceSet(msg)
+
+	case deviceMissingMsg:
+		m.status = fmt.Sprintf("Output %s is not connected; playing on the default", msg.name)
+
+	case favoritesChangedMsg:
+		if len(msg.Conflicts) > 0 {
+			m.status = fmt.Sprintf("Favorites changed on disk; %d conflicting station(s) kept as on disk", len(msg.Conflicts))
+		} else {
+			m.status = "Favorites reloaded from disk"
 		}
+		if m.favoritesMode {
+			m.showFavorites()
+		} else {
+			m.filterStations(m.textinput.Value())
+		}
+		cmds = append(cmds, waitForStorageChange(m.storageEvents))
+
+	case syncDoneMsg:
+		cmds = append(cmds, m.handleSyncDone(msg))
+
+	case syncTickMsg:
+		cmds = append(cmds, m.runSync())
+
+	case recordStartedMsg:
+		cmds = append(cmds, m.handleRecordStarted(msg))
+
+	case scheduleEventMsg:
+		cmds = append(cmds, m.handleScheduleEvent(scheduler.Event(msg)))
+		cmds = append(cmds, waitForScheduleEvent(m.scheduleEvents))
+
+	case sleepTickMsg:
+		cmds = append(cmds, m.handleSleepTick(msg))
+
+	case alarmTickMsg:
+		cmds = append(cmds, m.handleAlarmTick(msg))
+
+	case fadeDoneMsg:
+		m.handleFadeDone(msg)
+
+	case healthResultMsg:
+		cmds = append(cmds, m.handleHealthResult(msg))
+
+	case healthDoneMsg:
+		m.handleHealthDone(msg)
+
+	case infoTickMsg:
+		cmds = append(cmds, m.handleInfoTick(msg))
+
+	case streamInfoMsg:
+		m.handleStreamInfo(msg)
+
+	case probeMsg:
+		m.handleProbe(msg)
+
+	case faviconMsg:
+		m.handleFavicon(msg)
+
+	case detailsVariantMsg:
+		m.handleDetailsVariant(msg)
+
+	case loudnessTickMsg:
+		cmds = append(cmds, m.handleLoudnessTick())
+
+	case loudnessMsg:
+		m.handleLoudness(msg)
+
+	case vizTickMsg:
+		cmds = append(cmds, m.handleVizTick(msg))
+
+	case recordTickMsg:
+		cmds = append(cmds, m.handleRecordTick(msg))
 
 	case searchMsg:
-		m.loading = false
-		m.err = nil
-		m.allStations = msg
-		m.filterStations(m.textinput.Value())
-
-	case errMsg:
-		m.loading = false
-		m.err = msg
+		m.handleSearch(msg)
 	}
 
 	var cmd tea.Cmd
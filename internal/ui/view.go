@@ -4,10 +4,28 @@ import (
 	"fmt"
 	"time"
 
+	"radio/internal/favicon"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
 func (m *UIModel) View() string {
+	if m.schedule.open {
+		return m.renderScheduleView()
+	}
+	if m.details.open {
+		return m.renderDetails()
+	}
+	if m.eq.open {
+		return m.renderEQView()
+	}
+	if m.devices.open {
+		return m.renderDeviceView()
+	}
+	if m.settings.open {
+		return m.renderSettingsView()
+	}
+
 	if m.searchVisible {
 		modalStyle := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
@@ -37,7 +55,7 @@ func (m *UIModel) View() string {
 		contentParts = append(contentParts, loadingStyle.Render(m.spinner.View()+" Loading stations..."))
 
 	case m.err != nil:
-		contentParts = append(contentParts, errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+		contentParts = append(contentParts, errorStyle.Render("Error: "+friendlyError(m.err)))
 
 	case len(m.filteredItems) == 0:
 		placeholderBox := lipgloss.NewStyle().
@@ -69,6 +87,9 @@ func (m *UIModel) View() string {
 			MarginBottom(1)
 
 		listInBox := listBoxStyle.Render(m.list.View())
+		if m.info.open {
+			listInBox = lipgloss.JoinHorizontal(lipgloss.Top, listInBox, " ", m.renderInfoPanel())
+		}
 		contentParts = append(contentParts, listInBox)
 
 		contentParts = append(contentParts,
@@ -76,18 +97,33 @@ func (m *UIModel) View() string {
 		)
 	}
 
+	if m.timers.alarmPrompt {
+		contentParts = append(contentParts, fmt.Sprintf("Wake up to %s at (HH:MM): %s", m.timers.alarmPending.Name, m.timers.alarmInput.View()))
+	}
+
+	if m.status != "" {
+		contentParts = append(contentParts, positionStyle.Render(m.status))
+	}
+
 	mainContent := lipgloss.JoinVertical(lipgloss.Left, contentParts...)
 
 	footer := m.renderPlayer()
+	if viz := m.renderVisualizer(); viz != "" {
+		footer = lipgloss.JoinHorizontal(lipgloss.Center, footer, " ", viz)
+	}
 
 	help := helpStyle.Render("Tab: toggle search • Enter: play/search • s: stop • a: toggle favorite • " +
-		"z: favorites • 1/2/3: sort • m: toggle auto • [/] adjust delay • Esc/Ctrl+C: quit")
+		"z: favorites • 1/2/3: sort • m: scan • M: scan mode • F: scan filter • l: lock • [/] dwell • h: check streams • P: prune dead favorites • i: stream info • d: details • v: visualizer • e/E: EQ preset/editor • X: crossfade • o: output device • ,: stream settings • r: record • S: schedules • t: sleep timer • w: alarm • y: sync • Esc/Ctrl+C: quit")
 
-	return lipgloss.JoinVertical(lipgloss.Left,
+	view := lipgloss.JoinVertical(lipgloss.Left,
 		mainContent,
 		footer,
 		help,
 	)
+	if m.details.drewKitty {
+		view = favicon.ClearKitty + view
+	}
+	return view
 }
 
 func (m *UIModel) renderPlayer() string {
@@ -102,7 +138,11 @@ func (m *UIModel) renderPlayer() string {
 		if m.autoSwitching {
 			style = style.Background(lipgloss.Color("#FFAA00"))
 		}
-		return style.Render("⏸️  No station playing")
+		text := "⏸️  No station playing"
+		if info := m.timerInfo(); info != "" {
+			text += "\n" + info
+		}
+		return style.Render(text)
 	}
 
 	const maxNameLen = 20
@@ -131,7 +171,33 @@ func (m *UIModel) renderPlayer() string {
 
 	delayCol := ""
 	if m.autoSwitching {
-		delayCol = infoStyle.Render(fmt.Sprintf("⏩ %v", m.autoSwitchDelay.Round(time.Minute)))
+		remaining := m.autoSwitchRemaining
+		if remaining < 0 {
+			remaining = 0
+		}
+		elapsed := float64(m.scan.Dwell-remaining) / float64(m.scan.Dwell)
+		delayCol = infoStyle.Render(fmt.Sprintf("⏩ %s %s / %v • %s • %s", progressBar(elapsed, 10),
+			formatElapsed(remaining), m.scan.Dwell.Round(time.Second), m.scan.Mode, m.scanLabel()))
+	}
+
+	recCol := ""
+	if m.isRecording() {
+		st := m.recorder.Status()
+		rec := fmt.Sprintf("⏺ REC %s • %s", formatElapsed(time.Since(st.Started)), formatBytes(st.Bytes))
+		if st.Tracks > 0 {
+			rec += fmt.Sprintf(" • #%d %s", st.Tracks, truncateText(st.Track, maxNameLen))
+		}
+		recCol = recordingStyle.Render(rec)
+	}
+
+	timerCol := ""
+	if info := m.timerInfo(); info != "" {
+		timerCol = infoStyle.Render(info)
+	}
+
+	eqCol := ""
+	if m.eq.settings.Active() {
+		eqCol = infoStyle.Render("🎚 " + m.eq.settings.Label())
 	}
 
 	separator := lipgloss.NewStyle().Foreground(lipgloss.Color("#555555")).Render(" | ")
@@ -140,6 +206,15 @@ func (m *UIModel) renderPlayer() string {
 	if delayCol != "" {
 		cols = append(cols, separator, delayCol)
 	}
+	if recCol != "" {
+		cols = append(cols, separator, recCol)
+	}
+	if timerCol != "" {
+		cols = append(cols, separator, timerCol)
+	}
+	if eqCol != "" {
+		cols = append(cols, separator, eqCol)
+	}
 
 	playerContent := lipgloss.JoinHorizontal(lipgloss.Top, cols...)
 
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"context"
+	"time"
+
+	"radio/internal/audio"
+	"radio/internal/player"
+	"radio/internal/visualizer"
+	"radio/pkg/logger"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	vizFrame  = 50 * time.Millisecond
+	vizBars   = 24
+	vizHeight = 3
+)
+
+type vizTickMsg struct{ gen int }
+
+type vizState struct {
+	on       bool
+	gen      int
+	url      string
+	cancel   context.CancelFunc
+	analyzer *visualizer.Analyzer
+	source   visualizer.Source
+	// tapped is set while the analyzer hears the player itself rather
+	// than a decoder of its own.
+	tapped bool
+}
+
+func vizTick(gen int) tea.Cmd {
+	return tea.Tick(vizFrame, func(time.Time) tea.Msg {
+		return vizTickMsg{gen: gen}
+	})
+}
+
+func (m *UIModel) toggleVisualizer() tea.Cmd {
+	m.viz.gen++
+	if m.viz.on {
+		m.viz.on = false
+		m.stopVisualizer()
+		return nil
+	}
+	m.viz.on = true
+	m.syncVisualizer()
+	return vizTick(m.viz.gen)
+}
+
+// syncVisualizer follows the playing station, starting or stopping the
+// decoder as needed.
+func (m *UIModel) syncVisualizer() {
+	switch {
+	case m.playing == nil:
+		m.stopVisualizer()
+	case m.playing.URL != m.viz.url:
+		m.startVisualizer(m.playing.URL)
+	}
+}
+
+func (m *UIModel) startVisualizer(url string) {
+	m.stopVisualizer()
+	if tapper, ok := m.player.(player.Tapper); ok {
+		f := audio.OutputFormat
+		analyzer := visualizer.NewAnalyzerFormat(f.SampleRate, f.Channels)
+		if tapper.SetTap(analyzer) {
+			m.viz.url = url
+			m.viz.analyzer = analyzer
+			m.viz.tapped = true
+			return
+		}
+	}
+
+	if m.viz.source == nil {
+		m.viz.source = visualizer.MPVSource{}
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	analyzer := visualizer.NewAnalyzer()
+	m.viz.url = url
+	m.viz.cancel = cancel
+	m.viz.analyzer = analyzer
+
+	source := m.viz.source
+	go func() {
+		pcm, err := source.Open(ctx, url)
+		if err != nil {
+			logger.Log.Warn().Err(err).Msg("Visualizer could not decode the stream")
+			return
+		}
+		defer pcm.Close()
+		if err := analyzer.Feed(pcm); err != nil && ctx.Err() == nil {
+			logger.Log.Warn().Err(err).Msg("Visualizer stream ended")
+		}
+	}()
+}
+
+func (m *UIModel) stopVisualizer() {
+	if m.viz.cancel != nil {
+		m.viz.cancel()
+	}
+	if m.viz.tapped {
+		m.player.(player.Tapper).SetTap(nil)
+		m.viz.tapped = false
+	}
+	m.viz.cancel = nil
+	m.viz.analyzer = nil
+	m.viz.url = ""
+}
+
+func (m *UIModel) handleVizTick(msg vizTickMsg) tea.Cmd {
+	if !m.viz.on || msg.gen != m.viz.gen {
+		return nil
+	}
+	m.syncVisualizer()
+	return vizTick(msg.gen)
+}
+
+func (m *UIModel) renderVisualizer() string {
+	if !m.viz.on || m.viz.analyzer == nil {
+		return ""
+	}
+	bars := visualizer.RenderBars(m.viz.analyzer.Bars(vizBars), vizHeight)
+	meter := visualizer.RenderMeter(m.viz.analyzer.Level(), vizBars)
+	return vizStyle.Render(bars + "\n" + meter)
+}
@@ -0,0 +1,54 @@
+package visualizer
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft transforms x in place; len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit-reversal permutation.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := w * x[start+k+size/2]
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+}
+
+// magnitudes returns the spectrum of samples (Hann windowed) up to Nyquist.
+func magnitudes(samples []float64) []float64 {
+	n := len(samples)
+	buf := make([]complex128, n)
+	for i, s := range samples {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		buf[i] = complex(s*w, 0)
+	}
+	fft(buf)
+
+	out := make([]float64, n/2)
+	for i := range out {
+		out[i] = cmplx.Abs(buf[i]) / float64(n)
+	}
+	return out
+}
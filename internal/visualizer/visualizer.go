@@ -0,0 +1,266 @@
+// Package visualizer turns decoded audio into spectrum bars and a level
+// meter for the TUI.
+package visualizer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"radio/internal/netconf"
+)
+
+const (
+	// SampleRate is what the PCM source is asked to produce; mono, s16le.
+	SampleRate = 22050
+	windowSize = 1024
+	minFreq    = 40.0
+	// floorDB is the level shown as an empty bar.
+	floorDB = -70.0
+	// decay is how much a bar may fall per frame, so peaks linger.
+	decay = 0.08
+)
+
+// Source produces raw mono s16le PCM at SampleRate for a stream.
+type Source interface {
+	Open(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// MPVSource decodes with a second, silent mpv writing PCM to stdout, for
+// players that cannot hand out what they play. It opens its own
+// connection, so the picture may run a little ahead of or behind what is
+// heard.
+type MPVSource struct {
+	// Network supplies the proxy and TLS options; nil uses the
+	// environment's.
+	Network *netconf.Network
+}
+
+func (s MPVSource) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	args := []string{
+		"--no-video", "--really-quiet", "--no-terminal", "--idle=no",
+		"--ao=pcm", "--ao-pcm-file=/dev/stdout", "--ao-pcm-waveheader=no",
+		"--audio-format=s16", "--audio-channels=mono", fmt.Sprintf("--audio-samplerate=%d", SampleRate),
+	}
+	if s.Network != nil {
+		netArgs, err := s.Network.MPVArgs(url)
+		if err != nil {
+			return nil, fmt.Errorf("setting up the proxy for the visualizer: %w", err)
+		}
+		args = append(args, netArgs...)
+	}
+	cmd := exec.CommandContext(ctx, "mpv", append(args, url)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting visualizer decoder: %w", err)
+	}
+	return &processReader{ReadCloser: out, cmd: cmd}, nil
+}
+
+type processReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (p *processReader) Close() error {
+	_ = p.cmd.Process.Kill()
+	err := p.ReadCloser.Close()
+	_ = p.cmd.Wait()
+	return err
+}
+
+// Analyzer keeps the most recent samples read from a PCM stream.
+type Analyzer struct {
+	sampleRate int
+	channels   int
+
+	mu     sync.Mutex
+	ring   []float64
+	pos    int
+	filled bool
+	err    error
+	bars   []float64
+}
+
+// NewAnalyzer analyses what a Source produces: mono at SampleRate.
+func NewAnalyzer() *Analyzer {
+	return NewAnalyzerFormat(SampleRate, 1)
+}
+
+// NewAnalyzerFormat analyses interleaved audio with the given rate and
+// channel count, as handed to Process.
+func NewAnalyzerFormat(sampleRate, channels int) *Analyzer {
+	return &Analyzer{
+		sampleRate: sampleRate,
+		channels:   max(channels, 1),
+		ring:       make([]float64, windowSize),
+	}
+}
+
+// push adds one mono sample. The caller must hold a.mu.
+func (a *Analyzer) push(v float64) {
+	a.ring[a.pos] = v
+	a.pos = (a.pos + 1) % len(a.ring)
+	if a.pos == 0 {
+		a.filled = true
+	}
+}
+
+// Process takes interleaved float samples in -1..1 straight from a
+// player, mixing the channels down. It leaves samples untouched, so it
+// can sit in a player's filter chain.
+func (a *Analyzer) Process(samples []float32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := 0; i+a.channels <= len(samples); i += a.channels {
+		var sum float64
+		for _, v := range samples[i : i+a.channels] {
+			sum += float64(v)
+		}
+		a.push(sum / float64(a.channels))
+	}
+}
+
+// Feed reads PCM from r until it fails or ends. A live stream delivers a
+// burst on connect; only the newest window is ever analysed, so the
+// backlog is simply overwritten.
+func (a *Analyzer) Feed(r io.Reader) error {
+	buf := make([]byte, 4096)
+	var carry []byte
+	for {
+		n, err := r.Read(buf)
+		data := append(carry, buf[:n]...)
+		even := len(data) &^ 1
+
+		a.mu.Lock()
+		for i := 0; i < even; i += 2 {
+			a.push(float64(int16(binary.LittleEndian.Uint16(data[i:]))) / 32768)
+		}
+		a.mu.Unlock()
+
+		carry = append(carry[:0], data[even:]...)
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			a.mu.Lock()
+			a.err = err
+			a.mu.Unlock()
+			return err
+		}
+	}
+}
+
+func (a *Analyzer) window() []float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]float64, len(a.ring))
+	copy(out, a.ring[a.pos:])
+	copy(out[len(a.ring)-a.pos:], a.ring[:a.pos])
+	return out
+}
+
+// Level is the RMS of the newest window in dBFS, floorDB when silent.
+func (a *Analyzer) Level() float64 {
+	var sum float64
+	w := a.window()
+	for _, s := range w {
+		sum += s * s
+	}
+	rms := math.Sqrt(sum / float64(len(w)))
+	return toDB(rms)
+}
+
+// Bars returns n levels in 0..1 for log-spaced bands from minFreq to
+// Nyquist. Falling bars decay gradually between calls.
+func (a *Analyzer) Bars(n int) []float64 {
+	spectrum := magnitudes(a.window())
+	binHz := float64(a.sampleRate) / float64(windowSize)
+	maxFreq := float64(a.sampleRate) / 2
+
+	levels := make([]float64, n)
+	for i := 0; i < n; i++ {
+		lo := minFreq * math.Pow(maxFreq/minFreq, float64(i)/float64(n))
+		hi := minFreq * math.Pow(maxFreq/minFreq, float64(i+1)/float64(n))
+		from, to := int(lo/binHz), int(hi/binHz)
+		if to <= from {
+			to = from + 1
+		}
+		if to > len(spectrum) {
+			to = len(spectrum)
+		}
+
+		var peak float64
+		for _, m := range spectrum[from:to] {
+			peak = math.Max(peak, m)
+		}
+		// A full-scale sine has magnitude 0.25 after the Hann window.
+		levels[i] = normalize(toDB(peak * 4))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.bars) != n {
+		a.bars = make([]float64, n)
+	}
+	for i, l := range levels {
+		if l < a.bars[i]-decay {
+			l = a.bars[i] - decay
+		}
+		a.bars[i] = l
+	}
+	out := make([]float64, n)
+	copy(out, a.bars)
+	return out
+}
+
+func toDB(v float64) float64 {
+	if v <= 0 {
+		return floorDB
+	}
+	return math.Max(floorDB, 20*math.Log10(v))
+}
+
+func normalize(db float64) float64 {
+	return math.Min(1, math.Max(0, (db-floorDB)/-floorDB))
+}
+
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// RenderBars draws levels (0..1) as columns height rows tall.
+func RenderBars(levels []float64, height int) string {
+	rows := make([]string, height)
+	for r := 0; r < height; r++ {
+		var b strings.Builder
+		// Row 0 is the top; each row covers 8 eighths of the bar.
+		base := float64(height-1-r) * 8
+		for _, l := range levels {
+			eighths := l*float64(height)*8 - base
+			switch {
+			case eighths >= 8:
+				b.WriteRune(blocks[8])
+			case eighths <= 0:
+				b.WriteRune(blocks[0])
+			default:
+				b.WriteRune(blocks[int(eighths)])
+			}
+		}
+		rows[r] = b.String()
+	}
+	return strings.Join(rows, "\n")
+}
+
+// RenderMeter draws a horizontal level meter width cells wide for a dBFS
+// level.
+func RenderMeter(db float64, width int) string {
+	filled := int(normalize(db)*float64(width) + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("·", width-filled)
+}
@@ -0,0 +1,109 @@
+package visualizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func sine(freq, amplitude float64, n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		v := amplitude * math.Sin(2*math.Pi*freq*float64(i)/SampleRate)
+		binary.Write(&buf, binary.LittleEndian, int16(v*32767))
+	}
+	return buf.Bytes()
+}
+
+func TestFFTFindsSine(t *testing.T) {
+	samples := make([]float64, 256)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 16 * float64(i) / 256)
+	}
+	spectrum := magnitudes(samples)
+
+	peak := 0
+	for i, m := range spectrum {
+		if m > spectrum[peak] {
+			peak = i
+		}
+	}
+	if peak != 16 {
+		t.Errorf("expected the peak in bin 16, got %d", peak)
+	}
+	if math.Abs(spectrum[16]-0.25) > 0.01 {
+		t.Errorf("expected magnitude 0.25, got %f", spectrum[16])
+	}
+}
+
+func TestAnalyzerBars(t *testing.T) {
+	a := NewAnalyzer()
+	// A 1 kHz tone, fed in odd-sized reads to exercise sample carry-over.
+	data := sine(1000, 0.8, 4096)
+	if err := a.Feed(&oddReader{data: data}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bars := a.Bars(16)
+	loudest := 0
+	for i, b := range bars {
+		if b > bars[loudest] {
+			loudest = i
+		}
+	}
+
+	// Find which band 1 kHz belongs to.
+	maxFreq := float64(SampleRate) / 2
+	band := int(math.Log(1000/minFreq) / math.Log(maxFreq/minFreq) * 16)
+	if loudest < band-1 || loudest > band+1 {
+		t.Errorf("expected the loudest bar near band %d, got %d (%v)", band, loudest, bars)
+	}
+	if bars[loudest] < 0.9 {
+		t.Errorf("expected a near full bar, got %f", bars[loudest])
+	}
+
+	if level := a.Level(); level < -6 || level > 0 {
+		t.Errorf("expected about -5 dBFS, got %f", level)
+	}
+}
+
+func TestBarsDecay(t *testing.T) {
+	a := NewAnalyzer()
+	a.Feed(bytes.NewReader(sine(1000, 1, windowSize)))
+	first := a.Bars(8)
+
+	a.Feed(bytes.NewReader(make([]byte, windowSize*2)))
+	second := a.Bars(8)
+	for i := range first {
+		if first[i]-second[i] > decay+1e-9 {
+			t.Errorf("bar %d fell from %f to %f in one frame", i, first[i], second[i])
+		}
+	}
+}
+
+func TestRenderBars(t *testing.T) {
+	out := RenderBars([]float64{0, 0.5, 1}, 2)
+	rows := strings.Split(out, "\n")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0] != "  █" || rows[1] != " ██" {
+		t.Errorf("unexpected bars %q", rows)
+	}
+}
+
+type oddReader struct {
+	data []byte
+}
+
+func (r *oddReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), 333)], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}